@@ -1,6 +1,9 @@
 package actions
 
-import "shs/app/models"
+import (
+	"shs/app/models"
+	"time"
+)
 
 const (
 	patientPermissions = models.AccountPermissionReadOwnVisit | models.AccountPermissionWriteOwnVisit
@@ -18,7 +21,8 @@ const (
 		models.AccountPermissionReadMedicine | models.AccountPermissionWriteBloodTest |
 		models.AccountPermissionReadVirus | models.AccountPermissionWriteVirus |
 		models.AccountPermissionReadDiagnoses | models.AccountPermissionWriteDiagnoses |
-		models.AccountPermissionReadJoints | models.AccountPermissionWriteJoints
+		models.AccountPermissionReadJoints | models.AccountPermissionWriteJoints |
+		models.AccountPermissionReadAuditLogs
 
 	// aka jointologist
 	snoopDoggPermissions = models.AccountPermissionReadPatient |
@@ -31,6 +35,7 @@ type Account struct {
 	Username    string                    `json:"username"`
 	Type        string                    `json:"type"`
 	Permissions models.AccountPermissions `json:"permissions"`
+	DisabledAt  *time.Time                `json:"disabled_at"`
 }
 
 func (a *Account) FromModel(ma models.Account) {
@@ -40,6 +45,7 @@ func (a *Account) FromModel(ma models.Account) {
 		Username:    ma.Username,
 		Type:        string(ma.Type),
 		Permissions: ma.Permissions,
+		DisabledAt:  ma.DisabledAt,
 	}
 }
 
@@ -1,6 +1,9 @@
 package actions
 
-import "shs/app/models"
+import (
+	"shs/app"
+	"shs/app/models"
+)
 
 type GetAddressesAlikeParams struct {
 	ActionContext
@@ -34,3 +37,18 @@ func (a *Actions) GetAddressesAlike(params GetAddressesAlikeParams) (GetAddresse
 		Data: outAddresses,
 	}, nil
 }
+
+// findOrCreateAddress reuses an existing address when it's the only one alike the given address,
+// otherwise a new address is created.
+func findOrCreateAddress(appInstance *app.App, address Address) (models.Address, error) {
+	addresses, _ := appInstance.GetAllAddressesALike(models.Address{
+		Governorate: address.Governorate,
+		Suburb:      address.Suburb,
+		Street:      address.Street,
+	})
+	if len(addresses) == 1 {
+		return addresses[0], nil
+	}
+
+	return appInstance.CreateAddress(address.IntoModel())
+}
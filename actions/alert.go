@@ -0,0 +1,113 @@
+package actions
+
+import (
+	"shs/app/models"
+	"slices"
+	"time"
+)
+
+type Alert struct {
+	Id                uint      `json:"id"`
+	Type              string    `json:"type"`
+	ProductId         uint      `json:"product_id"`
+	PatientId         uint      `json:"patient_id"`
+	StockAmount       int       `json:"stock_amount"`
+	MinStock          int       `json:"min_stock"`
+	ProjectedRunOutAt time.Time `json:"projected_run_out_at"`
+	Message           string    `json:"message"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+func (a *Alert) FromModel(alert models.Alert) {
+	(*a) = Alert{
+		Id:                alert.Id,
+		Type:              string(alert.Type),
+		ProductId:         alert.ProductId,
+		PatientId:         alert.PatientId,
+		StockAmount:       alert.StockAmount,
+		MinStock:          alert.MinStock,
+		ProjectedRunOutAt: alert.ProjectedRunOutAt,
+		Message:           alert.Message,
+		CreatedAt:         alert.CreatedAt,
+	}
+}
+
+type ListAlertsParams struct {
+	ActionContext
+	Type      string
+	ProductId uint
+	From      time.Time
+	To        time.Time
+	Page      int
+	PageSize  int
+}
+
+type ListAlertsPayload struct {
+	Data     []Alert `json:"data"`
+	Total    int64   `json:"total"`
+	Page     int     `json:"page"`
+	PageSize int     `json:"page_size"`
+}
+
+func (a *Actions) ListAlerts(params ListAlertsParams) (ListAlertsPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionReadMedicine) {
+		return ListAlertsPayload{}, ErrPermissionDenied{}
+	}
+
+	if params.Type != "" && !slices.Contains(models.AlertTypes(), models.AlertType(params.Type)) {
+		return ListAlertsPayload{}, ErrValidation{Field: "type"}
+	}
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.PageSize < 1 || params.PageSize > maxPageSize {
+		params.PageSize = defaultPageSize
+	}
+
+	alerts, total, err := a.app.ListAlerts(models.AlertFilter{
+		Type:      models.AlertType(params.Type),
+		ProductId: params.ProductId,
+		From:      params.From,
+		To:        params.To,
+		Offset:    (params.Page - 1) * params.PageSize,
+		Limit:     params.PageSize,
+	})
+	if err != nil {
+		return ListAlertsPayload{}, err
+	}
+
+	outAlerts := make([]Alert, 0, len(alerts))
+	for _, alert := range alerts {
+		outAlert := new(Alert)
+		outAlert.FromModel(alert)
+		outAlerts = append(outAlerts, *outAlert)
+	}
+
+	return ListAlertsPayload{
+		Data:     outAlerts,
+		Total:    total,
+		Page:     params.Page,
+		PageSize: params.PageSize,
+	}, nil
+}
+
+type SendDailyStockSummaryParams struct {
+	ActionContext
+}
+
+type SendDailyStockSummaryPayload struct {
+	AlertsCount int `json:"alerts_count"`
+}
+
+// SendDailyStockSummary raises and sends alerts for products projected to run out soon,
+// it's not permission checked since it's run by the server's daily scheduler.
+func (a *Actions) SendDailyStockSummary(params SendDailyStockSummaryParams) (SendDailyStockSummaryPayload, error) {
+	alerts, err := a.app.SendDailyStockSummary(time.Now().UTC())
+	if err != nil {
+		return SendDailyStockSummaryPayload{}, err
+	}
+
+	return SendDailyStockSummaryPayload{
+		AlertsCount: len(alerts),
+	}, nil
+}
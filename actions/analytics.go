@@ -0,0 +1,129 @@
+package actions
+
+import (
+	"shs/app/models"
+	"time"
+)
+
+const maxAnalyticsWindowMonths = 120
+
+var defaultAnalyticsWindowsMonths = []int{6, 12}
+
+type BleedRates struct {
+	From                   time.Time `json:"from"`
+	To                     time.Time `json:"to"`
+	BleedsCount            int       `json:"bleeds_count"`
+	JointBleedsCount       int       `json:"joint_bleeds_count"`
+	SpontaneousBleedsCount int       `json:"spontaneous_bleeds_count"`
+	VisitBleedsCount       int       `json:"visit_bleeds_count"`
+	ABR                    float64   `json:"abr"`
+	AJBR                   float64   `json:"ajbr"`
+	ASBR                   float64   `json:"asbr"`
+}
+
+func (b *BleedRates) FromModel(rates models.BleedRates) {
+	(*b) = BleedRates{
+		From:                   rates.From,
+		To:                     rates.To,
+		BleedsCount:            rates.BleedsCount,
+		JointBleedsCount:       rates.JointBleedsCount,
+		SpontaneousBleedsCount: rates.SpontaneousBleedsCount,
+		VisitBleedsCount:       rates.VisitBleedsCount,
+		ABR:                    rates.ABR,
+		AJBR:                   rates.AJBR,
+		ASBR:                   rates.ASBR,
+	}
+}
+
+type TargetJoint struct {
+	Site        string    `json:"site"`
+	BleedsCount int       `json:"bleeds_count"`
+	DetectedAt  time.Time `json:"detected_at"`
+	LastBleedAt time.Time `json:"last_bleed_at"`
+	Active      bool      `json:"active"`
+	// LatestScore is the joint's score in the latest joints evaluation, or nil when the patient has no evaluations.
+	LatestScore *int `json:"latest_score"`
+}
+
+type GetPatientAnalyticsParams struct {
+	ActionContext
+	PatientId     string
+	To            time.Time
+	WindowsMonths []int
+}
+
+type GetPatientAnalyticsPayload struct {
+	Windows                []BleedRates      `json:"windows"`
+	TargetJoints           []TargetJoint     `json:"target_joints"`
+	LatestJointsEvaluation *JointsEvaluation `json:"latest_joints_evaluation"`
+	Consumption            FactorConsumption `json:"consumption"`
+}
+
+// GetPatientAnalytics reports the patient's ABR and AJBR over trailing windows of the given months that end at To,
+// which defaults to now, along with the patient's target joints and the factor consumption over the longest window.
+func (a *Actions) GetPatientAnalytics(params GetPatientAnalyticsParams) (GetPatientAnalyticsPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionReadOtherVisits) {
+		return GetPatientAnalyticsPayload{}, ErrPermissionDenied{}
+	}
+
+	if params.To.IsZero() {
+		params.To = time.Now().UTC()
+	}
+	if len(params.WindowsMonths) == 0 {
+		params.WindowsMonths = defaultAnalyticsWindowsMonths
+	}
+	for _, months := range params.WindowsMonths {
+		if months < 1 || months > maxAnalyticsWindowMonths {
+			return GetPatientAnalyticsPayload{}, ErrValidation{Field: "windows"}
+		}
+	}
+
+	patient, err := a.app.GetMinimalPatientByPublicId(params.PatientId)
+	if err != nil {
+		return GetPatientAnalyticsPayload{}, err
+	}
+
+	analytics, err := a.app.GetPatientBleedAnalytics(patient.Id, params.To, params.WindowsMonths)
+	if err != nil {
+		return GetPatientAnalyticsPayload{}, err
+	}
+
+	outWindows := make([]BleedRates, 0, len(analytics.Windows))
+	for _, window := range analytics.Windows {
+		outWindow := new(BleedRates)
+		outWindow.FromModel(window)
+		outWindows = append(outWindows, *outWindow)
+	}
+
+	outTargetJoints := make([]TargetJoint, 0, len(analytics.TargetJoints))
+	for _, targetJoint := range analytics.TargetJoints {
+		outTargetJoint := TargetJoint{
+			Site:        string(targetJoint.Site),
+			BleedsCount: targetJoint.BleedsCount,
+			DetectedAt:  targetJoint.DetectedAt,
+			LastBleedAt: targetJoint.LastBleedAt,
+			Active:      targetJoint.Active,
+		}
+		if analytics.LatestJointsEvaluation != nil {
+			score := analytics.LatestJointsEvaluation.JointScore(targetJoint.Site)
+			outTargetJoint.LatestScore = &score
+		}
+		outTargetJoints = append(outTargetJoints, outTargetJoint)
+	}
+
+	var outJointsEvaluation *JointsEvaluation
+	if analytics.LatestJointsEvaluation != nil {
+		outJointsEvaluation = new(JointsEvaluation)
+		outJointsEvaluation.FromModel(*analytics.LatestJointsEvaluation)
+	}
+
+	outConsumption := new(FactorConsumption)
+	outConsumption.FromModel(analytics.Consumption)
+
+	return GetPatientAnalyticsPayload{
+		Windows:                outWindows,
+		TargetJoints:           outTargetJoints,
+		LatestJointsEvaluation: outJointsEvaluation,
+		Consumption:            *outConsumption,
+	}, nil
+}
@@ -0,0 +1,120 @@
+package actions
+
+import (
+	"shs/app/models"
+	"time"
+)
+
+type AuditLog struct {
+	Id           uint      `json:"id"`
+	AccountId    uint      `json:"account_id"`
+	Action       string    `json:"action"`
+	ResourceType string    `json:"resource_type"`
+	ResourceId   string    `json:"resource_id"`
+	StatusCode   int       `json:"status_code"`
+	Payload      string    `json:"payload"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (l *AuditLog) FromModel(auditLog models.AuditLog) {
+	(*l) = AuditLog{
+		Id:           auditLog.Id,
+		AccountId:    auditLog.AccountId,
+		Action:       auditLog.Action,
+		ResourceType: auditLog.ResourceType,
+		ResourceId:   auditLog.ResourceId,
+		StatusCode:   auditLog.StatusCode,
+		Payload:      auditLog.Payload,
+		CreatedAt:    auditLog.CreatedAt,
+	}
+}
+
+type RecordAuditLogParams struct {
+	ActionContext
+	Action       string
+	ResourceType string
+	ResourceId   string
+	StatusCode   int
+	Payload      string
+}
+
+type RecordAuditLogPayload struct {
+}
+
+// RecordAuditLog records a write action done by the context's account,
+// it's not permission checked since every account's writes must be recorded.
+func (a *Actions) RecordAuditLog(params RecordAuditLogParams) (RecordAuditLogPayload, error) {
+	_, err := a.app.CreateAuditLog(models.AuditLog{
+		AccountId:    params.Account.Id,
+		Action:       params.Action,
+		ResourceType: params.ResourceType,
+		ResourceId:   params.ResourceId,
+		StatusCode:   params.StatusCode,
+		Payload:      params.Payload,
+	})
+	if err != nil {
+		return RecordAuditLogPayload{}, err
+	}
+
+	return RecordAuditLogPayload{}, nil
+}
+
+type ListAuditLogsParams struct {
+	ActionContext
+	AccountId    uint
+	Action       string
+	ResourceType string
+	ResourceId   string
+	From         time.Time
+	To           time.Time
+	Page         int
+	PageSize     int
+}
+
+type ListAuditLogsPayload struct {
+	Data     []AuditLog `json:"data"`
+	Total    int64      `json:"total"`
+	Page     int        `json:"page"`
+	PageSize int        `json:"page_size"`
+}
+
+func (a *Actions) ListAuditLogs(params ListAuditLogsParams) (ListAuditLogsPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionReadAuditLogs) {
+		return ListAuditLogsPayload{}, ErrPermissionDenied{}
+	}
+
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.PageSize < 1 || params.PageSize > maxPageSize {
+		params.PageSize = defaultPageSize
+	}
+
+	auditLogs, total, err := a.app.ListAuditLogs(models.AuditLogFilter{
+		AccountId:    params.AccountId,
+		Action:       params.Action,
+		ResourceType: params.ResourceType,
+		ResourceId:   params.ResourceId,
+		From:         params.From,
+		To:           params.To,
+		Offset:       (params.Page - 1) * params.PageSize,
+		Limit:        params.PageSize,
+	})
+	if err != nil {
+		return ListAuditLogsPayload{}, err
+	}
+
+	outAuditLogs := make([]AuditLog, 0, len(auditLogs))
+	for _, auditLog := range auditLogs {
+		outAuditLog := new(AuditLog)
+		outAuditLog.FromModel(auditLog)
+		outAuditLogs = append(outAuditLogs, *outAuditLog)
+	}
+
+	return ListAuditLogsPayload{
+		Data:     outAuditLogs,
+		Total:    total,
+		Page:     params.Page,
+		PageSize: params.PageSize,
+	}, nil
+}
@@ -28,6 +28,9 @@ func (a *Actions) AuthenticateAccount(sessionToken string) (models.Account, erro
 		if err != nil {
 			return models.Account{}, err
 		}
+		if account.IsDisabled() {
+			return models.Account{}, ErrInvalidSessionToken{}
+		}
 
 		err = a.cache.SetAuthenticatedAccount(sessionToken, account)
 		if err != nil {
@@ -68,7 +71,7 @@ type LoginWithUsernamePayload struct {
 
 func (a *Actions) LoginWithUsername(params LoginWithUsernameParams) (LoginWithUsernamePayload, error) {
 	account, err := a.app.GetAccountByUsername(params.Username)
-	if err != nil {
+	if err != nil || account.IsDisabled() {
 		return LoginWithUsernamePayload{}, ErrInvalidLoginCredientials{}
 	}
 
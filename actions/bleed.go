@@ -0,0 +1,194 @@
+package actions
+
+import (
+	"shs/app/models"
+	"slices"
+	"time"
+)
+
+type BleedEpisode struct {
+	Id                    uint      `json:"id"`
+	OccurredAt            time.Time `json:"occurred_at"`
+	Site                  string    `json:"site"`
+	SiteDetails           string    `json:"site_details"`
+	Cause                 string    `json:"cause"`
+	Severity              string    `json:"severity"`
+	Notes                 string    `json:"notes"`
+	PrescribedMedicineIds []uint    `json:"prescribed_medicine_ids"`
+	CreatedAt             time.Time `json:"created_at"`
+}
+
+func (b *BleedEpisode) FromModel(episode models.BleedEpisode) {
+	(*b) = BleedEpisode{
+		Id:                    episode.Id,
+		OccurredAt:            episode.OccurredAt,
+		Site:                  string(episode.Site),
+		SiteDetails:           episode.SiteDetails,
+		Cause:                 string(episode.Cause),
+		Severity:              string(episode.Severity),
+		Notes:                 episode.Notes,
+		PrescribedMedicineIds: make([]uint, 0, len(episode.Infusions)),
+		CreatedAt:             episode.CreatedAt,
+	}
+
+	for _, infusion := range episode.Infusions {
+		b.PrescribedMedicineIds = append(b.PrescribedMedicineIds, infusion.PrescribedMedicineId)
+	}
+}
+
+type CreateMyBleedEpisodeParams struct {
+	ActionContext
+	NewBleedEpisode BleedEpisode `json:"new_bleed_episode"`
+}
+
+type CreateMyBleedEpisodePayload struct {
+	Id uint `json:"id"`
+}
+
+// CreateMyBleedEpisode logs a bleed episode for the patient that owns the context's account,
+// where infusions can only be of the patient's own prescribed medicines.
+func (a *Actions) CreateMyBleedEpisode(params CreateMyBleedEpisodeParams) (CreateMyBleedEpisodePayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionWriteOwnVisit) {
+		return CreateMyBleedEpisodePayload{}, ErrPermissionDenied{}
+	}
+
+	episode := params.NewBleedEpisode
+	if episode.OccurredAt.IsZero() || episode.OccurredAt.After(time.Now().UTC()) {
+		return CreateMyBleedEpisodePayload{}, ErrValidation{Field: "occurred_at"}
+	}
+	if !slices.Contains(models.BleedSites(), models.BleedSite(episode.Site)) {
+		return CreateMyBleedEpisodePayload{}, ErrValidation{Field: "site"}
+	}
+	if !models.BleedSite(episode.Site).IsJoint() && episode.SiteDetails == "" {
+		return CreateMyBleedEpisodePayload{}, ErrValidation{Field: "site_details"}
+	}
+	if !slices.Contains(models.BleedCauses(), models.BleedCause(episode.Cause)) {
+		return CreateMyBleedEpisodePayload{}, ErrValidation{Field: "cause"}
+	}
+	if !slices.Contains(models.BleedSeverities(), models.BleedSeverity(episode.Severity)) {
+		return CreateMyBleedEpisodePayload{}, ErrValidation{Field: "severity"}
+	}
+
+	patient, err := a.app.GetMinimalPatientByPublicId(params.Account.Username)
+	if err != nil {
+		return CreateMyBleedEpisodePayload{}, err
+	}
+
+	infusedMedicineIds := slices.Compact(slices.Sorted(slices.Values(episode.PrescribedMedicineIds)))
+	infusedMedicines := make([]models.PrescribedMedicine, 0, len(infusedMedicineIds))
+	if len(infusedMedicineIds) > 0 {
+		infusedMedicines, err = a.app.ListPrescribedMedicinesByIds(infusedMedicineIds)
+		if err != nil {
+			return CreateMyBleedEpisodePayload{}, err
+		}
+	}
+	if len(infusedMedicines) != len(infusedMedicineIds) {
+		return CreateMyBleedEpisodePayload{}, ErrValidation{Field: "prescribed_medicine_ids"}
+	}
+
+	infusions := make([]models.BleedInfusion, 0, len(infusedMedicines))
+	for _, pm := range infusedMedicines {
+		if pm.PatientId != patient.Id {
+			return CreateMyBleedEpisodePayload{}, ErrPermissionDenied{}
+		}
+		infusions = append(infusions, models.BleedInfusion{
+			PrescribedMedicineId: pm.Id,
+		})
+	}
+
+	newEpisode, err := a.app.CreateBleedEpisode(models.BleedEpisode{
+		PatientId:   patient.Id,
+		AccountId:   params.Account.Id,
+		OccurredAt:  episode.OccurredAt.UTC(),
+		Site:        models.BleedSite(episode.Site),
+		SiteDetails: episode.SiteDetails,
+		Cause:       models.BleedCause(episode.Cause),
+		Severity:    models.BleedSeverity(episode.Severity),
+		Notes:       episode.Notes,
+		Infusions:   infusions,
+	}, infusedMedicines)
+	if err != nil {
+		return CreateMyBleedEpisodePayload{}, err
+	}
+
+	return CreateMyBleedEpisodePayload{
+		Id: newEpisode.Id,
+	}, nil
+}
+
+type ListMyBleedEpisodesParams struct {
+	ActionContext
+	From time.Time
+	To   time.Time
+}
+
+type ListMyBleedEpisodesPayload struct {
+	Data []BleedEpisode `json:"data"`
+}
+
+func (a *Actions) ListMyBleedEpisodes(params ListMyBleedEpisodesParams) (ListMyBleedEpisodesPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionReadOwnVisit) {
+		return ListMyBleedEpisodesPayload{}, ErrPermissionDenied{}
+	}
+
+	patient, err := a.app.GetMinimalPatientByPublicId(params.Account.Username)
+	if err != nil {
+		return ListMyBleedEpisodesPayload{}, err
+	}
+
+	outEpisodes, err := a.listPatientBleedEpisodes(patient.Id, params.From, params.To)
+	if err != nil {
+		return ListMyBleedEpisodesPayload{}, err
+	}
+
+	return ListMyBleedEpisodesPayload{
+		Data: outEpisodes,
+	}, nil
+}
+
+type ListPatientBleedEpisodesParams struct {
+	ActionContext
+	PatientId string
+	From      time.Time
+	To        time.Time
+}
+
+type ListPatientBleedEpisodesPayload struct {
+	Data []BleedEpisode `json:"data"`
+}
+
+func (a *Actions) ListPatientBleedEpisodes(params ListPatientBleedEpisodesParams) (ListPatientBleedEpisodesPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionReadOtherVisits) {
+		return ListPatientBleedEpisodesPayload{}, ErrPermissionDenied{}
+	}
+
+	patient, err := a.app.GetMinimalPatientByPublicId(params.PatientId)
+	if err != nil {
+		return ListPatientBleedEpisodesPayload{}, err
+	}
+
+	outEpisodes, err := a.listPatientBleedEpisodes(patient.Id, params.From, params.To)
+	if err != nil {
+		return ListPatientBleedEpisodesPayload{}, err
+	}
+
+	return ListPatientBleedEpisodesPayload{
+		Data: outEpisodes,
+	}, nil
+}
+
+func (a *Actions) listPatientBleedEpisodes(patientId uint, from, to time.Time) ([]BleedEpisode, error) {
+	episodes, err := a.app.ListPatientBleedEpisodes(patientId, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	outEpisodes := make([]BleedEpisode, 0, len(episodes))
+	for _, episode := range episodes {
+		outEpisode := new(BleedEpisode)
+		outEpisode.FromModel(episode)
+		outEpisodes = append(outEpisodes, *outEpisode)
+	}
+
+	return outEpisodes, nil
+}
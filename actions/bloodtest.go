@@ -1,36 +1,107 @@
 package actions
 
 import (
-	"errors"
+	"maps"
+	"math"
 	"shs/app/models"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 )
 
+const maxSeriesPoints = 1000
+
 type BloodTestField struct {
-	Id             uint                 `json:"id"`
-	Name           string               `json:"name"`
-	Unit           models.BlootTestUnit `json:"unit"`
-	MinValueNumber float64              `json:"min_value_number"`
-	MinValueString string               `json:"min_value_string"`
-	MaxValueNumber float64              `json:"max_value_number"`
-	MaxValueString string               `json:"max_value_string"`
+	Id                     uint                    `json:"id"`
+	Name                   string                  `json:"name"`
+	Unit                   models.BlootTestUnit    `json:"unit"`
+	Required               bool                    `json:"required"`
+	MinValueNumber         float64                 `json:"min_value_number"`
+	MinValueString         string                  `json:"min_value_string"`
+	MaxValueNumber         float64                 `json:"max_value_number"`
+	MaxValueString         string                  `json:"max_value_string"`
+	CriticalMinValueNumber *float64                `json:"critical_min_value_number"`
+	CriticalMaxValueNumber *float64                `json:"critical_max_value_number"`
+	Version                int                     `json:"version"`
+	Retired                bool                    `json:"retired"`
+	Versions               []BloodTestFieldVersion `json:"versions"`
+}
+
+type BloodTestFieldVersion struct {
+	Version                int                  `json:"version"`
+	Name                   string               `json:"name"`
+	Unit                   models.BlootTestUnit `json:"unit"`
+	Required               bool                 `json:"required"`
+	MinValueNumber         float64              `json:"min_value_number"`
+	MinValueString         string               `json:"min_value_string"`
+	MaxValueNumber         float64              `json:"max_value_number"`
+	MaxValueString         string               `json:"max_value_string"`
+	CriticalMinValueNumber *float64             `json:"critical_min_value_number"`
+	CriticalMaxValueNumber *float64             `json:"critical_max_value_number"`
+	Retired                bool                 `json:"retired"`
+	EffectiveFrom          time.Time            `json:"effective_from"`
+}
+
+func (f *BloodTestField) FromModel(field models.BloodTestField) {
+	versions := make([]BloodTestFieldVersion, 0, len(field.Versions))
+	for _, version := range field.Versions {
+		versions = append(versions, BloodTestFieldVersion{
+			Version:                version.Version,
+			Name:                   version.Name,
+			Unit:                   version.Unit,
+			Required:               version.Required,
+			MinValueNumber:         version.MinValueNumber,
+			MinValueString:         version.MinValueString,
+			MaxValueNumber:         version.MaxValueNumber,
+			MaxValueString:         version.MaxValueString,
+			CriticalMinValueNumber: version.CriticalMinValueNumber,
+			CriticalMaxValueNumber: version.CriticalMaxValueNumber,
+			Retired:                version.Retired,
+			EffectiveFrom:          version.EffectiveFrom,
+		})
+	}
+
+	(*f) = BloodTestField{
+		Id:                     field.Id,
+		Name:                   field.Name,
+		Unit:                   field.Unit,
+		Required:               field.Required,
+		MinValueNumber:         field.MinValueNumber,
+		MinValueString:         field.MinValueString,
+		MaxValueNumber:         field.MaxValueNumber,
+		MaxValueString:         field.MaxValueString,
+		CriticalMinValueNumber: field.CriticalMinValueNumber,
+		CriticalMaxValueNumber: field.CriticalMaxValueNumber,
+		Version:                field.Version,
+		Retired:                field.Retired,
+		Versions:               versions,
+	}
 }
 
 type BloodTest struct {
-	Id     uint             `json:"id"`
-	Name   string           `json:"name"`
-	Fields []BloodTestField `json:"fields"`
+	Id         uint             `json:"id"`
+	Name       string           `json:"name"`
+	Version    int              `json:"version"`
+	Fields     []BloodTestField `json:"fields"`
+	ArchivedAt *time.Time       `json:"archived_at"`
 }
 
 func (bt BloodTest) IntoModel() models.BloodTest {
 	bloodTestFields := make([]models.BloodTestField, 0, len(bt.Fields))
 	for _, field := range bt.Fields {
 		bloodTestFields = append(bloodTestFields, models.BloodTestField{
-			Name:           field.Name,
-			Unit:           field.Unit,
-			MinValueNumber: field.MinValueNumber,
-			MinValueString: field.MinValueString,
-			MaxValueNumber: field.MaxValueNumber,
-			MaxValueString: field.MaxValueString,
+			Id:                     field.Id,
+			Name:                   field.Name,
+			Unit:                   field.Unit,
+			Required:               field.Required,
+			MinValueNumber:         field.MinValueNumber,
+			MinValueString:         field.MinValueString,
+			MaxValueNumber:         field.MaxValueNumber,
+			MaxValueString:         field.MaxValueString,
+			CriticalMinValueNumber: field.CriticalMinValueNumber,
+			CriticalMaxValueNumber: field.CriticalMaxValueNumber,
+			Retired:                field.Retired,
 		})
 	}
 	return models.BloodTest{
@@ -42,21 +113,17 @@ func (bt BloodTest) IntoModel() models.BloodTest {
 func (bt *BloodTest) FromModel(bloodTest models.BloodTest) {
 	btFields := make([]BloodTestField, 0, len(bloodTest.Fields))
 	for _, field := range bloodTest.Fields {
-		btFields = append(btFields, BloodTestField{
-			Id:             field.Id,
-			Name:           field.Name,
-			Unit:           field.Unit,
-			MinValueNumber: field.MinValueNumber,
-			MinValueString: field.MinValueString,
-			MaxValueNumber: field.MaxValueNumber,
-			MaxValueString: field.MaxValueString,
-		})
+		outField := new(BloodTestField)
+		outField.FromModel(field)
+		btFields = append(btFields, *outField)
 	}
 
 	(*bt) = BloodTest{
-		Id:     bloodTest.Id,
-		Name:   bloodTest.Name,
-		Fields: btFields,
+		Id:         bloodTest.Id,
+		Name:       bloodTest.Name,
+		Version:    bloodTest.Version,
+		Fields:     btFields,
+		ArchivedAt: bloodTest.ArchivedAt,
 	}
 }
 
@@ -83,13 +150,94 @@ func (a *Actions) CreateBloodTest(params CreateBloodTestParams) (CreateBloodTest
 
 type UpdateBloodTestParams struct {
 	ActionContext
+	BloodTestId  uint      `json:"blood_test_id"`
+	NewBloodTest BloodTest `json:"new_blood_test"`
 }
 
 type UpdateBloodTestPayload struct {
+	Data BloodTest `json:"data"`
 }
 
+// UpdateBloodTest renames the blood test, and adds, changes or retires the given fields as the blood test's next version,
+// the results taken before the update keep being interpreted with the fields' previous versions.
 func (a *Actions) UpdateBloodTest(params UpdateBloodTestParams) (UpdateBloodTestPayload, error) {
-	return UpdateBloodTestPayload{}, errors.New("not implemented")
+	if !params.Account.HasPermission(models.AccountPermissionWriteBloodTest) {
+		return UpdateBloodTestPayload{}, ErrPermissionDenied{}
+	}
+
+	current, err := a.app.GetBloodTest(params.BloodTestId)
+	if err != nil {
+		return UpdateBloodTestPayload{}, err
+	}
+
+	bt := params.NewBloodTest.IntoModel()
+	bt.Name = strings.TrimSpace(bt.Name)
+	err = validateBloodTestUpdate(current, bt)
+	if err != nil {
+		return UpdateBloodTestPayload{}, err
+	}
+
+	updated, err := a.app.UpdateBloodTest(params.BloodTestId, bt)
+	if err != nil {
+		return UpdateBloodTestPayload{}, err
+	}
+
+	outBt := new(BloodTest)
+	outBt.FromModel(updated)
+
+	return UpdateBloodTestPayload{
+		Data: *outBt,
+	}, nil
+}
+
+// validateBloodTestUpdate checks the updated fields, and that the blood test's fields that aren't retired
+// still have unique names after the update.
+func validateBloodTestUpdate(current, bt models.BloodTest) error {
+	if bt.Name == "" {
+		return ErrValidation{Field: "name"}
+	}
+
+	fields := make(map[uint]models.BloodTestField, len(current.Fields))
+	for _, field := range current.Fields {
+		fields[field.Id] = field
+	}
+
+	updated := make(map[uint]bool, len(bt.Fields))
+	newFields := make([]models.BloodTestField, 0)
+	for i, field := range bt.Fields {
+		bt.Fields[i].Name = strings.TrimSpace(field.Name)
+		field.Name = bt.Fields[i].Name
+		if field.Name == "" {
+			return ErrValidation{Field: "fields.name"}
+		}
+		if !slices.Contains(models.BloodTestUnits(), field.Unit) {
+			return ErrValidation{Field: "fields.unit"}
+		}
+
+		if field.Id == 0 {
+			newFields = append(newFields, field)
+			continue
+		}
+		if _, ok := fields[field.Id]; !ok || updated[field.Id] {
+			return ErrValidation{Field: "fields.id"}
+		}
+		updated[field.Id] = true
+		fields[field.Id] = field
+	}
+
+	names := make(map[string]bool, len(fields)+len(newFields))
+	for _, field := range slices.Concat(slices.Collect(maps.Values(fields)), newFields) {
+		if field.Retired {
+			continue
+		}
+		name := strings.ToLower(field.Name)
+		if names[name] {
+			return ErrValidation{Field: "fields.name"}
+		}
+		names[name] = true
+	}
+
+	return nil
 }
 
 type DeleteBloodTestParams struct {
@@ -142,6 +290,7 @@ func (a *Actions) GetBloodTest(params GetBloodTestParams) (GetBloodTestPayload,
 
 type ListAllBloodTestsParams struct {
 	ActionContext
+	IncludeArchived bool
 }
 
 type ListAllBloodTestsPayload struct {
@@ -160,6 +309,9 @@ func (a *Actions) ListAllBloodTests(params ListAllBloodTestsParams) (ListAllBloo
 
 	outBloodTests := make([]BloodTest, 0, len(bloodTests))
 	for _, bt := range bloodTests {
+		if bt.IsArchived() && !params.IncludeArchived {
+			continue
+		}
 		outBt := new(BloodTest)
 		outBt.FromModel(bt)
 		outBloodTests = append(outBloodTests, *outBt)
@@ -169,3 +321,209 @@ func (a *Actions) ListAllBloodTests(params ListAllBloodTestsParams) (ListAllBloo
 		Data: outBloodTests,
 	}, nil
 }
+
+// validateBloodTestFilledFields checks the filled fields against the blood test's fields,
+// and sets every numeric field's number from its string value, which must be given.
+// Results that aren't pending must have all the required fields, counting the ones filled before.
+func validateBloodTestFilledFields(bt models.BloodTest, fields, filledBefore []models.BloodTestFilledField, pending bool) error {
+	btFields := make(map[uint]models.BloodTestField, len(bt.Fields))
+	for _, field := range bt.Fields {
+		btFields[field.Id] = field
+	}
+
+	filled := make(map[uint]bool, len(fields)+len(filledBefore))
+	for _, field := range filledBefore {
+		filled[field.BloodTestFieldId] = true
+	}
+
+	seen := make(map[uint]bool, len(fields))
+	for i, field := range fields {
+		btField, ok := btFields[field.BloodTestFieldId]
+		if !ok {
+			return ErrInvalidBloodTestResultField{
+				BloodTestFieldId: field.BloodTestFieldId,
+				Reason:           "not-in-blood-test",
+			}
+		}
+		if seen[field.BloodTestFieldId] {
+			return ErrInvalidBloodTestResultField{
+				BloodTestFieldId: btField.Id,
+				FieldName:        btField.Name,
+				Reason:           "duplicate",
+			}
+		}
+		if filled[field.BloodTestFieldId] {
+			return ErrInvalidBloodTestResultField{
+				BloodTestFieldId: btField.Id,
+				FieldName:        btField.Name,
+				Reason:           "already-filled",
+			}
+		}
+		if btField.Retired {
+			return ErrInvalidBloodTestResultField{
+				BloodTestFieldId: btField.Id,
+				FieldName:        btField.Name,
+				Reason:           "retired",
+			}
+		}
+		seen[field.BloodTestFieldId] = true
+		filled[field.BloodTestFieldId] = true
+
+		if !btField.IsNumeric() {
+			if strings.TrimSpace(field.ValueString) == "" {
+				return ErrInvalidBloodTestResultField{
+					BloodTestFieldId: btField.Id,
+					FieldName:        btField.Name,
+					Reason:           "empty-value",
+				}
+			}
+			continue
+		}
+
+		if strings.TrimSpace(field.ValueString) == "" {
+			return ErrInvalidBloodTestResultField{
+				BloodTestFieldId: btField.Id,
+				FieldName:        btField.Name,
+				Reason:           "empty-value",
+			}
+		}
+
+		value, err := strconv.ParseFloat(strings.TrimSpace(field.ValueString), 64)
+		if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+			return ErrInvalidBloodTestResultField{
+				BloodTestFieldId: btField.Id,
+				FieldName:        btField.Name,
+				Reason:           "not-a-number",
+			}
+		}
+		fields[i].ValueNumber = value
+	}
+
+	if pending {
+		return nil
+	}
+
+	for _, btField := range bt.Fields {
+		if btField.Required && !btField.Retired && !filled[btField.Id] {
+			return ErrInvalidBloodTestResultField{
+				BloodTestFieldId: btField.Id,
+				FieldName:        btField.Name,
+				Reason:           "missing-required",
+			}
+		}
+	}
+
+	return nil
+}
+
+type BloodTestFieldPoint struct {
+	BloodTestResultId uint      `json:"blood_test_result_id"`
+	Value             float64   `json:"value"`
+	MeasuredAt        time.Time `json:"measured_at"`
+	Flag              string    `json:"flag"`
+	Min               float64   `json:"min"`
+	Max               float64   `json:"max"`
+	Count             int       `json:"count"`
+}
+
+type GetPatientBloodTestFieldSeriesParams struct {
+	ActionContext
+	PatientId string
+	FieldId   uint
+	From      time.Time
+	To        time.Time
+	MaxPoints int
+}
+
+type GetPatientBloodTestFieldSeriesPayload struct {
+	Field       BloodTestField        `json:"field"`
+	Points      []BloodTestFieldPoint `json:"points"`
+	TotalPoints int                   `json:"total_points"`
+	Downsampled bool                  `json:"downsampled"`
+}
+
+// GetPatientBloodTestFieldSeries returns a numeric field's values over time in the patient's results,
+// with the field's unit and reference range, averaged into at most MaxPoints points when it's set.
+func (a *Actions) GetPatientBloodTestFieldSeries(params GetPatientBloodTestFieldSeriesParams) (GetPatientBloodTestFieldSeriesPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionReadPatient) {
+		return GetPatientBloodTestFieldSeriesPayload{}, ErrPermissionDenied{}
+	}
+	if !params.Account.HasPermission(models.AccountPermissionReadBloodTest) {
+		return GetPatientBloodTestFieldSeriesPayload{}, ErrPermissionDenied{}
+	}
+
+	if params.MaxPoints < 0 || params.MaxPoints > maxSeriesPoints {
+		return GetPatientBloodTestFieldSeriesPayload{}, ErrValidation{Field: "max_points"}
+	}
+	if !params.From.IsZero() && !params.To.IsZero() && !params.From.Before(params.To) {
+		return GetPatientBloodTestFieldSeriesPayload{}, ErrValidation{Field: "from"}
+	}
+
+	patient, err := a.app.GetMinimalPatientByPublicId(params.PatientId)
+	if err != nil {
+		return GetPatientBloodTestFieldSeriesPayload{}, err
+	}
+
+	field, err := a.app.GetBloodTestField(params.FieldId)
+	if err != nil {
+		return GetPatientBloodTestFieldSeriesPayload{}, err
+	}
+	if !field.IsNumeric() {
+		return GetPatientBloodTestFieldSeriesPayload{}, ErrValidation{Field: "field_id"}
+	}
+
+	points, totalPoints, err := a.app.GetPatientBloodTestFieldSeries(patient.Id, field.Id, params.From, params.To, params.MaxPoints)
+	if err != nil {
+		return GetPatientBloodTestFieldSeriesPayload{}, err
+	}
+
+	outField := new(BloodTestField)
+	outField.FromModel(field)
+
+	outPoints := make([]BloodTestFieldPoint, 0, len(points))
+	for _, point := range points {
+		if point.Count == 0 {
+			point.Min, point.Max, point.Count = point.Value, point.Value, 1
+		}
+		outPoints = append(outPoints, BloodTestFieldPoint{
+			BloodTestResultId: point.BloodTestResultId,
+			Value:             point.Value,
+			MeasuredAt:        point.MeasuredAt,
+			Flag:              string(field.At(point.MeasuredAt).Flag(point.Value)),
+			Min:               point.Min,
+			Max:               point.Max,
+			Count:             point.Count,
+		})
+	}
+
+	return GetPatientBloodTestFieldSeriesPayload{
+		Field:       *outField,
+		Points:      outPoints,
+		TotalPoints: totalPoints,
+		Downsampled: len(points) < totalPoints,
+	}, nil
+}
+
+type SetBloodTestArchivedParams struct {
+	ActionContext
+	BloodTestId uint
+	Archived    bool
+}
+
+type SetBloodTestArchivedPayload struct {
+}
+
+// SetBloodTestArchived archives the blood test, which hides it from new results while the records that have it still show it,
+// or unarchives it.
+func (a *Actions) SetBloodTestArchived(params SetBloodTestArchivedParams) (SetBloodTestArchivedPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionWriteBloodTest) {
+		return SetBloodTestArchivedPayload{}, ErrPermissionDenied{}
+	}
+
+	err := a.app.SetBloodTestArchived(params.BloodTestId, params.Archived)
+	if err != nil {
+		return SetBloodTestArchivedPayload{}, err
+	}
+
+	return SetBloodTestArchivedPayload{}, nil
+}
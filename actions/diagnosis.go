@@ -1,32 +1,96 @@
 package actions
 
 import (
+	"math"
+	"shs/app"
 	"shs/app/models"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 )
 
+type DiagnosisField struct {
+	Id       uint                      `json:"id"`
+	Name     string                    `json:"name"`
+	Type     models.DiagnosisFieldType `json:"type"`
+	Required bool                      `json:"required"`
+	Choices  []string                  `json:"choices"`
+	Unit     string                    `json:"unit"`
+}
+
 type Diagnosis struct {
-	Id        uint   `json:"id"`
-	GroupName string `json:"group_name"`
-	Title     string `json:"title"`
+	Id        uint             `json:"id"`
+	GroupName string           `json:"group_name"`
+	Title     string           `json:"title"`
+	Fields    []DiagnosisField `json:"fields"`
 
-	CreatedAt time.Time `json:"created_at"`
+	ArchivedAt *time.Time `json:"archived_at"`
+	CreatedAt  time.Time  `json:"created_at"`
 }
 
 func (d Diagnosis) IntoModel() models.Diagnosis {
+	fields := make([]models.DiagnosisField, 0, len(d.Fields))
+	for _, field := range d.Fields {
+		fields = append(fields, models.DiagnosisField{
+			Name:     strings.TrimSpace(field.Name),
+			Type:     field.Type,
+			Required: field.Required,
+			Choices:  strings.Join(field.Choices, "\n"),
+			Unit:     strings.TrimSpace(field.Unit),
+		})
+	}
+
 	return models.Diagnosis{
 		GroupName: d.GroupName,
 		Title:     d.Title,
+		Fields:    fields,
 	}
 }
 
 func (d *Diagnosis) FromModel(diagnosis models.Diagnosis) {
+	fields := make([]DiagnosisField, 0, len(diagnosis.Fields))
+	for _, field := range diagnosis.Fields {
+		fields = append(fields, DiagnosisField{
+			Id:       field.Id,
+			Name:     field.Name,
+			Type:     field.Type,
+			Required: field.Required,
+			Choices:  field.ChoicesList(),
+			Unit:     field.Unit,
+		})
+	}
+
 	(*d).Id = diagnosis.Id
 	(*d).GroupName = diagnosis.GroupName
 	(*d).Title = diagnosis.Title
+	(*d).Fields = fields
+	(*d).ArchivedAt = diagnosis.ArchivedAt
 	(*d).CreatedAt = diagnosis.CreatedAt
 }
 
+// validateDiagnosisFields checks that the diagnosis's fields have unique names and known types,
+// and that choice fields have choices.
+func validateDiagnosisFields(diagnosis models.Diagnosis) error {
+	names := make(map[string]bool, len(diagnosis.Fields))
+	for _, field := range diagnosis.Fields {
+		name := strings.ToLower(field.Name)
+		if name == "" || names[name] {
+			return ErrValidation{Field: "fields.name"}
+		}
+		names[name] = true
+
+		if !slices.Contains(models.DiagnosisFieldTypes(), field.Type) {
+			return ErrValidation{Field: "fields.type"}
+		}
+		if field.Type == models.DiagnosisFieldTypeChoice && len(field.ChoicesList()) == 0 {
+			return ErrValidation{Field: "fields.choices"}
+		}
+	}
+
+	return nil
+}
+
 type CreateDiagnosisParams struct {
 	ActionContext
 	Diagnosis Diagnosis `json:"new_diagnosis"`
@@ -40,7 +104,13 @@ func (a *Actions) CreateDiagnosis(params CreateDiagnosisParams) (CreateDiagnosis
 		return CreateDiagnosisPayload{}, ErrPermissionDenied{}
 	}
 
-	_, err := a.app.CreateDiagnosis(params.Diagnosis.IntoModel())
+	diagnosis := params.Diagnosis.IntoModel()
+	err := validateDiagnosisFields(diagnosis)
+	if err != nil {
+		return CreateDiagnosisPayload{}, err
+	}
+
+	_, err = a.app.CreateDiagnosis(diagnosis)
 	if err != nil {
 		return CreateDiagnosisPayload{}, err
 	}
@@ -50,6 +120,7 @@ func (a *Actions) CreateDiagnosis(params CreateDiagnosisParams) (CreateDiagnosis
 
 type ListAllDiagnosesParams struct {
 	ActionContext
+	IncludeArchived bool
 }
 
 type ListAllDiagnosesPayload struct {
@@ -68,6 +139,9 @@ func (a *Actions) ListAllDiagnoses(params ListAllDiagnosesParams) (ListAllDiagno
 
 	outDiagnoses := make([]Diagnosis, 0, len(diagnoses))
 	for _, d := range diagnoses {
+		if d.IsArchived() && !params.IncludeArchived {
+			continue
+		}
 		outDiagnosis := new(Diagnosis)
 		outDiagnosis.FromModel(d)
 		outDiagnoses = append(outDiagnoses, *outDiagnosis)
@@ -98,3 +172,299 @@ func (a *Actions) DeleteDiagnosis(params DeleteDiagnosisParams) (DeleteDiagnosis
 
 	return DeleteDiagnosisPayload{}, nil
 }
+
+type SetDiagnosisArchivedParams struct {
+	ActionContext
+	DiagnosisId uint
+	Archived    bool
+}
+
+type SetDiagnosisArchivedPayload struct {
+}
+
+// SetDiagnosisArchived archives the diagnosis, which hides it from new diagnoses while the records that have it still show it,
+// or unarchives it.
+func (a *Actions) SetDiagnosisArchived(params SetDiagnosisArchivedParams) (SetDiagnosisArchivedPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionWriteDiagnoses) {
+		return SetDiagnosisArchivedPayload{}, ErrPermissionDenied{}
+	}
+
+	err := a.app.SetDiagnosisArchived(params.DiagnosisId, params.Archived)
+	if err != nil {
+		return SetDiagnosisArchivedPayload{}, err
+	}
+
+	return SetDiagnosisArchivedPayload{}, nil
+}
+
+// validateDiagnosisFilledFields checks the filled fields against the diagnosis's fields, and sets their values in their canonical forms,
+// where every required field must be filled.
+func validateDiagnosisFilledFields(diagnosis models.Diagnosis, fields []models.DiagnosisFilledField) error {
+	diagnosisFields := make(map[uint]models.DiagnosisField, len(diagnosis.Fields))
+	for _, field := range diagnosis.Fields {
+		diagnosisFields[field.Id] = field
+	}
+
+	filled := make(map[uint]bool, len(fields))
+	for i, field := range fields {
+		diagnosisField, ok := diagnosisFields[field.DiagnosisFieldId]
+		if !ok {
+			return ErrInvalidDiagnosisResultField{
+				DiagnosisFieldId: field.DiagnosisFieldId,
+				Reason:           "not-in-diagnosis",
+			}
+		}
+		if filled[field.DiagnosisFieldId] {
+			return ErrInvalidDiagnosisResultField{
+				DiagnosisFieldId: diagnosisField.Id,
+				FieldName:        diagnosisField.Name,
+				Reason:           "duplicate",
+			}
+		}
+		filled[field.DiagnosisFieldId] = true
+
+		value := strings.TrimSpace(field.ValueString)
+		if value == "" {
+			return ErrInvalidDiagnosisResultField{
+				DiagnosisFieldId: diagnosisField.Id,
+				FieldName:        diagnosisField.Name,
+				Reason:           "empty-value",
+			}
+		}
+
+		invalid := ErrInvalidDiagnosisResultField{
+			DiagnosisFieldId: diagnosisField.Id,
+			FieldName:        diagnosisField.Name,
+			Reason:           "not-a-" + string(diagnosisField.Type),
+		}
+		switch diagnosisField.Type {
+		case models.DiagnosisFieldTypeNumber:
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+				return invalid
+			}
+			fields[i].ValueNumber = number
+			value = strconv.FormatFloat(number, 'f', -1, 64)
+		case models.DiagnosisFieldTypeBoolean:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return invalid
+			}
+			value = strconv.FormatBool(b)
+		case models.DiagnosisFieldTypeDate:
+			date, err := time.Parse(time.DateOnly, value)
+			if err != nil {
+				return invalid
+			}
+			value = date.Format(time.DateOnly)
+		case models.DiagnosisFieldTypeChoice:
+			choiceIdx := slices.IndexFunc(diagnosisField.ChoicesList(), func(choice string) bool {
+				return strings.EqualFold(choice, value)
+			})
+			if choiceIdx < 0 {
+				return invalid
+			}
+			value = diagnosisField.ChoicesList()[choiceIdx]
+		}
+		fields[i].ValueString = value
+	}
+
+	for _, field := range diagnosis.Fields {
+		if field.Required && !filled[field.Id] {
+			return ErrInvalidDiagnosisResultField{
+				DiagnosisFieldId: field.Id,
+				FieldName:        field.Name,
+				Reason:           "missing-required",
+			}
+		}
+	}
+
+	return nil
+}
+
+// diagnosisResultFromParams validates the patient's result of the diagnosis, which is diagnosed now when it's not given.
+func diagnosisResultFromParams(diagnosis models.Diagnosis, patientId, accountId uint, params DiagnosisResult) (models.DiagnosisResult, error) {
+	if params.DiagnosedAt.IsZero() {
+		params.DiagnosedAt = time.Now().UTC()
+	}
+	if params.DiagnosedAt.After(time.Now().UTC()) {
+		return models.DiagnosisResult{}, ErrValidation{Field: "diagnosed_at"}
+	}
+
+	filledFields := make([]models.DiagnosisFilledField, 0, len(params.FilledFields))
+	for _, field := range params.FilledFields {
+		filledFields = append(filledFields, models.DiagnosisFilledField{
+			DiagnosisFieldId: field.DiagnosisFieldId,
+			ValueString:      field.Value,
+		})
+	}
+
+	err := validateDiagnosisFilledFields(diagnosis, filledFields)
+	if err != nil {
+		return models.DiagnosisResult{}, err
+	}
+
+	return models.DiagnosisResult{
+		DiagnosisId:   diagnosis.Id,
+		PatientId:     patientId,
+		DiagnosedAt:   params.DiagnosedAt,
+		DiagnosedById: accountId,
+		FilledFields:  filledFields,
+		Notes:         strings.TrimSpace(params.Notes),
+	}, nil
+}
+
+// getPatientDiagnosisResult gets the patient's active result, with its diagnosis.
+func (a *Actions) getPatientDiagnosisResult(patientId, resultId uint) (models.DiagnosisResult, error) {
+	dr, err := a.app.GetDiagnosisResult(resultId)
+	if err != nil {
+		return models.DiagnosisResult{}, err
+	}
+	if dr.PatientId != patientId {
+		return models.DiagnosisResult{}, &app.ErrNotFound{
+			ResourceName: "diagnosis_result",
+		}
+	}
+	if !dr.IsActive() {
+		return models.DiagnosisResult{}, &app.ErrInactiveDiagnosisResult{
+			ReplacedById: dr.ReplacedById,
+		}
+	}
+
+	dr.Diagnosis, err = a.app.GetDiagnosis(dr.DiagnosisId)
+	if err != nil {
+		return models.DiagnosisResult{}, err
+	}
+
+	return dr, nil
+}
+
+type UpdatePatientDiagnosisResultParams struct {
+	ActionContext
+	PatientPublicId   string
+	DiagnosisResultId uint
+	Diagnosis         DiagnosisResult `json:"patient_diagnosis"`
+}
+
+type UpdatePatientDiagnosisResultPayload struct {
+	Data DiagnosisResult `json:"data"`
+}
+
+// UpdatePatientDiagnosisResult replaces the patient's active result with the updated one,
+// the replaced result stays in the patient's diagnoses history.
+func (a *Actions) UpdatePatientDiagnosisResult(params UpdatePatientDiagnosisResultParams) (UpdatePatientDiagnosisResultPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionWritePatient) {
+		return UpdatePatientDiagnosisResultPayload{}, ErrPermissionDenied{}
+	}
+	if !params.Account.HasPermission(models.AccountPermissionWriteDiagnoses) {
+		return UpdatePatientDiagnosisResultPayload{}, ErrPermissionDenied{}
+	}
+
+	patient, err := a.app.GetMinimalPatientByPublicId(params.PatientPublicId)
+	if err != nil {
+		return UpdatePatientDiagnosisResultPayload{}, err
+	}
+
+	current, err := a.getPatientDiagnosisResult(patient.Id, params.DiagnosisResultId)
+	if err != nil {
+		return UpdatePatientDiagnosisResultPayload{}, err
+	}
+
+	dr, err := diagnosisResultFromParams(current.Diagnosis, patient.Id, params.Account.Id, params.Diagnosis)
+	if err != nil {
+		return UpdatePatientDiagnosisResultPayload{}, err
+	}
+
+	dr, err = a.app.ReplaceDiagnosisResult(current.Id, dr)
+	if err != nil {
+		return UpdatePatientDiagnosisResultPayload{}, err
+	}
+	dr.Diagnosis = current.Diagnosis
+
+	outDr := new(DiagnosisResult)
+	outDr.FromModel(dr)
+
+	return UpdatePatientDiagnosisResultPayload{
+		Data: *outDr,
+	}, nil
+}
+
+type RevokePatientDiagnosisResultParams struct {
+	ActionContext
+	PatientPublicId   string
+	DiagnosisResultId uint
+	Reason            string
+}
+
+type RevokePatientDiagnosisResultPayload struct {
+}
+
+// RevokePatientDiagnosisResult revokes the patient's active result, which stays in the patient's diagnoses history.
+func (a *Actions) RevokePatientDiagnosisResult(params RevokePatientDiagnosisResultParams) (RevokePatientDiagnosisResultPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionWritePatient) {
+		return RevokePatientDiagnosisResultPayload{}, ErrPermissionDenied{}
+	}
+	if !params.Account.HasPermission(models.AccountPermissionWriteDiagnoses) {
+		return RevokePatientDiagnosisResultPayload{}, ErrPermissionDenied{}
+	}
+
+	reason := strings.TrimSpace(params.Reason)
+	if reason == "" {
+		return RevokePatientDiagnosisResultPayload{}, ErrValidation{Field: "reason"}
+	}
+
+	patient, err := a.app.GetMinimalPatientByPublicId(params.PatientPublicId)
+	if err != nil {
+		return RevokePatientDiagnosisResultPayload{}, err
+	}
+
+	current, err := a.getPatientDiagnosisResult(patient.Id, params.DiagnosisResultId)
+	if err != nil {
+		return RevokePatientDiagnosisResultPayload{}, err
+	}
+
+	err = a.app.RevokeDiagnosisResult(current.Id, params.Account.Id, reason)
+	if err != nil {
+		return RevokePatientDiagnosisResultPayload{}, err
+	}
+
+	return RevokePatientDiagnosisResultPayload{}, nil
+}
+
+type ListPatientDiagnosisResultsParams struct {
+	ActionContext
+	PatientPublicId string
+}
+
+type ListPatientDiagnosisResultsPayload struct {
+	Data []DiagnosisResult `json:"data"`
+}
+
+// ListPatientDiagnosisResults lists the patient's diagnoses history, including the revoked and replaced results, oldest diagnosed first.
+func (a *Actions) ListPatientDiagnosisResults(params ListPatientDiagnosisResultsParams) (ListPatientDiagnosisResultsPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionReadPatient) {
+		return ListPatientDiagnosisResultsPayload{}, ErrPermissionDenied{}
+	}
+	if !params.Account.HasPermission(models.AccountPermissionReadDiagnoses) {
+		return ListPatientDiagnosisResultsPayload{}, ErrPermissionDenied{}
+	}
+
+	patient, err := a.app.GetMinimalPatientByPublicId(params.PatientPublicId)
+	if err != nil {
+		return ListPatientDiagnosisResultsPayload{}, err
+	}
+
+	diagnoses, err := a.app.ListAllDiagnoses()
+	if err != nil {
+		return ListPatientDiagnosisResultsPayload{}, err
+	}
+
+	results, err := a.app.ListPatientDiagnosisResults(patient.Id)
+	if err != nil {
+		return ListPatientDiagnosisResultsPayload{}, err
+	}
+
+	return ListPatientDiagnosisResultsPayload{
+		Data: diagnosisResultsFromModels(results, diagnoses, false),
+	}, nil
+}
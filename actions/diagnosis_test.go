@@ -0,0 +1,116 @@
+package actions
+
+import (
+	"shs/app/models"
+	"slices"
+	"testing"
+)
+
+func TestValidateDiagnosisFilledFields(t *testing.T) {
+	diagnosis := models.Diagnosis{
+		Id: 1,
+		Fields: []models.DiagnosisField{
+			{Id: 1, Name: "Type", Type: models.DiagnosisFieldTypeChoice, Required: true, Choices: "A\nB"},
+			{Id: 2, Name: "Factor level", Type: models.DiagnosisFieldTypeNumber},
+			{Id: 3, Name: "Carrier", Type: models.DiagnosisFieldTypeBoolean},
+			{Id: 4, Name: "Onset", Type: models.DiagnosisFieldTypeDate},
+			{Id: 5, Name: "Notes", Type: models.DiagnosisFieldTypeText},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		fields     []models.DiagnosisFilledField
+		wantFields []models.DiagnosisFilledField
+		wantReason string
+	}{
+		{
+			name: "values are set in their canonical forms",
+			fields: []models.DiagnosisFilledField{
+				{DiagnosisFieldId: 1, ValueString: " a "},
+				{DiagnosisFieldId: 2, ValueString: "0.50"},
+				{DiagnosisFieldId: 3, ValueString: "TRUE"},
+				{DiagnosisFieldId: 4, ValueString: "2024-01-02"},
+				{DiagnosisFieldId: 5, ValueString: " mild bleeding "},
+			},
+			wantFields: []models.DiagnosisFilledField{
+				{DiagnosisFieldId: 1, ValueString: "A"},
+				{DiagnosisFieldId: 2, ValueString: "0.5", ValueNumber: 0.5},
+				{DiagnosisFieldId: 3, ValueString: "true"},
+				{DiagnosisFieldId: 4, ValueString: "2024-01-02"},
+				{DiagnosisFieldId: 5, ValueString: "mild bleeding"},
+			},
+		},
+		{
+			name:       "missing required field",
+			fields:     []models.DiagnosisFilledField{{DiagnosisFieldId: 2, ValueString: "3"}},
+			wantReason: "missing-required",
+		},
+		{
+			name:       "field of another diagnosis",
+			fields:     []models.DiagnosisFilledField{{DiagnosisFieldId: 9, ValueString: "3"}},
+			wantReason: "not-in-diagnosis",
+		},
+		{
+			name: "duplicate field",
+			fields: []models.DiagnosisFilledField{
+				{DiagnosisFieldId: 1, ValueString: "A"},
+				{DiagnosisFieldId: 1, ValueString: "B"},
+			},
+			wantReason: "duplicate",
+		},
+		{
+			name:       "empty value",
+			fields:     []models.DiagnosisFilledField{{DiagnosisFieldId: 1, ValueString: "  "}},
+			wantReason: "empty-value",
+		},
+		{
+			name:       "unknown choice",
+			fields:     []models.DiagnosisFilledField{{DiagnosisFieldId: 1, ValueString: "C"}},
+			wantReason: "not-a-choice",
+		},
+		{
+			name: "invalid number",
+			fields: []models.DiagnosisFilledField{
+				{DiagnosisFieldId: 1, ValueString: "A"},
+				{DiagnosisFieldId: 2, ValueString: "NaN"},
+			},
+			wantReason: "not-a-number",
+		},
+		{
+			name: "invalid boolean",
+			fields: []models.DiagnosisFilledField{
+				{DiagnosisFieldId: 1, ValueString: "A"},
+				{DiagnosisFieldId: 3, ValueString: "maybe"},
+			},
+			wantReason: "not-a-boolean",
+		},
+		{
+			name: "invalid date",
+			fields: []models.DiagnosisFilledField{
+				{DiagnosisFieldId: 1, ValueString: "A"},
+				{DiagnosisFieldId: 4, ValueString: "02/01/2024"},
+			},
+			wantReason: "not-a-date",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDiagnosisFilledFields(diagnosis, tt.fields)
+			if tt.wantReason != "" {
+				fieldErr, ok := err.(ErrInvalidDiagnosisResultField)
+				if !ok || fieldErr.Reason != tt.wantReason {
+					t.Fatalf("validateDiagnosisFilledFields() error = %v, want reason %q", err, tt.wantReason)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateDiagnosisFilledFields() error = %v", err)
+			}
+			if !slices.Equal(tt.fields, tt.wantFields) {
+				t.Errorf("validateDiagnosisFilledFields() fields = %+v, want %+v", tt.fields, tt.wantFields)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package actions
+
+import (
+	"shs/app/models"
+	"time"
+)
+
+// defaultMinDuplicateScore is the least score of the listed duplicates when the request doesn't set one.
+const defaultMinDuplicateScore = 0.75
+
+type PatientDuplicate struct {
+	Patient   Patient  `json:"patient"`
+	Duplicate Patient  `json:"duplicate"`
+	Score     float64  `json:"score"`
+	MatchedOn []string `json:"matched_on"`
+}
+
+func (d *PatientDuplicate) FromModel(duplicate models.PatientDuplicate) {
+	outPatient := new(Patient)
+	outPatient.FromModel(duplicate.Patient)
+	outDuplicate := new(Patient)
+	outDuplicate.FromModel(duplicate.Duplicate)
+
+	(*d) = PatientDuplicate{
+		Patient:   *outPatient,
+		Duplicate: *outDuplicate,
+		Score:     duplicate.Score,
+		MatchedOn: duplicate.MatchedOn,
+	}
+}
+
+func duplicatesFromModels(duplicates []models.PatientDuplicate) []PatientDuplicate {
+	outDuplicates := make([]PatientDuplicate, 0, len(duplicates))
+	for _, duplicate := range duplicates {
+		outDuplicate := new(PatientDuplicate)
+		outDuplicate.FromModel(duplicate)
+		outDuplicates = append(outDuplicates, *outDuplicate)
+	}
+
+	return outDuplicates
+}
+
+func minDuplicateScore(minScore float64) (float64, error) {
+	if minScore == 0 {
+		return defaultMinDuplicateScore, nil
+	}
+	if minScore < 0 || minScore > 1 {
+		return 0, ErrValidation{Field: "min_score"}
+	}
+
+	return minScore, nil
+}
+
+type ListDuplicatePatientsParams struct {
+	ActionContext
+	MinScore float64
+}
+
+type ListDuplicatePatientsPayload struct {
+	Data []PatientDuplicate `json:"data"`
+}
+
+// ListDuplicatePatients lists the pairs of patients that are likely the same person, best scored first.
+func (a *Actions) ListDuplicatePatients(params ListDuplicatePatientsParams) (ListDuplicatePatientsPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionReadPatient) {
+		return ListDuplicatePatientsPayload{}, ErrPermissionDenied{}
+	}
+
+	minScore, err := minDuplicateScore(params.MinScore)
+	if err != nil {
+		return ListDuplicatePatientsPayload{}, err
+	}
+
+	duplicates, err := a.app.ListDuplicatePatients(minScore)
+	if err != nil {
+		return ListDuplicatePatientsPayload{}, err
+	}
+
+	return ListDuplicatePatientsPayload{
+		Data: duplicatesFromModels(duplicates),
+	}, nil
+}
+
+type ListPatientDuplicatesParams struct {
+	ActionContext
+	PatientId string
+	MinScore  float64
+}
+
+type ListPatientDuplicatesPayload struct {
+	Data []PatientDuplicate `json:"data"`
+}
+
+// ListPatientDuplicates lists the patients that are likely the same person as the patient, best scored first.
+func (a *Actions) ListPatientDuplicates(params ListPatientDuplicatesParams) (ListPatientDuplicatesPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionReadPatient) {
+		return ListPatientDuplicatesPayload{}, ErrPermissionDenied{}
+	}
+
+	minScore, err := minDuplicateScore(params.MinScore)
+	if err != nil {
+		return ListPatientDuplicatesPayload{}, err
+	}
+
+	patient, err := a.app.GetMinimalPatientByPublicId(params.PatientId)
+	if err != nil {
+		return ListPatientDuplicatesPayload{}, err
+	}
+
+	duplicates, err := a.app.ListPatientDuplicates(patient, minScore)
+	if err != nil {
+		return ListPatientDuplicatesPayload{}, err
+	}
+
+	return ListPatientDuplicatesPayload{
+		Data: duplicatesFromModels(duplicates),
+	}, nil
+}
+
+type PatientAlias struct {
+	AliasPublicId string    `json:"alias_public_id"`
+	PatientId     string    `json:"patient_id"`
+	MergedAt      time.Time `json:"merged_at"`
+}
+
+type MergePatientsParams struct {
+	ActionContext
+	PatientId       string
+	MergedPatientId string `json:"merged_patient_id"`
+}
+
+type MergePatientsPayload struct {
+	Data PatientAlias `json:"data"`
+}
+
+// MergePatients merges a duplicate patient into the patient, where the duplicate's records are moved to the patient,
+// the duplicate's public id keeps resolving to the patient, and the duplicate's account is disabled.
+func (a *Actions) MergePatients(params MergePatientsParams) (MergePatientsPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionWritePatient) {
+		return MergePatientsPayload{}, ErrPermissionDenied{}
+	}
+	if !params.Account.HasPermission(models.AccountPermissionWriteAccounts) {
+		return MergePatientsPayload{}, ErrPermissionDenied{}
+	}
+
+	survivor, err := a.app.GetMinimalPatientByPublicId(params.PatientId)
+	if err != nil {
+		return MergePatientsPayload{}, err
+	}
+
+	merged, err := a.app.GetMinimalPatientByPublicId(params.MergedPatientId)
+	if err != nil {
+		return MergePatientsPayload{}, err
+	}
+	// an already merged public id resolves to the patient it was merged into.
+	if merged.Id == survivor.Id {
+		return MergePatientsPayload{}, ErrValidation{Field: "merged_patient_id"}
+	}
+
+	alias, disabledAccountId, err := a.app.MergePatients(survivor.Id, merged.Id, params.Account.Id)
+	if err != nil {
+		return MergePatientsPayload{}, err
+	}
+
+	if disabledAccountId != 0 {
+		err = a.cache.InvalidateAuthenticatedAccountById(disabledAccountId)
+		if err != nil {
+			return MergePatientsPayload{}, err
+		}
+	}
+
+	return MergePatientsPayload{
+		Data: PatientAlias{
+			AliasPublicId: alias.AliasPublicId,
+			PatientId:     survivor.PublicId,
+			MergedAt:      alias.CreatedAt,
+		},
+	}, nil
+}
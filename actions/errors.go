@@ -1,6 +1,9 @@
 package actions
 
-import "net/http"
+import (
+	"net/http"
+	"time"
+)
 
 type ErrInvalidLoginCredientials struct{}
 
@@ -150,28 +153,118 @@ func (e ErrValidation) ExposeToClients() bool {
 	return true
 }
 
-type ErrInsufficientMedicine struct {
-	MedicineName    string
-	ExceedingAmount int
-	LeftPackages    int
+type ErrExpiredMedicine struct {
+	MedicineName string
+	BatchNumber  string
+	ExpiresAt    time.Time
 }
 
-func (e ErrInsufficientMedicine) Error() string {
-	return "insufficient-medicine-amount"
+func (e ErrExpiredMedicine) Error() string {
+	return "expired-medicine"
 }
 
-func (e ErrInsufficientMedicine) ClientStatusCode() int {
-	return http.StatusForbidden
+func (e ErrExpiredMedicine) ClientStatusCode() int {
+	return http.StatusConflict
+}
+
+func (e ErrExpiredMedicine) ExtraData() map[string]any {
+	return map[string]any{
+		"medicine_name": e.MedicineName,
+		"batch_number":  e.BatchNumber,
+		"expires_at":    e.ExpiresAt,
+	}
+}
+
+func (e ErrExpiredMedicine) ExposeToClients() bool {
+	return true
+}
+
+// ErrFirstExpiredFirstOut is returned when a batch is prescribed while another batch
+// of the same medicine expires sooner, the sooner to expire batch is suggested instead.
+type ErrFirstExpiredFirstOut struct {
+	MedicineName          string
+	BatchNumber           string
+	SuggestedMedicineId   uint
+	SuggestedBatchNumber  string
+	SuggestedExpiresAt    time.Time
+	SuggestedLeftPackages int
+}
+
+func (e ErrFirstExpiredFirstOut) Error() string {
+	return "first-expired-first-out"
+}
+
+func (e ErrFirstExpiredFirstOut) ClientStatusCode() int {
+	return http.StatusConflict
+}
+
+func (e ErrFirstExpiredFirstOut) ExtraData() map[string]any {
+	return map[string]any{
+		"medicine_name":           e.MedicineName,
+		"batch_number":            e.BatchNumber,
+		"suggested_medicine_id":   e.SuggestedMedicineId,
+		"suggested_batch_number":  e.SuggestedBatchNumber,
+		"suggested_expires_at":    e.SuggestedExpiresAt,
+		"suggested_left_packages": e.SuggestedLeftPackages,
+	}
+}
+
+func (e ErrFirstExpiredFirstOut) ExposeToClients() bool {
+	return true
+}
+
+// ErrInvalidBloodTestResultField is returned when a blood test result's filled field
+// doesn't fit its blood test's fields, or when a required field is missing.
+type ErrInvalidBloodTestResultField struct {
+	BloodTestFieldId uint
+	FieldName        string
+	Reason           string
+}
+
+func (e ErrInvalidBloodTestResultField) Error() string {
+	return "invalid-blood-test-result-field"
+}
+
+func (e ErrInvalidBloodTestResultField) ClientStatusCode() int {
+	return http.StatusBadRequest
+}
+
+func (e ErrInvalidBloodTestResultField) ExtraData() map[string]any {
+	return map[string]any{
+		"blood_test_field_id": e.BloodTestFieldId,
+		"field_name":          e.FieldName,
+		"reason":              e.Reason,
+	}
+}
+
+func (e ErrInvalidBloodTestResultField) ExposeToClients() bool {
+	return true
+}
+
+// ErrInvalidDiagnosisResultField is returned when a diagnosis result's filled field
+// doesn't fit its diagnosis's fields, or when a required field is missing.
+type ErrInvalidDiagnosisResultField struct {
+	DiagnosisFieldId uint
+	FieldName        string
+	Reason           string
+}
+
+func (e ErrInvalidDiagnosisResultField) Error() string {
+	return "invalid-diagnosis-result-field"
+}
+
+func (e ErrInvalidDiagnosisResultField) ClientStatusCode() int {
+	return http.StatusBadRequest
 }
 
-func (e ErrInsufficientMedicine) ExtraData() map[string]any {
+func (e ErrInvalidDiagnosisResultField) ExtraData() map[string]any {
 	return map[string]any{
-		"medicine_name":    e.MedicineName,
-		"exceeding_amount": e.ExceedingAmount,
-		"left_packages":    e.LeftPackages,
+		"diagnosis_field_id": e.DiagnosisFieldId,
+		"field_name":         e.FieldName,
+		"reason":             e.Reason,
 	}
 }
 
-func (e ErrInsufficientMedicine) ExposeToClients() bool {
+func (e ErrInvalidDiagnosisResultField) ExposeToClients() bool {
 	return true
 }
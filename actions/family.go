@@ -0,0 +1,381 @@
+package actions
+
+import (
+	"shs/app"
+	"shs/app/models"
+	"slices"
+	"strings"
+	"time"
+)
+
+type Relative struct {
+	Id            uint   `json:"id"`
+	Name          string `json:"name"`
+	Gender        bool   `json:"gender"`
+	CarrierStatus string `json:"carrier_status"`
+	Notes         string `json:"notes"`
+}
+
+func (r Relative) IntoModel() models.Relative {
+	return models.Relative{
+		Id:            r.Id,
+		Name:          strings.TrimSpace(r.Name),
+		Gender:        r.Gender,
+		CarrierStatus: models.CarrierStatus(r.CarrierStatus),
+		Notes:         strings.TrimSpace(r.Notes),
+	}
+}
+
+func (r *Relative) FromModel(relative models.Relative) {
+	(*r) = Relative{
+		Id:            relative.Id,
+		Name:          relative.Name,
+		Gender:        relative.Gender,
+		CarrierStatus: string(relative.CarrierStatus),
+		Notes:         relative.Notes,
+	}
+}
+
+func validateRelative(relative models.Relative) error {
+	if relative.Name == "" {
+		return ErrValidation{Field: "relative.name"}
+	}
+	if relative.CarrierStatus != "" && (relative.Gender || !slices.Contains(models.CarrierStatuses(), relative.CarrierStatus)) {
+		return ErrValidation{Field: "relative.carrier_status"}
+	}
+
+	return nil
+}
+
+type PatientRelationship struct {
+	Id                uint      `json:"id"`
+	PatientId         string    `json:"patient_id"`
+	RelativePatientId string    `json:"relative_patient_id"`
+	RelativeId        uint      `json:"relative_id"`
+	Relative          *Relative `json:"relative,omitempty"`
+	Type              string    `json:"type"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// FromModel sets the relationship from the model, where the patients' ids are replaced by their public ids.
+func (r *PatientRelationship) FromModel(relationship models.PatientRelationship, publicIds map[uint]string) {
+	(*r) = PatientRelationship{
+		Id:                relationship.Id,
+		PatientId:         publicIds[relationship.PatientId],
+		RelativePatientId: publicIds[relationship.RelativePatientId],
+		RelativeId:        relationship.RelativeId,
+		Type:              string(relationship.Type),
+		CreatedAt:         relationship.CreatedAt,
+	}
+}
+
+type PedigreeMember struct {
+	// PatientId is the public id of members that are patients, and RelativeId is the id of the rest.
+	PatientId          string `json:"patient_id"`
+	RelativeId         uint   `json:"relative_id"`
+	Name               string `json:"name"`
+	Gender             bool   `json:"gender"`
+	CarrierStatus      string `json:"carrier_status"`
+	HemophiliaType     string `json:"hemophilia_type"`
+	HemophiliaSeverity string `json:"hemophilia_severity"`
+	// Proband is set for the patient whose pedigree was requested.
+	Proband bool `json:"proband"`
+}
+
+type Pedigree struct {
+	Members       []PedigreeMember      `json:"members"`
+	Relationships []PatientRelationship `json:"relationships"`
+}
+
+func (p *Pedigree) FromModel(pedigree models.Pedigree, probandId uint) {
+	publicIds := make(map[uint]string, len(pedigree.Patients))
+	members := make([]PedigreeMember, 0, len(pedigree.Patients)+len(pedigree.Relatives))
+	for _, patient := range pedigree.Patients {
+		publicIds[patient.Id] = patient.PublicId
+		members = append(members, PedigreeMember{
+			PatientId:          patient.PublicId,
+			Name:               strings.TrimSpace(patient.FirstName + " " + patient.LastName),
+			Gender:             patient.Gender,
+			CarrierStatus:      string(patient.CarrierStatus),
+			HemophiliaType:     string(patient.HemophiliaType),
+			HemophiliaSeverity: string(patient.HemophiliaSeverity),
+			Proband:            patient.Id == probandId,
+		})
+	}
+	for _, relative := range pedigree.Relatives {
+		members = append(members, PedigreeMember{
+			RelativeId:    relative.Id,
+			Name:          relative.Name,
+			Gender:        relative.Gender,
+			CarrierStatus: string(relative.CarrierStatus),
+		})
+	}
+
+	relationships := make([]PatientRelationship, 0, len(pedigree.Relationships))
+	for _, relationship := range pedigree.Relationships {
+		outRelationship := new(PatientRelationship)
+		outRelationship.FromModel(relationship, publicIds)
+		relationships = append(relationships, *outRelationship)
+	}
+
+	(*p) = Pedigree{
+		Members:       members,
+		Relationships: relationships,
+	}
+}
+
+type RelationshipProposal struct {
+	Patient   Patient  `json:"patient"`
+	Type      string   `json:"type"`
+	MatchedOn []string `json:"matched_on"`
+}
+
+func (r *RelationshipProposal) FromModel(proposal models.RelationshipProposal) {
+	outPatient := new(Patient)
+	outPatient.FromModel(proposal.Patient)
+
+	(*r) = RelationshipProposal{
+		Patient:   *outPatient,
+		Type:      string(proposal.Type),
+		MatchedOn: proposal.MatchedOn,
+	}
+}
+
+type NewPatientRelationship struct {
+	Type string `json:"type"`
+	// the relative is either a patient by their public id, an existing relative by their id, or a new relative.
+	RelativePatientId string    `json:"relative_patient_id"`
+	RelativeId        uint      `json:"relative_id"`
+	Relative          *Relative `json:"relative"`
+}
+
+type CreatePatientRelationshipParams struct {
+	ActionContext
+	PatientId       string
+	NewRelationship NewPatientRelationship `json:"new_relationship"`
+}
+
+type CreatePatientRelationshipPayload struct {
+	Data PatientRelationship `json:"data"`
+}
+
+// CreatePatientRelationship links the patient to another patient, to a relative of the patient's family who isn't a patient,
+// or to a new relative who isn't a patient.
+func (a *Actions) CreatePatientRelationship(params CreatePatientRelationshipParams) (CreatePatientRelationshipPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionWritePatient) {
+		return CreatePatientRelationshipPayload{}, ErrPermissionDenied{}
+	}
+
+	relationshipType := models.RelationshipType(params.NewRelationship.Type)
+	if !slices.Contains(models.RelationshipTypes(), relationshipType) {
+		return CreatePatientRelationshipPayload{}, ErrValidation{Field: "type"}
+	}
+
+	patient, err := a.app.GetMinimalPatientByPublicId(params.PatientId)
+	if err != nil {
+		return CreatePatientRelationshipPayload{}, err
+	}
+
+	relationship := models.PatientRelationship{
+		PatientId: patient.Id,
+		Type:      relationshipType,
+		AccountId: params.Account.Id,
+	}
+	publicIds := map[uint]string{
+		patient.Id: patient.PublicId,
+	}
+	var relative models.Relative
+
+	switch {
+	case params.NewRelationship.RelativePatientId != "":
+		relativePatient, err := a.app.GetMinimalPatientByPublicId(params.NewRelationship.RelativePatientId)
+		if err != nil {
+			return CreatePatientRelationshipPayload{}, err
+		}
+		if relativePatient.Id == patient.Id {
+			return CreatePatientRelationshipPayload{}, ErrValidation{Field: "relative_patient_id"}
+		}
+		relationship.RelativePatientId = relativePatient.Id
+		publicIds[relativePatient.Id] = relativePatient.PublicId
+	case params.NewRelationship.RelativeId != 0:
+		relative, err = a.app.GetRelative(params.NewRelationship.RelativeId)
+		if err != nil {
+			return CreatePatientRelationshipPayload{}, err
+		}
+		relationship.RelativeId = relative.Id
+	case params.NewRelationship.Relative != nil:
+		relative = params.NewRelationship.Relative.IntoModel()
+		relative.Id = 0
+		relative.AccountId = params.Account.Id
+		err = validateRelative(relative)
+		if err != nil {
+			return CreatePatientRelationshipPayload{}, err
+		}
+	default:
+		return CreatePatientRelationshipPayload{}, ErrValidation{Field: "relative"}
+	}
+
+	relationship, err = a.app.CreatePatientRelationship(relationship, relative)
+	if err != nil {
+		return CreatePatientRelationshipPayload{}, err
+	}
+
+	outRelationship := new(PatientRelationship)
+	outRelationship.FromModel(relationship, publicIds)
+	if relationship.RelativeId != 0 {
+		relative.Id = relationship.RelativeId
+		outRelative := new(Relative)
+		outRelative.FromModel(relative)
+		outRelationship.Relative = outRelative
+	}
+
+	return CreatePatientRelationshipPayload{
+		Data: *outRelationship,
+	}, nil
+}
+
+type DeletePatientRelationshipParams struct {
+	ActionContext
+	PatientId      string
+	RelationshipId uint
+}
+
+type DeletePatientRelationshipPayload struct {
+}
+
+// DeletePatientRelationship unlinks one of the patient's relationships, either ones of the patient or ones to the patient.
+func (a *Actions) DeletePatientRelationship(params DeletePatientRelationshipParams) (DeletePatientRelationshipPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionWritePatient) {
+		return DeletePatientRelationshipPayload{}, ErrPermissionDenied{}
+	}
+
+	patient, err := a.app.GetMinimalPatientByPublicId(params.PatientId)
+	if err != nil {
+		return DeletePatientRelationshipPayload{}, err
+	}
+
+	relationship, err := a.app.GetPatientRelationship(params.RelationshipId)
+	if err != nil {
+		return DeletePatientRelationshipPayload{}, err
+	}
+	if relationship.PatientId != patient.Id && relationship.RelativePatientId != patient.Id {
+		return DeletePatientRelationshipPayload{}, &app.ErrNotFound{
+			ResourceName: "patient_relationship",
+		}
+	}
+
+	err = a.app.DeletePatientRelationship(relationship.Id)
+	if err != nil {
+		return DeletePatientRelationshipPayload{}, err
+	}
+
+	return DeletePatientRelationshipPayload{}, nil
+}
+
+type UpdateRelativeParams struct {
+	ActionContext
+	RelativeId  uint
+	NewRelative Relative `json:"new_relative"`
+}
+
+type UpdateRelativePayload struct {
+	Data Relative `json:"data"`
+}
+
+// UpdateRelative updates a relative who isn't a patient, like their carrier status.
+func (a *Actions) UpdateRelative(params UpdateRelativeParams) (UpdateRelativePayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionWritePatient) {
+		return UpdateRelativePayload{}, ErrPermissionDenied{}
+	}
+
+	relative := params.NewRelative.IntoModel()
+	relative.Id = params.RelativeId
+	relative.AccountId = params.Account.Id
+	err := validateRelative(relative)
+	if err != nil {
+		return UpdateRelativePayload{}, err
+	}
+
+	relative, err = a.app.UpdateRelative(relative)
+	if err != nil {
+		return UpdateRelativePayload{}, err
+	}
+
+	outRelative := new(Relative)
+	outRelative.FromModel(relative)
+
+	return UpdateRelativePayload{
+		Data: *outRelative,
+	}, nil
+}
+
+type GetPatientPedigreeParams struct {
+	ActionContext
+	PatientId string
+}
+
+type GetPatientPedigreePayload struct {
+	Data Pedigree `json:"data"`
+}
+
+// GetPatientPedigree returns the patient's family graph, with the patient marked as the proband.
+func (a *Actions) GetPatientPedigree(params GetPatientPedigreeParams) (GetPatientPedigreePayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionReadPatient) {
+		return GetPatientPedigreePayload{}, ErrPermissionDenied{}
+	}
+
+	patient, err := a.app.GetMinimalPatientByPublicId(params.PatientId)
+	if err != nil {
+		return GetPatientPedigreePayload{}, err
+	}
+
+	pedigree, err := a.app.GetPedigree(patient.Id)
+	if err != nil {
+		return GetPatientPedigreePayload{}, err
+	}
+
+	outPedigree := new(Pedigree)
+	outPedigree.FromModel(pedigree, patient.Id)
+
+	return GetPatientPedigreePayload{
+		Data: *outPedigree,
+	}, nil
+}
+
+type ListPatientRelationshipProposalsParams struct {
+	ActionContext
+	PatientId string
+}
+
+type ListPatientRelationshipProposalsPayload struct {
+	Data []RelationshipProposal `json:"data"`
+}
+
+// ListPatientRelationshipProposals proposes likely family links from the patients' names,
+// proposals aren't recorded until they're confirmed by creating the relationship.
+func (a *Actions) ListPatientRelationshipProposals(params ListPatientRelationshipProposalsParams) (ListPatientRelationshipProposalsPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionReadPatient) {
+		return ListPatientRelationshipProposalsPayload{}, ErrPermissionDenied{}
+	}
+
+	patient, err := a.app.GetMinimalPatientByPublicId(params.PatientId)
+	if err != nil {
+		return ListPatientRelationshipProposalsPayload{}, err
+	}
+
+	proposals, err := a.app.ListPatientRelationshipProposals(patient)
+	if err != nil {
+		return ListPatientRelationshipProposalsPayload{}, err
+	}
+
+	outProposals := make([]RelationshipProposal, 0, len(proposals))
+	for _, proposal := range proposals {
+		outProposal := new(RelationshipProposal)
+		outProposal.FromModel(proposal)
+		outProposals = append(outProposals, *outProposal)
+	}
+
+	return ListPatientRelationshipProposalsPayload{
+		Data: outProposals,
+	}, nil
+}
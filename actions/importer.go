@@ -4,6 +4,7 @@ import (
 	"encoding/csv"
 	"fmt"
 	"io"
+	"shs/app"
 	"shs/app/models"
 	"shs/log"
 	"slices"
@@ -222,16 +223,6 @@ func (a *Actions) ImportPatientsFromCsv(params ImportPatientsFromCsvParams) (Imp
 		inPatients = append(inPatients, patients[i])
 	}
 
-	for _, patient := range inPatients {
-		newPatient, err := a.app.CreatePatient(patient)
-		if err != nil {
-			log.Errorln("Failed to create patient: ", err)
-			continue
-		}
-
-		newPatients = append(newPatients, newPatient)
-	}
-
 	diagnoses, err := a.app.ListAllDiagnoses()
 	if err != nil {
 		log.Warningln("No diagnoses were found,", err)
@@ -250,116 +241,127 @@ func (a *Actions) ImportPatientsFromCsv(params ImportPatientsFromCsvParams) (Imp
 	if err != nil {
 		log.Warningln("No blood tests were found,", err)
 	}
+	bloodGroupIdx := slices.IndexFunc(bloodTests, func(bt models.BloodTest) bool {
+		return bt.Name == "Blood Group"
+	})
 	for key := range mPatientBloodGroup {
-		foundBtIdx := slices.IndexFunc(bloodTests, func(bt models.BloodTest) bool {
-			return bt.Name == "Blood Group"
-		})
-		if foundBtIdx > -1 {
-			mPatientBloodGroup[key].Id = bloodTests[foundBtIdx].Id
+		if bloodGroupIdx < 0 {
+			break
 		}
-		foundBtFieldAboIdx := slices.IndexFunc(bloodTests[foundBtIdx].Fields, func(btf models.BloodTestField) bool {
+		mPatientBloodGroup[key].Id = bloodTests[bloodGroupIdx].Id
+		foundBtFieldAboIdx := slices.IndexFunc(bloodTests[bloodGroupIdx].Fields, func(btf models.BloodTestField) bool {
 			return btf.Name == "ABO"
 		})
 		if foundBtFieldAboIdx > -1 {
-			mPatientBloodGroup[key].ABOFieldId = bloodTests[foundBtIdx].Fields[foundBtFieldAboIdx].Id
+			mPatientBloodGroup[key].ABOFieldId = bloodTests[bloodGroupIdx].Fields[foundBtFieldAboIdx].Id
 		}
-		foundBtFieldRhIdx := slices.IndexFunc(bloodTests[foundBtIdx].Fields, func(btf models.BloodTestField) bool {
+		foundBtFieldRhIdx := slices.IndexFunc(bloodTests[bloodGroupIdx].Fields, func(btf models.BloodTestField) bool {
 			return btf.Name == "Rh(D)"
 		})
 		if foundBtFieldRhIdx > -1 {
-			mPatientBloodGroup[key].RhFieldId = bloodTests[foundBtIdx].Fields[foundBtFieldRhIdx].Id
+			mPatientBloodGroup[key].RhFieldId = bloodTests[bloodGroupIdx].Fields[foundBtFieldRhIdx].Id
 		}
 	}
 
+	factorViiiIdx := slices.IndexFunc(bloodTests, func(bt models.BloodTest) bool {
+		return bt.Name == "Factor - VIII"
+	})
 	for key := range mPatientFactorVIII {
-		foundBtIdx := slices.IndexFunc(bloodTests, func(bt models.BloodTest) bool {
-			return bt.Name == "Factor - VIII"
-		})
-		if foundBtIdx > -1 {
-			mPatientFactorVIII[key].Id = bloodTests[foundBtIdx].Id
+		if factorViiiIdx < 0 {
+			break
 		}
-		foundBtFieldAboIdx := slices.IndexFunc(bloodTests[foundBtIdx].Fields, func(btf models.BloodTestField) bool {
+		mPatientFactorVIII[key].Id = bloodTests[factorViiiIdx].Id
+		foundBtFieldIdx := slices.IndexFunc(bloodTests[factorViiiIdx].Fields, func(btf models.BloodTestField) bool {
 			return btf.Name == "Factor - VIII"
 		})
-		if foundBtFieldAboIdx > -1 {
-			mPatientFactorVIII[key].FieldId = bloodTests[foundBtIdx].Fields[foundBtFieldAboIdx].Id
+		if foundBtFieldIdx > -1 {
+			mPatientFactorVIII[key].FieldId = bloodTests[factorViiiIdx].Fields[foundBtFieldIdx].Id
 		}
 	}
 
-	for _, patient := range newPatients {
-		patientDiagnosis, ok := patientDiagnoses[patient.IndexId()]
-		if !ok {
-			log.Warningf("Diagnosis was not found for patient '%s'\n", patient.IndexId())
-			continue
-		}
-
-		_, err := a.app.CreateDiagnosisResult(models.DiagnosisResult{
-			DiagnosisId: patientDiagnosis.Id,
-			PatientId:   patient.Id,
-			CreatedAt:   patientDiagnosis.CreatedAt,
-		})
-		if err != nil {
-			log.Warningf("Failed to assign '%s - %s' diagnosis to patient with id %s\n", patientDiagnosis.GroupName, patientDiagnosis.Title, patient.PublicId)
-			continue
-		}
-	}
-
-	for _, patient := range newPatients {
-		// blood groups
-		patientBloodGroup, ok := mPatientBloodGroup[patient.IndexId()]
-		if !ok {
-			log.Warningf("Blood group was not found for patient '%s'\n", patient.IndexId())
-			continue
-		}
-
-		_, err = a.app.CreateBloodTestResult(models.BloodTestResult{
-			CreatedAt:   patientBloodGroup.CreatedAt,
-			BloodTestId: patientBloodGroup.Id,
-			PatientId:   patient.Id,
-			FilledFields: []models.BloodTestFilledField{
-				{
-					CreatedAt:        patientBloodGroup.CreatedAt,
-					BloodTestFieldId: patientBloodGroup.RhFieldId,
-					ValueString:      patientBloodGroup.Rh,
-				},
-				{
-					CreatedAt:        patientBloodGroup.CreatedAt,
-					BloodTestFieldId: patientBloodGroup.ABOFieldId,
-					ValueString:      patientBloodGroup.ABO,
-				},
-			},
+	for _, patient := range inPatients {
+		var newPatient models.Patient
+		// each patient is imported with its results as a whole, so a failing row doesn't leave a half imported patient.
+		err = a.app.WithTx(func(txApp *app.App) error {
+			var err error
+			newPatient, err = txApp.CreatePatient(patient)
+			if err != nil {
+				return err
+			}
+
+			if patientDiagnosis, ok := patientDiagnoses[patient.IndexId()]; ok && patientDiagnosis.Id != 0 {
+				_, err = txApp.CreateDiagnosisResult(models.DiagnosisResult{
+					DiagnosisId: patientDiagnosis.Id,
+					PatientId:   newPatient.Id,
+					CreatedAt:   patientDiagnosis.CreatedAt,
+				})
+				if err != nil {
+					return err
+				}
+			} else {
+				log.Warningf("Diagnosis was not found for patient '%s'\n", patient.IndexId())
+			}
+
+			if patientBloodGroup, ok := mPatientBloodGroup[patient.IndexId()]; ok && patientBloodGroup.Id != 0 {
+				_, err = txApp.CreateBloodTestResult(models.BloodTestResult{
+					CreatedAt:   patientBloodGroup.CreatedAt,
+					BloodTestId: patientBloodGroup.Id,
+					PatientId:   newPatient.Id,
+					FilledFields: []models.BloodTestFilledField{
+						{
+							CreatedAt:        patientBloodGroup.CreatedAt,
+							BloodTestFieldId: patientBloodGroup.RhFieldId,
+							ValueString:      patientBloodGroup.Rh,
+						},
+						{
+							CreatedAt:        patientBloodGroup.CreatedAt,
+							BloodTestFieldId: patientBloodGroup.ABOFieldId,
+							ValueString:      patientBloodGroup.ABO,
+						},
+					},
+				})
+				if err != nil {
+					return err
+				}
+			} else {
+				log.Warningf("Blood group was not found for patient '%s'\n", patient.IndexId())
+			}
+
+			if patientFactor8, ok := mPatientFactorVIII[patient.IndexId()]; ok && patientFactor8.Id != 0 {
+				patientFactor8Value, parseErr := strconv.ParseFloat(patientFactor8.FactorViii, 64)
+				if parseErr != nil {
+					log.Warningf("Factor VIII '%s' is not a number for patient '%s'\n", patientFactor8.FactorViii, patient.IndexId())
+					return nil
+				}
+
+				_, err = txApp.CreateBloodTestResult(models.BloodTestResult{
+					CreatedAt:   patientFactor8.CreatedAt,
+					BloodTestId: patientFactor8.Id,
+					PatientId:   newPatient.Id,
+					FilledFields: []models.BloodTestFilledField{
+						{
+							CreatedAt:        patientFactor8.CreatedAt,
+							BloodTestFieldId: patientFactor8.FieldId,
+							ValueString:      patientFactor8.FactorViii,
+							ValueNumber:      patientFactor8Value,
+						},
+					},
+				})
+				if err != nil {
+					return err
+				}
+			} else {
+				log.Warningf("Factor VIII was not found for patient '%s'\n", patient.IndexId())
+			}
+
+			return nil
 		})
 		if err != nil {
-			continue
-		}
-	}
-
-	for _, patient := range newPatients {
-		// factor viii
-		patientFactor7, ok := mPatientFactorVIII[patient.IndexId()]
-		if !ok {
-			log.Warningf("Factor 7 was not found for patient '%s'\n", patient.IndexId())
+			log.Errorf("Failed to import patient '%s', error: %s\n", patient.IndexId(), err.Error())
 			continue
 		}
 
-		patientFactor7Value, _ := strconv.ParseFloat(patientFactor7.FactorViii, 64)
-
-		_, err = a.app.CreateBloodTestResult(models.BloodTestResult{
-			CreatedAt:   patientFactor7.CreatedAt,
-			BloodTestId: patientFactor7.Id,
-			PatientId:   patient.Id,
-			FilledFields: []models.BloodTestFilledField{
-				{
-					CreatedAt:        patientFactor7.CreatedAt,
-					BloodTestFieldId: patientFactor7.FieldId,
-					ValueString:      patientFactor7.FactorViii,
-					ValueNumber:      patientFactor7Value,
-				},
-			},
-		})
-		if err != nil {
-			continue
-		}
+		newPatients = append(newPatients, newPatient)
 	}
 
 	outIgnoredPatients := make([]Patient, len(ignoredPatients))
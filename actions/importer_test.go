@@ -0,0 +1,118 @@
+package actions
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTryParseTime(t *testing.T) {
+	tests := []struct {
+		dateStr string
+		want    time.Time
+		wantErr bool
+	}{
+		{dateStr: "4/3/2010", want: time.Date(2010, time.March, 4, 0, 0, 0, 0, time.UTC)},
+		{dateStr: "04/03/2010", want: time.Date(2010, time.March, 4, 0, 0, 0, 0, time.UTC)},
+		{dateStr: "4/03/2010", want: time.Date(2010, time.March, 4, 0, 0, 0, 0, time.UTC)},
+		{dateStr: "04/3/2010", want: time.Date(2010, time.March, 4, 0, 0, 0, 0, time.UTC)},
+		{dateStr: "2010-03-04", wantErr: true},
+		{dateStr: "31/2/2010", wantErr: true},
+		{dateStr: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dateStr, func(t *testing.T) {
+			got, err := tryParseTime(tt.dateStr)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("tryParseTime() = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("tryParseTime() error = %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("tryParseTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractCsvRecords(t *testing.T) {
+	header := "first_name,last_name,father_name,mother_name,nationality,national_id,gender,date_of_birth,phone_number," +
+		"pob_governorate,pob_suburb,pob_street,residency_governorate,residency_suburb,residency_street," +
+		"diagnosis,date_of_diagnosis,factor_viii,abo,rhd\n"
+
+	tests := []struct {
+		name string
+		csv  string
+		want []csvRow
+	}{
+		{
+			name: "only the header",
+			csv:  header,
+		},
+		{
+			name: "values are trimmed and the diagnosis is split",
+			csv: header +
+				" Ahmad ,Khalil,Omar,Huda, Syrian ,01020304050, MALE ,4/3/2010,0933000000," +
+				"Damascus,Mazzeh,Street 1,Damascus,Mazzeh,Street 2," +
+				" Hemophilia#Hemophilia A ,05/06/2012, 2% ,A,+\n",
+			want: []csvRow{{
+				FirstName:             "Ahmad",
+				LastName:              "Khalil",
+				FatherName:            "Omar",
+				MotherName:            "Huda",
+				Nationality:           "syrian",
+				NationalID:            "01020304050",
+				Gender:                "male",
+				DateOfBirth:           time.Date(2010, time.March, 4, 0, 0, 0, 0, time.UTC),
+				PhoneNumber:           "0933000000",
+				POB_Governorate:       "Damascus",
+				POB_Suburb:            "Mazzeh",
+				POB_Street:            "Street 1",
+				Residency_Governorate: "Damascus",
+				Residency_Suburb:      "Mazzeh",
+				Residency_Street:      "Street 2",
+				Diagnosis_GroupName:   "Hemophilia",
+				Diagnosis_Title:       "Hemophilia A",
+				DateOfDiagnosis:       time.Date(2012, time.June, 5, 0, 0, 0, 0, time.UTC),
+				FactorVIII:            "2%",
+				BloodGroupABO:         "A",
+				BloodGroupRhD:         "+",
+			}},
+		},
+		{
+			name: "rows with missing columns are skipped",
+			csv: header +
+				"Ahmad,Khalil\n" +
+				"Sara,Khalil,Omar,Huda,Syrian,,Female,,,,,,,,,Hemophilia,,,,\n",
+			want: []csvRow{{
+				FirstName:           "Sara",
+				LastName:            "Khalil",
+				FatherName:          "Omar",
+				MotherName:          "Huda",
+				Nationality:         "syrian",
+				Gender:              "female",
+				DateOfBirth:         time.Time{}.Add(69 * time.Minute),
+				Diagnosis_GroupName: "Hemophilia",
+				DateOfDiagnosis:     time.Time{}.Add(69 * time.Minute),
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extractCsvRecords(strings.NewReader(tt.csv))
+			if err != nil {
+				t.Fatalf("extractCsvRecords() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("extractCsvRecords() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
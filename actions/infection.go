@@ -0,0 +1,287 @@
+package actions
+
+import (
+	"shs/app"
+	"shs/app/models"
+	"slices"
+	"time"
+)
+
+type PatientVirus struct {
+	VirusId           uint      `json:"virus_id"`
+	VirusName         string    `json:"virus_name"`
+	Status            string    `json:"status"`
+	DetectedAt        time.Time `json:"detected_at"`
+	StatusChangedAt   time.Time `json:"status_changed_at"`
+	BloodTestResultId uint      `json:"blood_test_result_id"`
+	Notes             string    `json:"notes"`
+}
+
+func (pv *PatientVirus) FromModel(patientVirus models.PatientVirus) {
+	(*pv) = PatientVirus{
+		VirusId:           patientVirus.VirusId,
+		VirusName:         patientVirus.Virus.Name,
+		Status:            string(patientVirus.Status),
+		DetectedAt:        patientVirus.DetectedAt,
+		StatusChangedAt:   patientVirus.StatusChangedAt,
+		BloodTestResultId: patientVirus.BloodTestResultId,
+		Notes:             patientVirus.Notes,
+	}
+}
+
+type InfectionSuggestion struct {
+	VirusId           uint      `json:"virus_id"`
+	VirusName         string    `json:"virus_name"`
+	Status            string    `json:"status"`
+	CurrentStatus     string    `json:"current_status"`
+	BloodTestResultId uint      `json:"blood_test_result_id"`
+	DetectedAt        time.Time `json:"detected_at"`
+}
+
+func (s *InfectionSuggestion) FromModel(suggestion models.InfectionSuggestion) {
+	(*s) = InfectionSuggestion{
+		VirusId:           suggestion.Virus.Id,
+		VirusName:         suggestion.Virus.Name,
+		Status:            string(suggestion.Status),
+		BloodTestResultId: suggestion.BloodTestResultId,
+		DetectedAt:        suggestion.DetectedAt,
+	}
+	if suggestion.Current != nil {
+		(*s).CurrentStatus = string(suggestion.Current.Status)
+	}
+}
+
+// validatePatientVirus checks the infection's status and detection date, and that its confirming result is a completed result
+// of the patient's, of one of the virus's identifying blood tests when the virus has any.
+// The detection date defaults to the confirming result's date.
+func (a *Actions) validatePatientVirus(patientVirus *models.PatientVirus, virus models.Virus) error {
+	if !slices.Contains(models.InfectionStatuses(), patientVirus.Status) {
+		return ErrValidation{Field: "status"}
+	}
+
+	if patientVirus.BloodTestResultId != 0 {
+		results, err := a.app.ListPatientBloodTestResults(patientVirus.PatientId)
+		if err != nil {
+			return err
+		}
+
+		btrIdx := slices.IndexFunc(results, func(btr models.BloodTestResult) bool {
+			return btr.Id == patientVirus.BloodTestResultId
+		})
+		if btrIdx < 0 {
+			return &app.ErrNotFound{
+				ResourceName: "blood_test_result",
+			}
+		}
+
+		btr := results[btrIdx]
+		identifying := len(virus.IdentifyingBloodTests) == 0 || slices.ContainsFunc(virus.IdentifyingBloodTests, func(bt models.BloodTest) bool {
+			return bt.Id == btr.BloodTestId
+		})
+		if btr.Pending || !identifying {
+			return ErrValidation{Field: "blood_test_result_id"}
+		}
+
+		if patientVirus.DetectedAt.IsZero() {
+			patientVirus.DetectedAt = btr.CreatedAt
+		}
+	}
+
+	if patientVirus.DetectedAt.IsZero() || patientVirus.DetectedAt.After(time.Now().UTC()) {
+		return ErrValidation{Field: "detected_at"}
+	}
+
+	return nil
+}
+
+type CreatePatientVirusParams struct {
+	ActionContext
+	PatientId       string
+	NewPatientVirus PatientVirus `json:"new_patient_virus"`
+}
+
+type CreatePatientVirusPayload struct {
+	Data PatientVirus `json:"data"`
+}
+
+// CreatePatientVirus records the patient's infection with a virus, optionally confirmed by one of the patient's results.
+func (a *Actions) CreatePatientVirus(params CreatePatientVirusParams) (CreatePatientVirusPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionWritePatient) {
+		return CreatePatientVirusPayload{}, ErrPermissionDenied{}
+	}
+
+	patient, err := a.app.GetMinimalPatientByPublicId(params.PatientId)
+	if err != nil {
+		return CreatePatientVirusPayload{}, err
+	}
+
+	virus, err := a.app.GetVirus(params.NewPatientVirus.VirusId)
+	if err != nil {
+		return CreatePatientVirusPayload{}, err
+	}
+	if virus.IsArchived() {
+		return CreatePatientVirusPayload{}, &app.ErrArchived{
+			ResourceName: "virus",
+		}
+	}
+
+	patientVirus := models.PatientVirus{
+		PatientId:         patient.Id,
+		VirusId:           virus.Id,
+		Status:            models.InfectionStatus(params.NewPatientVirus.Status),
+		DetectedAt:        params.NewPatientVirus.DetectedAt,
+		BloodTestResultId: params.NewPatientVirus.BloodTestResultId,
+		AccountId:         params.Account.Id,
+		Notes:             params.NewPatientVirus.Notes,
+	}
+	if patientVirus.Status == "" {
+		patientVirus.Status = models.InfectionStatusActive
+	}
+
+	err = a.validatePatientVirus(&patientVirus, virus)
+	if err != nil {
+		return CreatePatientVirusPayload{}, err
+	}
+
+	patientVirus, err = a.app.CreatePatientVirus(patientVirus)
+	if err != nil {
+		return CreatePatientVirusPayload{}, err
+	}
+	patientVirus.Virus = virus
+
+	outPatientVirus := new(PatientVirus)
+	outPatientVirus.FromModel(patientVirus)
+
+	return CreatePatientVirusPayload{
+		Data: *outPatientVirus,
+	}, nil
+}
+
+type UpdatePatientVirusParams struct {
+	ActionContext
+	PatientId       string
+	VirusId         uint
+	NewPatientVirus PatientVirus `json:"new_patient_virus"`
+}
+
+type UpdatePatientVirusPayload struct {
+	Data PatientVirus `json:"data"`
+}
+
+// UpdatePatientVirus updates the status, detection date, confirming result and notes of the patient's infection with the virus.
+func (a *Actions) UpdatePatientVirus(params UpdatePatientVirusParams) (UpdatePatientVirusPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionWritePatient) {
+		return UpdatePatientVirusPayload{}, ErrPermissionDenied{}
+	}
+
+	patient, err := a.app.GetMinimalPatientByPublicId(params.PatientId)
+	if err != nil {
+		return UpdatePatientVirusPayload{}, err
+	}
+
+	virus, err := a.app.GetVirus(params.VirusId)
+	if err != nil {
+		return UpdatePatientVirusPayload{}, err
+	}
+
+	patientVirus := models.PatientVirus{
+		PatientId:         patient.Id,
+		VirusId:           virus.Id,
+		Status:            models.InfectionStatus(params.NewPatientVirus.Status),
+		DetectedAt:        params.NewPatientVirus.DetectedAt,
+		BloodTestResultId: params.NewPatientVirus.BloodTestResultId,
+		AccountId:         params.Account.Id,
+		Notes:             params.NewPatientVirus.Notes,
+	}
+
+	err = a.validatePatientVirus(&patientVirus, virus)
+	if err != nil {
+		return UpdatePatientVirusPayload{}, err
+	}
+
+	patientVirus, err = a.app.UpdatePatientVirus(patientVirus)
+	if err != nil {
+		return UpdatePatientVirusPayload{}, err
+	}
+
+	outPatientVirus := new(PatientVirus)
+	outPatientVirus.FromModel(patientVirus)
+
+	return UpdatePatientVirusPayload{
+		Data: *outPatientVirus,
+	}, nil
+}
+
+type ListPatientVirusesParams struct {
+	ActionContext
+	PatientId string
+}
+
+type ListPatientVirusesPayload struct {
+	Data []PatientVirus `json:"data"`
+}
+
+func (a *Actions) ListPatientViruses(params ListPatientVirusesParams) (ListPatientVirusesPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionReadPatient) {
+		return ListPatientVirusesPayload{}, ErrPermissionDenied{}
+	}
+
+	patient, err := a.app.GetMinimalPatientByPublicId(params.PatientId)
+	if err != nil {
+		return ListPatientVirusesPayload{}, err
+	}
+
+	patientViruses, err := a.app.ListPatientViruses(patient.Id)
+	if err != nil {
+		return ListPatientVirusesPayload{}, err
+	}
+
+	outPatientViruses := make([]PatientVirus, 0, len(patientViruses))
+	for _, patientVirus := range patientViruses {
+		outPatientVirus := new(PatientVirus)
+		outPatientVirus.FromModel(patientVirus)
+		outPatientViruses = append(outPatientViruses, *outPatientVirus)
+	}
+
+	return ListPatientVirusesPayload{
+		Data: outPatientViruses,
+	}, nil
+}
+
+type ListPatientInfectionSuggestionsParams struct {
+	ActionContext
+	PatientId string
+}
+
+type ListPatientInfectionSuggestionsPayload struct {
+	Data []InfectionSuggestion `json:"data"`
+}
+
+// ListPatientInfectionSuggestions suggests infection statuses from the patient's results of the viruses' identifying blood tests,
+// suggestions aren't recorded until they're accepted by creating or updating the patient's infection.
+func (a *Actions) ListPatientInfectionSuggestions(params ListPatientInfectionSuggestionsParams) (ListPatientInfectionSuggestionsPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionReadPatient) {
+		return ListPatientInfectionSuggestionsPayload{}, ErrPermissionDenied{}
+	}
+
+	patient, err := a.app.GetMinimalPatientByPublicId(params.PatientId)
+	if err != nil {
+		return ListPatientInfectionSuggestionsPayload{}, err
+	}
+
+	suggestions, err := a.app.ListPatientInfectionSuggestions(patient.Id)
+	if err != nil {
+		return ListPatientInfectionSuggestionsPayload{}, err
+	}
+
+	outSuggestions := make([]InfectionSuggestion, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		outSuggestion := new(InfectionSuggestion)
+		outSuggestion.FromModel(suggestion)
+		outSuggestions = append(outSuggestions, *outSuggestion)
+	}
+
+	return ListPatientInfectionSuggestionsPayload{
+		Data: outSuggestions,
+	}, nil
+}
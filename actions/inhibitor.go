@@ -0,0 +1,102 @@
+package actions
+
+import (
+	"shs/app/models"
+	"time"
+)
+
+type InhibitorTiter struct {
+	BloodTestResultId uint      `json:"blood_test_result_id"`
+	Titer             float64   `json:"titer"`
+	Positive          bool      `json:"positive"`
+	MeasuredAt        time.Time `json:"measured_at"`
+}
+
+func (t *InhibitorTiter) FromModel(titer models.InhibitorTiter) {
+	(*t) = InhibitorTiter{
+		BloodTestResultId: titer.BloodTestResultId,
+		Titer:             titer.Titer,
+		Positive:          titer.IsPositive(),
+		MeasuredAt:        titer.MeasuredAt,
+	}
+}
+
+func inhibitorTitersFromModels(titers []models.InhibitorTiter) []InhibitorTiter {
+	outTiters := make([]InhibitorTiter, 0, len(titers))
+	for _, titer := range titers {
+		outTiter := new(InhibitorTiter)
+		outTiter.FromModel(titer)
+		outTiters = append(outTiters, *outTiter)
+	}
+
+	return outTiters
+}
+
+type ListPatientInhibitorTitersParams struct {
+	ActionContext
+	PatientId string
+}
+
+type ListPatientInhibitorTitersPayload struct {
+	Data []InhibitorTiter `json:"data"`
+}
+
+func (a *Actions) ListPatientInhibitorTiters(params ListPatientInhibitorTitersParams) (ListPatientInhibitorTitersPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionReadPatient) {
+		return ListPatientInhibitorTitersPayload{}, ErrPermissionDenied{}
+	}
+
+	patient, err := a.app.GetMinimalPatientByPublicId(params.PatientId)
+	if err != nil {
+		return ListPatientInhibitorTitersPayload{}, err
+	}
+
+	titers, err := a.app.ListPatientInhibitorTiters(patient.Id)
+	if err != nil {
+		return ListPatientInhibitorTitersPayload{}, err
+	}
+
+	return ListPatientInhibitorTitersPayload{
+		Data: inhibitorTitersFromModels(titers),
+	}, nil
+}
+
+type PatientInhibitor struct {
+	Patient Patient          `json:"patient"`
+	Titers  []InhibitorTiter `json:"titers"`
+}
+
+type ListPatientsWithInhibitorsParams struct {
+	ActionContext
+}
+
+type ListPatientsWithInhibitorsPayload struct {
+	Data []PatientInhibitor `json:"data"`
+}
+
+// ListPatientsWithInhibitors reports the patients with positive inhibitors, highest titer first,
+// with each patient's titer history.
+func (a *Actions) ListPatientsWithInhibitors(params ListPatientsWithInhibitorsParams) (ListPatientsWithInhibitorsPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionReadPatient) {
+		return ListPatientsWithInhibitorsPayload{}, ErrPermissionDenied{}
+	}
+
+	patients, titers, err := a.app.ListPatientsWithInhibitorTiters()
+	if err != nil {
+		return ListPatientsWithInhibitorsPayload{}, err
+	}
+
+	outPatients := make([]PatientInhibitor, 0, len(patients))
+	for _, patient := range patients {
+		outPatient := new(Patient)
+		outPatient.FromModel(patient)
+		outPatients = append(outPatients, PatientInhibitor{
+			Patient: *outPatient,
+			Titers:  inhibitorTitersFromModels(titers[patient.Id]),
+		})
+	}
+
+	return ListPatientsWithInhibitorsPayload{
+		Data: outPatients,
+	}, nil
+}
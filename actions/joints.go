@@ -2,45 +2,146 @@ package actions
 
 import (
 	"shs/app/models"
+	"slices"
 	"time"
 )
 
+type JointHealthScore struct {
+	Joint         string `json:"joint"`
+	Swelling      int    `json:"swelling"`
+	Duration      int    `json:"duration"`
+	MuscleAtrophy int    `json:"muscle_atrophy"`
+	Crepitus      int    `json:"crepitus"`
+	FlexionLoss   int    `json:"flexion_loss"`
+	ExtensionLoss int    `json:"extension_loss"`
+	JointPain     int    `json:"joint_pain"`
+	Strength      int    `json:"strength"`
+	Total         int    `json:"total"`
+}
+
+func (j *JointHealthScore) FromModel(score models.JointHealthScore) {
+	(*j) = JointHealthScore{
+		Joint:         string(score.Joint),
+		Swelling:      score.Swelling,
+		Duration:      score.Duration,
+		MuscleAtrophy: score.MuscleAtrophy,
+		Crepitus:      score.Crepitus,
+		FlexionLoss:   score.FlexionLoss,
+		ExtensionLoss: score.ExtensionLoss,
+		JointPain:     score.JointPain,
+		Strength:      score.Strength,
+		Total:         score.Total(),
+	}
+}
+
+func (j JointHealthScore) IntoModel() models.JointHealthScore {
+	return models.JointHealthScore{
+		Joint:         models.BleedSite(j.Joint),
+		Swelling:      j.Swelling,
+		Duration:      j.Duration,
+		MuscleAtrophy: j.MuscleAtrophy,
+		Crepitus:      j.Crepitus,
+		FlexionLoss:   j.FlexionLoss,
+		ExtensionLoss: j.ExtensionLoss,
+		JointPain:     j.JointPain,
+		Strength:      j.Strength,
+	}
+}
+
 type JointsEvaluation struct {
-	Id         uint      `json:"id"`
-	RightAnkle int       `json:"right_ankle"`
-	LeftAnkle  int       `json:"left_ankle"`
-	RightKnee  int       `json:"right_knee"`
-	LeftKnee   int       `json:"left_knee"`
-	RightElbow int       `json:"right_elbow"`
-	LeftElbow  int       `json:"left_elbow"`
-	Result     int       `json:"result"`
-	CreatedAt  time.Time `json:"created_at"`
+	Id         uint               `json:"id"`
+	Kind       string             `json:"kind"`
+	RightAnkle int                `json:"right_ankle"`
+	LeftAnkle  int                `json:"left_ankle"`
+	RightKnee  int                `json:"right_knee"`
+	LeftKnee   int                `json:"left_knee"`
+	RightElbow int                `json:"right_elbow"`
+	LeftElbow  int                `json:"left_elbow"`
+	GlobalGait int                `json:"global_gait"`
+	Joints     []JointHealthScore `json:"joints"`
+	Result     int                `json:"result"`
+	CreatedAt  time.Time          `json:"created_at"`
 }
 
 func (j *JointsEvaluation) FromModel(je models.JointsEvaluation) {
 	(*j).Id = je.Id
+	(*j).Kind = string(je.Kind)
 	(*j).RightAnkle = je.RightAnkle
 	(*j).LeftAnkle = je.LeftAnkle
 	(*j).RightKnee = je.RightKnee
 	(*j).LeftKnee = je.LeftKnee
 	(*j).RightElbow = je.RightElbow
 	(*j).LeftElbow = je.LeftElbow
+	(*j).GlobalGait = je.GlobalGait
 	(*j).CreatedAt = je.CreatedAt
+	(*j).Result = je.Total()
 
-	(*j).Result = je.RightAnkle + je.LeftAnkle +
-		je.RightKnee + je.LeftKnee +
-		je.RightElbow + je.LeftElbow
+	(*j).Joints = make([]JointHealthScore, 0, len(je.Joints))
+	for _, joint := range je.Joints {
+		outJoint := new(JointHealthScore)
+		outJoint.FromModel(joint)
+		(*j).Joints = append((*j).Joints, *outJoint)
+	}
 }
 
 func (j JointsEvaluation) IntoModel() models.JointsEvaluation {
-	return models.JointsEvaluation{
+	je := models.JointsEvaluation{
+		Kind:       models.JointsEvaluationKind(j.Kind),
 		RightAnkle: j.RightAnkle,
 		LeftAnkle:  j.LeftAnkle,
 		RightKnee:  j.RightKnee,
 		LeftKnee:   j.LeftKnee,
 		RightElbow: j.RightElbow,
 		LeftElbow:  j.LeftElbow,
+		GlobalGait: j.GlobalGait,
+		Joints:     make([]models.JointHealthScore, 0, len(j.Joints)),
+	}
+	if je.Kind == "" {
+		je.Kind = models.JointsEvaluationKindSimple
+	}
+	for _, joint := range j.Joints {
+		je.Joints = append(je.Joints, joint.IntoModel())
 	}
+
+	return je
+}
+
+// validateJointsEvaluation checks that an HJHS evaluation has every evaluated joint exactly once
+// with its items in their allowed ranges, and that a simple evaluation has no HJHS items.
+func validateJointsEvaluation(je models.JointsEvaluation) error {
+	if !slices.Contains(models.JointsEvaluationKinds(), je.Kind) {
+		return ErrValidation{Field: "kind"}
+	}
+
+	if je.Kind == models.JointsEvaluationKindSimple {
+		if len(je.Joints) > 0 || je.GlobalGait != 0 {
+			return ErrValidation{Field: "joints"}
+		}
+		return nil
+	}
+
+	if je.GlobalGait < 0 || je.GlobalGait > models.HJHSMaxGlobalGait {
+		return ErrValidation{Field: "global_gait"}
+	}
+	if len(je.Joints) != len(models.EvaluatedJoints()) {
+		return ErrValidation{Field: "joints"}
+	}
+
+	seenJoints := make(map[models.BleedSite]bool)
+	for _, joint := range je.Joints {
+		if !slices.Contains(models.EvaluatedJoints(), joint.Joint) || seenJoints[joint.Joint] {
+			return ErrValidation{Field: "joints"}
+		}
+		seenJoints[joint.Joint] = true
+
+		for _, item := range joint.Items() {
+			if item.Score < 0 || item.Score > item.Max {
+				return ErrValidation{Field: "joints." + string(joint.Joint) + "." + item.Name}
+			}
+		}
+	}
+
+	return nil
 }
 
 type CreatePatientJointsEvaluationParams struct {
@@ -64,6 +165,10 @@ func (a *Actions) CreatePatientJointsEvaluation(params CreatePatientJointsEvalua
 
 	je := params.JointsEvaluation.IntoModel()
 	je.PatientId = patient.Id
+	err = validateJointsEvaluation(je)
+	if err != nil {
+		return CreatePatientJointsEvaluationPayload{}, err
+	}
 
 	_, err = a.app.CreateJointsEvaluation(je)
 	if err != nil {
@@ -108,3 +213,98 @@ func (a *Actions) ListPatientJointsEvaluations(params ListPatientJointsEvaluatio
 		Data: outJoints,
 	}, nil
 }
+
+type JointTrendPoint struct {
+	EvaluationId uint              `json:"evaluation_id"`
+	EvaluatedAt  time.Time         `json:"evaluated_at"`
+	Kind         string            `json:"kind"`
+	Score        int               `json:"score"`
+	Items        *JointHealthScore `json:"items"`
+}
+
+type JointTrend struct {
+	Joint  string            `json:"joint"`
+	Points []JointTrendPoint `json:"points"`
+	Change int               `json:"change"`
+}
+
+func (j *JointTrend) FromModel(trend models.JointTrend) {
+	(*j) = JointTrend{
+		Joint:  string(trend.Joint),
+		Points: make([]JointTrendPoint, 0, len(trend.Points)),
+		Change: trend.Change,
+	}
+
+	for _, point := range trend.Points {
+		outPoint := JointTrendPoint{
+			EvaluationId: point.EvaluationId,
+			EvaluatedAt:  point.EvaluatedAt,
+			Kind:         string(point.Kind),
+			Score:        point.Score,
+		}
+		if point.Items != nil {
+			outPoint.Items = new(JointHealthScore)
+			outPoint.Items.FromModel(*point.Items)
+		}
+		j.Points = append(j.Points, outPoint)
+	}
+}
+
+type JointsEvaluationTotal struct {
+	EvaluationId uint      `json:"evaluation_id"`
+	EvaluatedAt  time.Time `json:"evaluated_at"`
+	Kind         string    `json:"kind"`
+	GlobalGait   int       `json:"global_gait"`
+	Result       int       `json:"result"`
+}
+
+type GetPatientJointsTrendParams struct {
+	ActionContext
+	PatientId string
+}
+
+type GetPatientJointsTrendPayload struct {
+	Joints []JointTrend            `json:"joints"`
+	Totals []JointsEvaluationTotal `json:"totals"`
+}
+
+// GetPatientJointsTrend reports every joint's score over the patient's evaluations, oldest first,
+// along with each evaluation's total.
+func (a *Actions) GetPatientJointsTrend(params GetPatientJointsTrendParams) (GetPatientJointsTrendPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionReadPatient) {
+		return GetPatientJointsTrendPayload{}, ErrPermissionDenied{}
+	}
+
+	patient, err := a.app.GetMinimalPatientByPublicId(params.PatientId)
+	if err != nil {
+		return GetPatientJointsTrendPayload{}, err
+	}
+
+	trends, jes, err := a.app.GetPatientJointsTrend(patient.Id)
+	if err != nil {
+		return GetPatientJointsTrendPayload{}, err
+	}
+
+	outTrends := make([]JointTrend, 0, len(trends))
+	for _, trend := range trends {
+		outTrend := new(JointTrend)
+		outTrend.FromModel(trend)
+		outTrends = append(outTrends, *outTrend)
+	}
+
+	outTotals := make([]JointsEvaluationTotal, 0, len(jes))
+	for _, je := range jes {
+		outTotals = append(outTotals, JointsEvaluationTotal{
+			EvaluationId: je.Id,
+			EvaluatedAt:  je.CreatedAt,
+			Kind:         string(je.Kind),
+			GlobalGait:   je.GlobalGait,
+			Result:       je.Total(),
+		})
+	}
+
+	return GetPatientJointsTrendPayload{
+		Joints: outTrends,
+		Totals: outTotals,
+	}, nil
+}
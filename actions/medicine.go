@@ -2,20 +2,24 @@ package actions
 
 import (
 	"shs/app/models"
+	"strings"
 	"time"
 )
 
+// Medicine is a batch of a medicine product, with the product's attributes flattened into it.
 type Medicine struct {
-	Id           uint      `json:"id"`
-	Name         string    `json:"name"`
-	Dose         int       `json:"dose"`
-	Unit         string    `json:"unit"`
-	Amount       int       `json:"amount"`
-	ExpiresAt    time.Time `json:"expires_at"`
-	ReceivedAt   time.Time `json:"received_at"`
-	Manufacturer string    `json:"manufacturer"`
-	BatchNumber  string    `json:"batch_number"`
-	FactorType   string    `json:"factor_type"`
+	Id           uint       `json:"id"`
+	ProductId    uint       `json:"product_id"`
+	Name         string     `json:"name"`
+	Dose         int        `json:"dose"`
+	Unit         string     `json:"unit"`
+	Amount       int        `json:"amount"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	ReceivedAt   time.Time  `json:"received_at"`
+	Manufacturer string     `json:"manufacturer"`
+	BatchNumber  string     `json:"batch_number"`
+	FactorType   string     `json:"factor_type"`
+	ArchivedAt   *time.Time `json:"archived_at"`
 }
 
 type CreateMedicineParams struct {
@@ -25,30 +29,68 @@ type CreateMedicineParams struct {
 
 func (m Medicine) IntoModel() models.Medicine {
 	return models.Medicine{
-		Name:         m.Name,
-		Dose:         m.Dose,
-		Unit:         m.Unit,
-		Amount:       m.Amount,
-		ExpiresAt:    m.ExpiresAt,
-		ReceivedAt:   m.ReceivedAt,
-		Manufacturer: m.Manufacturer,
-		BatchNumber:  m.BatchNumber,
-		FactorType:   m.FactorType,
+		ProductId: m.ProductId,
+		Product: models.MedicineProduct{
+			Name:         m.Name,
+			Dose:         m.Dose,
+			Unit:         m.Unit,
+			Manufacturer: m.Manufacturer,
+			FactorType:   m.FactorType,
+		},
+		Amount:      m.Amount,
+		ExpiresAt:   m.ExpiresAt,
+		ReceivedAt:  m.ReceivedAt,
+		BatchNumber: m.BatchNumber,
 	}
 }
 
 func (m *Medicine) FromModel(medicine models.Medicine) {
 	(*m) = Medicine{
 		Id:           medicine.Id,
-		Name:         medicine.Name,
-		Dose:         medicine.Dose,
-		Unit:         medicine.Unit,
+		ProductId:    medicine.ProductId,
+		Name:         medicine.Product.Name,
+		Dose:         medicine.Product.Dose,
+		Unit:         medicine.Product.Unit,
 		Amount:       medicine.Amount,
 		ExpiresAt:    medicine.ExpiresAt,
 		ReceivedAt:   medicine.ReceivedAt,
-		Manufacturer: medicine.Manufacturer,
+		Manufacturer: medicine.Product.Manufacturer,
 		BatchNumber:  medicine.BatchNumber,
-		FactorType:   medicine.FactorType,
+		FactorType:   medicine.Product.FactorType,
+		ArchivedAt:   medicine.ArchivedAt,
+	}
+}
+
+type MedicineProduct struct {
+	Id           uint       `json:"id"`
+	Name         string     `json:"name"`
+	Dose         int        `json:"dose"`
+	Unit         string     `json:"unit"`
+	Manufacturer string     `json:"manufacturer"`
+	FactorType   string     `json:"factor_type"`
+	MinStock     int        `json:"min_stock"`
+	Amount       int        `json:"amount"`
+	Batches      []Medicine `json:"batches"`
+}
+
+func (p *MedicineProduct) FromModel(product models.MedicineProduct) {
+	(*p) = MedicineProduct{
+		Id:           product.Id,
+		Name:         product.Name,
+		Dose:         product.Dose,
+		Unit:         product.Unit,
+		Manufacturer: product.Manufacturer,
+		FactorType:   product.FactorType,
+		MinStock:     product.MinStock,
+		Batches:      make([]Medicine, 0, len(product.Batches)),
+	}
+
+	for _, batch := range product.Batches {
+		batch.Product = product
+		outBatch := new(Medicine)
+		outBatch.FromModel(batch)
+		p.Batches = append(p.Batches, *outBatch)
+		p.Amount += batch.Amount
 	}
 }
 
@@ -60,15 +102,16 @@ func (a *Actions) CreateMedicine(params CreateMedicineParams) (CreateMedicinePay
 		return CreateMedicinePayload{}, ErrPermissionDenied{}
 	}
 
-	_, err := a.app.CreateMedicine(params.NewMedicine.IntoModel())
+	_, err := a.app.CreateMedicine(params.NewMedicine.IntoModel(), params.Account.Id)
 
 	return CreateMedicinePayload{}, err
 }
 
 type UpdateMedicineParams struct {
 	ActionContext
-	MedicineId uint `json:"medicine_id"`
-	Amount     int  `json:"amount"`
+	MedicineId uint   `json:"medicine_id"`
+	Amount     int    `json:"amount"`
+	Reason     string `json:"reason"`
 }
 
 type UpdateMedicinePayload struct {
@@ -79,7 +122,15 @@ func (a *Actions) UpdateMedicine(params UpdateMedicineParams) (UpdateMedicinePay
 		return UpdateMedicinePayload{}, ErrPermissionDenied{}
 	}
 
-	err := a.app.UpdateMedicineAmount(params.MedicineId, params.Amount)
+	if params.Amount < 0 {
+		return UpdateMedicinePayload{}, ErrValidation{Field: "amount"}
+	}
+	params.Reason = strings.TrimSpace(params.Reason)
+	if params.Reason == "" {
+		return UpdateMedicinePayload{}, ErrValidation{Field: "reason"}
+	}
+
+	err := a.app.SetMedicineAmount(params.MedicineId, params.Amount, params.Reason, params.Account.Id)
 
 	return UpdateMedicinePayload{}, err
 }
@@ -129,6 +180,7 @@ func (a *Actions) GetMedicine(params GetMedicineParams) (GetMedicinePayload, err
 
 type ListAllMedicineParams struct {
 	ActionContext
+	IncludeArchived bool
 }
 
 type ListAllMedicinePayload struct {
@@ -147,6 +199,9 @@ func (a *Actions) ListAllMedicine(params ListAllMedicineParams) (ListAllMedicine
 
 	outMedicines := make([]Medicine, 0, len(medicines))
 	for _, medicine := range medicines {
+		if medicine.IsArchived() && !params.IncludeArchived {
+			continue
+		}
 		outMedicine := new(Medicine)
 		outMedicine.FromModel(medicine)
 		outMedicines = append(outMedicines, *outMedicine)
@@ -156,3 +211,212 @@ func (a *Actions) ListAllMedicine(params ListAllMedicineParams) (ListAllMedicine
 		Data: outMedicines,
 	}, nil
 }
+
+const defaultExpiringWithinDays = 30
+
+type ListExpiringMedicinesParams struct {
+	ActionContext
+	WithinDays int
+}
+
+type ListExpiringMedicinesPayload struct {
+	WithinDays int        `json:"within_days"`
+	Expiring   []Medicine `json:"expiring"`
+	Expired    []Medicine `json:"expired"`
+}
+
+// ListExpiringMedicines lists batches with packages left that expire within the given days,
+// and the ones that have already expired, soonest to expire first.
+func (a *Actions) ListExpiringMedicines(params ListExpiringMedicinesParams) (ListExpiringMedicinesPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionReadMedicine) {
+		return ListExpiringMedicinesPayload{}, ErrPermissionDenied{}
+	}
+
+	if params.WithinDays < 0 {
+		return ListExpiringMedicinesPayload{}, ErrValidation{Field: "days"}
+	}
+	if params.WithinDays == 0 {
+		params.WithinDays = defaultExpiringWithinDays
+	}
+
+	now := time.Now().UTC()
+	medicines, err := a.app.ListMedicinesInStockExpiringBefore(now.AddDate(0, 0, params.WithinDays))
+	if err != nil {
+		return ListExpiringMedicinesPayload{}, err
+	}
+
+	expiring := make([]Medicine, 0, len(medicines))
+	expired := make([]Medicine, 0)
+	for _, medicine := range medicines {
+		outMedicine := new(Medicine)
+		outMedicine.FromModel(medicine)
+		if medicine.IsExpired(now) {
+			expired = append(expired, *outMedicine)
+		} else {
+			expiring = append(expiring, *outMedicine)
+		}
+	}
+
+	return ListExpiringMedicinesPayload{
+		WithinDays: params.WithinDays,
+		Expiring:   expiring,
+		Expired:    expired,
+	}, nil
+}
+
+type ListAllMedicineProductsParams struct {
+	ActionContext
+}
+
+type ListAllMedicineProductsPayload struct {
+	Data []MedicineProduct `json:"data"`
+}
+
+func (a *Actions) ListAllMedicineProducts(params ListAllMedicineProductsParams) (ListAllMedicineProductsPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionReadMedicine) {
+		return ListAllMedicineProductsPayload{}, ErrPermissionDenied{}
+	}
+
+	products, err := a.app.ListAllMedicineProducts()
+	if err != nil {
+		return ListAllMedicineProductsPayload{}, err
+	}
+
+	outProducts := make([]MedicineProduct, 0, len(products))
+	for _, product := range products {
+		outProduct := new(MedicineProduct)
+		outProduct.FromModel(product)
+		outProducts = append(outProducts, *outProduct)
+	}
+
+	return ListAllMedicineProductsPayload{
+		Data: outProducts,
+	}, nil
+}
+
+type GetMedicineProductParams struct {
+	ActionContext
+	ProductId uint
+}
+
+type GetMedicineProductPayload struct {
+	Data MedicineProduct `json:"data"`
+}
+
+func (a *Actions) GetMedicineProduct(params GetMedicineProductParams) (GetMedicineProductPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionReadMedicine) {
+		return GetMedicineProductPayload{}, ErrPermissionDenied{}
+	}
+
+	product, err := a.app.GetMedicineProduct(params.ProductId)
+	if err != nil {
+		return GetMedicineProductPayload{}, err
+	}
+
+	outProduct := new(MedicineProduct)
+	outProduct.FromModel(product)
+
+	return GetMedicineProductPayload{
+		Data: *outProduct,
+	}, nil
+}
+
+type UpdateMedicineProductMinStockParams struct {
+	ActionContext
+	ProductId uint
+	MinStock  int `json:"min_stock"`
+}
+
+type UpdateMedicineProductMinStockPayload struct {
+}
+
+func (a *Actions) UpdateMedicineProductMinStock(params UpdateMedicineProductMinStockParams) (UpdateMedicineProductMinStockPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionWriteMedicine) {
+		return UpdateMedicineProductMinStockPayload{}, ErrPermissionDenied{}
+	}
+
+	if params.MinStock < 0 {
+		return UpdateMedicineProductMinStockPayload{}, ErrValidation{Field: "min_stock"}
+	}
+
+	err := a.app.UpdateMedicineProductMinStock(params.ProductId, params.MinStock)
+	if err != nil {
+		return UpdateMedicineProductMinStockPayload{}, err
+	}
+
+	return UpdateMedicineProductMinStockPayload{}, nil
+}
+
+type MedicineProductProjection struct {
+	ProductId         uint      `json:"product_id"`
+	Name              string    `json:"name"`
+	Manufacturer      string    `json:"manufacturer"`
+	FactorType        string    `json:"factor_type"`
+	MinStock          int       `json:"min_stock"`
+	Amount            int       `json:"amount"`
+	DailyDispensation float64   `json:"daily_dispensation"`
+	RunOutAt          time.Time `json:"run_out_at"`
+}
+
+type ListMedicineProductProjectionsParams struct {
+	ActionContext
+}
+
+type ListMedicineProductProjectionsPayload struct {
+	Data []MedicineProductProjection `json:"data"`
+}
+
+// ListMedicineProductProjections lists when each product is projected to run out,
+// based on its average daily dispensation over the last 90 days.
+func (a *Actions) ListMedicineProductProjections(params ListMedicineProductProjectionsParams) (ListMedicineProductProjectionsPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionReadMedicine) {
+		return ListMedicineProductProjectionsPayload{}, ErrPermissionDenied{}
+	}
+
+	projections, err := a.app.ListMedicineProductProjections(time.Now().UTC())
+	if err != nil {
+		return ListMedicineProductProjectionsPayload{}, err
+	}
+
+	outProjections := make([]MedicineProductProjection, 0, len(projections))
+	for _, projection := range projections {
+		outProjections = append(outProjections, MedicineProductProjection{
+			ProductId:         projection.Product.Id,
+			Name:              projection.Product.Name,
+			Manufacturer:      projection.Product.Manufacturer,
+			FactorType:        projection.Product.FactorType,
+			MinStock:          projection.Product.MinStock,
+			Amount:            projection.Amount,
+			DailyDispensation: projection.DailyDispensation,
+			RunOutAt:          projection.RunOutAt,
+		})
+	}
+
+	return ListMedicineProductProjectionsPayload{
+		Data: outProjections,
+	}, nil
+}
+
+type SetMedicineArchivedParams struct {
+	ActionContext
+	MedicineId uint
+	Archived   bool
+}
+
+type SetMedicineArchivedPayload struct {
+}
+
+// SetMedicineArchived archives the medicine batch, which hides it from new prescriptions while the records that have it still show it,
+// or unarchives it.
+func (a *Actions) SetMedicineArchived(params SetMedicineArchivedParams) (SetMedicineArchivedPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionWriteMedicine) {
+		return SetMedicineArchivedPayload{}, ErrPermissionDenied{}
+	}
+
+	err := a.app.SetMedicineArchived(params.MedicineId, params.Archived)
+	if err != nil {
+		return SetMedicineArchivedPayload{}, err
+	}
+
+	return SetMedicineArchivedPayload{}, nil
+}
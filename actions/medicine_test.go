@@ -0,0 +1,113 @@
+package actions
+
+import (
+	"shs/app/models"
+	"testing"
+)
+
+func TestUpdateMedicineValidation(t *testing.T) {
+	writer := ActionContext{Account: models.Account{Id: 1, Permissions: models.AccountPermissionWriteMedicine}}
+	reader := ActionContext{Account: models.Account{Id: 2, Permissions: models.AccountPermissionReadMedicine}}
+
+	tests := []struct {
+		name      string
+		params    UpdateMedicineParams
+		wantField string
+	}{
+		{
+			name:   "without the write permission",
+			params: UpdateMedicineParams{ActionContext: reader, Amount: 3, Reason: "recount"},
+		},
+		{
+			name:      "negative amount",
+			params:    UpdateMedicineParams{ActionContext: writer, Amount: -1, Reason: "recount"},
+			wantField: "amount",
+		},
+		{
+			name:      "blank reason",
+			params:    UpdateMedicineParams{ActionContext: writer, Amount: 3, Reason: "  "},
+			wantField: "reason",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := (&Actions{}).UpdateMedicine(tt.params)
+			assertMedicineActionError(t, err, tt.wantField)
+		})
+	}
+}
+
+func TestCreateStockMovementValidation(t *testing.T) {
+	writer := ActionContext{Account: models.Account{Id: 1, Permissions: models.AccountPermissionWriteMedicine}}
+
+	tests := []struct {
+		name      string
+		params    CreateStockMovementParams
+		wantField string
+	}{
+		{
+			name:   "without the write permission",
+			params: CreateStockMovementParams{Type: "receipt", Quantity: 3},
+		},
+		{
+			name:      "unknown type",
+			params:    CreateStockMovementParams{ActionContext: writer, Type: "loss", Quantity: 3},
+			wantField: "type",
+		},
+		{
+			name:      "dispensations are only recorded through visits",
+			params:    CreateStockMovementParams{ActionContext: writer, Type: "dispensation", Quantity: 3},
+			wantField: "type",
+		},
+		{
+			name:      "adjustment without a difference",
+			params:    CreateStockMovementParams{ActionContext: writer, Type: "adjustment", Reason: "recount"},
+			wantField: "quantity",
+		},
+		{
+			name:      "adjustment without a reason",
+			params:    CreateStockMovementParams{ActionContext: writer, Type: "adjustment", Quantity: -2},
+			wantField: "reason",
+		},
+		{
+			name:      "transfer without a destination",
+			params:    CreateStockMovementParams{ActionContext: writer, Type: "transfer", Quantity: 2},
+			wantField: "destination",
+		},
+		{
+			name:      "negative write off",
+			params:    CreateStockMovementParams{ActionContext: writer, Type: "expiry_write_off", Quantity: -2},
+			wantField: "quantity",
+		},
+		{
+			name:      "empty receipt",
+			params:    CreateStockMovementParams{ActionContext: writer, Type: "receipt"},
+			wantField: "quantity",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := (&Actions{}).CreateStockMovement(tt.params)
+			assertMedicineActionError(t, err, tt.wantField)
+		})
+	}
+}
+
+// assertMedicineActionError checks that err is a validation error of wantField,
+// or a permission error when wantField is empty.
+func assertMedicineActionError(t *testing.T, err error, wantField string) {
+	t.Helper()
+
+	if wantField == "" {
+		if _, ok := err.(ErrPermissionDenied); !ok {
+			t.Errorf("error = %v, want a permission error", err)
+		}
+		return
+	}
+	validationErr, ok := err.(ErrValidation)
+	if !ok || validationErr.Field != wantField {
+		t.Errorf("error = %v, want a validation error of %q", err, wantField)
+	}
+}
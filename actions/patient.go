@@ -1,30 +1,132 @@
 package actions
 
 import (
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/json"
 	"shs/app"
 	"shs/app/models"
 	"shs/cardgen"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 )
 
+type DiagnosisFilledField struct {
+	DiagnosisFieldId uint                      `json:"diagnosis_field_id"`
+	Name             string                    `json:"name"`
+	Type             models.DiagnosisFieldType `json:"type"`
+	Value            string                    `json:"value"`
+	ValueNumber      float64                   `json:"value_number"`
+}
+
 type DiagnosisResult struct {
 	Diagnosis
-	Id          uint      `json:"id"`
-	DiagnosisId uint      `json:"diagnosis_id"`
-	DiagnosedAt time.Time `json:"diagnosed_at"`
+	Id            uint                   `json:"id"`
+	DiagnosisId   uint                   `json:"diagnosis_id"`
+	DiagnosedAt   time.Time              `json:"diagnosed_at"`
+	DiagnosedById uint                   `json:"diagnosed_by_id"`
+	FilledFields  []DiagnosisFilledField `json:"filled_fields"`
+	Notes         string                 `json:"notes"`
+	// Active is false for revoked results, and for results that were replaced by their updates.
+	Active           bool       `json:"active"`
+	ReplacesId       uint       `json:"replaces_id"`
+	ReplacedById     uint       `json:"replaced_by_id"`
+	RevokedAt        *time.Time `json:"revoked_at"`
+	RevokedById      uint       `json:"revoked_by_id"`
+	RevocationReason string     `json:"revocation_reason"`
 
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// FromModel sets the result from the model, with its fields' names and types from the result's diagnosis.
+func (dr *DiagnosisResult) FromModel(result models.DiagnosisResult) {
+	diagnosisFields := make(map[uint]models.DiagnosisField, len(result.Diagnosis.Fields))
+	for _, field := range result.Diagnosis.Fields {
+		diagnosisFields[field.Id] = field
+	}
+
+	filledFields := make([]DiagnosisFilledField, 0, len(result.FilledFields))
+	for _, field := range result.FilledFields {
+		filledFields = append(filledFields, DiagnosisFilledField{
+			DiagnosisFieldId: field.DiagnosisFieldId,
+			Name:             diagnosisFields[field.DiagnosisFieldId].Name,
+			Type:             diagnosisFields[field.DiagnosisFieldId].Type,
+			Value:            field.ValueString,
+			ValueNumber:      field.ValueNumber,
+		})
+	}
+
+	outDiagnosis := new(Diagnosis)
+	outDiagnosis.FromModel(result.Diagnosis)
+
+	(*dr) = DiagnosisResult{
+		Diagnosis:        *outDiagnosis,
+		Id:               result.Id,
+		DiagnosisId:      result.DiagnosisId,
+		DiagnosedAt:      result.DiagnosedAt,
+		DiagnosedById:    result.DiagnosedById,
+		FilledFields:     filledFields,
+		Notes:            result.Notes,
+		Active:           result.IsActive(),
+		ReplacesId:       result.ReplacesId,
+		ReplacedById:     result.ReplacedById,
+		RevokedAt:        result.RevokedAt,
+		RevokedById:      result.RevokedById,
+		RevocationReason: result.RevocationReason,
+		CreatedAt:        result.CreatedAt,
+	}
+}
+
+// diagnosisResultsFromModels converts the results with their diagnoses, leaving out the inactive ones when activeOnly is set.
+func diagnosisResultsFromModels(results []models.DiagnosisResult, diagnoses []models.Diagnosis, activeOnly bool) []DiagnosisResult {
+	diagnosesById := make(map[uint]models.Diagnosis, len(diagnoses))
+	for _, d := range diagnoses {
+		diagnosesById[d.Id] = d
+	}
+
+	outResults := make([]DiagnosisResult, 0, len(results))
+	for _, dr := range results {
+		if activeOnly && !dr.IsActive() {
+			continue
+		}
+		dr.Diagnosis = diagnosesById[dr.DiagnosisId]
+
+		outResult := new(DiagnosisResult)
+		outResult.FromModel(dr)
+		outResults = append(outResults, *outResult)
+	}
+
+	return outResults
+}
+
 type BloodTestFilledField struct {
 	BloodTestFieldId uint                 `json:"blood_test_field_id"`
 	Name             string               `json:"name"`
 	Unit             models.BlootTestUnit `json:"unit"`
-	ValueNumber      float64              `json:"value_number"`
-	ValueString      string               `json:"value_string"`
+	// ValueNumber is nil when a numeric value is only given as its value_string, so that a missing value isn't taken as zero.
+	ValueNumber *float64 `json:"value_number"`
+	ValueString string   `json:"value_string"`
+	// Flag is one of low, normal, high or critical, and it's empty when the value can't be classified.
+	Flag string `json:"flag"`
+}
+
+// IntoModel sets the value string from the value number when only the number is given,
+// the value string is then validated and parsed as the field's value.
+func (f BloodTestFilledField) IntoModel() models.BloodTestFilledField {
+	field := models.BloodTestFilledField{
+		BloodTestFieldId: f.BloodTestFieldId,
+		ValueString:      f.ValueString,
+	}
+	if f.ValueNumber != nil {
+		field.ValueNumber = *f.ValueNumber
+		if strings.TrimSpace(field.ValueString) == "" {
+			field.ValueString = strconv.FormatFloat(*f.ValueNumber, 'f', -1, 64)
+		}
+	}
+
+	return field
 }
 
 type BloodTestResult struct {
@@ -53,26 +155,36 @@ func (a Address) IntoModel() models.Address {
 }
 
 type Patient struct {
-	Id                  uint               `json:"id"`
-	PublicId            string             `json:"public_id"`
-	NationalId          string             `json:"national_id"`
-	Nationality         string             `json:"nationality"`
-	FirstName           string             `json:"first_name"`
-	LastName            string             `json:"last_name"`
-	FatherName          string             `json:"father_name"`
-	MotherName          string             `json:"mother_name"`
-	PlaceOfBirth        Address            `json:"place_of_birth"`
-	DateOfBirth         time.Time          `json:"date_of_birth"`
-	Residency           Address            `json:"residency"`
-	Gender              bool               `json:"gender"`
-	PhoneNumber         string             `json:"phone_number"`
-	BATScore            uint               `json:"bat_score"`
-	FamilyHistoryExists bool               `json:"family_history_exists"`
-	FirstVisitReason    string             `json:"first_visit_reason"`
-	Viruses             []Virus            `json:"viruses"`
-	BloodTestResults    []BloodTestResult  `json:"blood_test_results"`
-	JointsEvaluations   []JointsEvaluation `json:"joints_evaluations"`
-	Diagnoses           []DiagnosisResult  `json:"diagnoses"`
+	Id                    uint               `json:"id"`
+	PublicId              string             `json:"public_id"`
+	NationalId            string             `json:"national_id"`
+	Nationality           string             `json:"nationality"`
+	FirstName             string             `json:"first_name"`
+	LastName              string             `json:"last_name"`
+	FatherName            string             `json:"father_name"`
+	MotherName            string             `json:"mother_name"`
+	PlaceOfBirth          Address            `json:"place_of_birth"`
+	DateOfBirth           time.Time          `json:"date_of_birth"`
+	Residency             Address            `json:"residency"`
+	Gender                bool               `json:"gender"`
+	PhoneNumber           string             `json:"phone_number"`
+	BATScore              uint               `json:"bat_score"`
+	FamilyHistoryExists   bool               `json:"family_history_exists"`
+	CarrierStatus         string             `json:"carrier_status"`
+	FirstVisitReason      string             `json:"first_visit_reason"`
+	HemophiliaType        string             `json:"hemophilia_type"`
+	HemophiliaSeverity    string             `json:"hemophilia_severity"`
+	FactorLevel           float64            `json:"factor_level"`
+	FactorLevelMeasuredAt *time.Time         `json:"factor_level_measured_at"`
+	InhibitorPositive     bool               `json:"inhibitor_positive"`
+	InhibitorResponder    string             `json:"inhibitor_responder"`
+	InhibitorTiter        float64            `json:"inhibitor_titer"`
+	InhibitorPeakTiter    float64            `json:"inhibitor_peak_titer"`
+	InhibitorMeasuredAt   *time.Time         `json:"inhibitor_measured_at"`
+	Viruses               []Virus            `json:"viruses"`
+	BloodTestResults      []BloodTestResult  `json:"blood_test_results"`
+	JointsEvaluations     []JointsEvaluation `json:"joints_evaluations"`
+	Diagnoses             []DiagnosisResult  `json:"diagnoses"`
 }
 
 func (p Patient) IntoModel() models.Patient {
@@ -88,12 +200,9 @@ func (p Patient) IntoModel() models.Patient {
 	for _, btr := range p.BloodTestResults {
 		bloodTestResultFields := make([]models.BloodTestFilledField, 0, len(btr.FilledFields))
 		for _, field := range btr.FilledFields {
-			bloodTestResultFields = append(bloodTestResultFields, models.BloodTestFilledField{
-				BloodTestResultId: btr.BloodTestId,
-				BloodTestFieldId:  field.BloodTestFieldId,
-				ValueNumber:       field.ValueNumber,
-				ValueString:       field.ValueString,
-			})
+			filledField := field.IntoModel()
+			filledField.BloodTestResultId = btr.BloodTestId
+			bloodTestResultFields = append(bloodTestResultFields, filledField)
 		}
 
 		bloodTestResults = append(bloodTestResults, models.BloodTestResult{
@@ -127,6 +236,7 @@ func (p Patient) IntoModel() models.Patient {
 		Gender:              p.Gender,
 		PhoneNumber:         p.PhoneNumber,
 		FamilyHistoryExists: p.FamilyHistoryExists,
+		CarrierStatus:       models.CarrierStatus(p.CarrierStatus),
 		FirstVisitReason:    models.PatientFirstVisitReason(p.FirstVisitReason),
 		BATScore:            p.BATScore,
 		Viruses:             viruses,
@@ -161,20 +271,32 @@ func (p *Patient) FromModel(patient models.Patient) {
 		PhoneNumber:         patient.PhoneNumber,
 		BATScore:            patient.BATScore,
 		FamilyHistoryExists: patient.FamilyHistoryExists,
+		CarrierStatus:       string(patient.CarrierStatus),
 		FirstVisitReason:    string(patient.FirstVisitReason),
+		HemophiliaType:      string(patient.HemophiliaType),
+		HemophiliaSeverity:  string(patient.HemophiliaSeverity),
+		FactorLevel:         patient.FactorLevel,
+		InhibitorPositive:   patient.InhibitorPositive,
+		InhibitorResponder:  string(patient.InhibitorResponder),
+		InhibitorTiter:      patient.InhibitorTiter,
+		InhibitorPeakTiter:  patient.InhibitorPeakTiter,
+	}
+	if patient.FactorLevelBloodTestResultId != 0 {
+		(*p).FactorLevelMeasuredAt = &patient.FactorLevelMeasuredAt
+	}
+	if !patient.InhibitorMeasuredAt.IsZero() {
+		(*p).InhibitorMeasuredAt = &patient.InhibitorMeasuredAt
 	}
 }
 
 func (p *Patient) WithBloodTestResults(patientBloodTestResults []models.BloodTestResult, bloodTests []models.BloodTest) {
 	bloodTestNames := make(map[uint]string)
-	bloodTestFieldNames := make(map[uint]string)
-	bloodTestFieldUnits := make(map[uint]models.BlootTestUnit)
+	bloodTestFields := make(map[uint]models.BloodTestField)
 
 	for _, bt := range bloodTests {
 		bloodTestNames[bt.Id] = bt.Name
 		for _, field := range bt.Fields {
-			bloodTestFieldNames[field.Id] = field.Name
-			bloodTestFieldUnits[field.Id] = field.Unit
+			bloodTestFields[field.Id] = field
 		}
 	}
 
@@ -182,12 +304,16 @@ func (p *Patient) WithBloodTestResults(patientBloodTestResults []models.BloodTes
 	for _, btr := range patientBloodTestResults {
 		fields := make([]BloodTestFilledField, 0, len(btr.FilledFields))
 		for _, field := range btr.FilledFields {
+			btField := bloodTestFields[field.BloodTestFieldId]
+			// the result is interpreted with the field's definition when it was taken, but is shown with the field's current name.
+			takenField := btField.At(btr.CreatedAt)
 			fields = append(fields, BloodTestFilledField{
 				BloodTestFieldId: field.BloodTestFieldId,
-				Name:             bloodTestFieldNames[field.BloodTestFieldId],
-				Unit:             bloodTestFieldUnits[field.BloodTestFieldId],
-				ValueNumber:      field.ValueNumber,
+				Name:             btField.Name,
+				Unit:             takenField.Unit,
+				ValueNumber:      &field.ValueNumber,
 				ValueString:      field.ValueString,
+				Flag:             string(takenField.Flag(field.ValueNumber)),
 			})
 		}
 
@@ -220,25 +346,31 @@ func (p *Patient) WithViruses(patientViruses []models.Virus, viruses []models.Vi
 	}
 }
 
+// WithDiagnoses sets the patient's current diagnoses, which leaves out the revoked and replaced results.
 func (p *Patient) WithDiagnoses(diagnosesResults []models.DiagnosisResult, diagnoses []models.Diagnosis) {
-	diagnosisMapped := make(map[uint]Diagnosis)
+	(*p).Diagnoses = diagnosisResultsFromModels(diagnosesResults, diagnoses, true)
+}
 
-	for _, d := range diagnoses {
-		outD := new(Diagnosis)
-		outD.FromModel(d)
-		diagnosisMapped[d.Id] = *outD
-	}
-
-	(*p).Diagnoses = make([]DiagnosisResult, 0, len(diagnosesResults))
-	for _, dr := range diagnosesResults {
-		(*p).Diagnoses = append((*p).Diagnoses, DiagnosisResult{
-			Diagnosis:   diagnosisMapped[dr.DiagnosisId],
-			DiagnosisId: dr.DiagnosisId,
-			Id:          dr.Id,
-			DiagnosedAt: dr.DiagnosedAt,
-			CreatedAt:   dr.CreatedAt,
-		})
+// validatePatient validates the fields that are set by the clients when a patient is created or updated,
+// where the first visit reason is optional since the imported patients don't have one.
+func validatePatient(patient models.Patient) error {
+	if strings.TrimSpace(patient.FirstName) == "" {
+		return ErrValidation{Field: "first_name"}
+	}
+	if strings.TrimSpace(patient.LastName) == "" {
+		return ErrValidation{Field: "last_name"}
+	}
+	if patient.DateOfBirth.IsZero() || patient.DateOfBirth.After(time.Now()) {
+		return ErrValidation{Field: "date_of_birth"}
+	}
+	if patient.FirstVisitReason != "" && !slices.Contains(models.PatientFirstVisitReasons(), patient.FirstVisitReason) {
+		return ErrValidation{Field: "first_visit_reason"}
+	}
+	if patient.CarrierStatus != "" && (patient.Gender || !slices.Contains(models.CarrierStatuses(), patient.CarrierStatus)) {
+		return ErrValidation{Field: "carrier_status"}
 	}
+
+	return nil
 }
 
 type CreatePatientParams struct {
@@ -270,59 +402,48 @@ func (a *Actions) CreatePatient(params CreatePatientParams) (CreatePatientPayloa
 		Viruses:             []models.Virus{},
 		BloodTestResults:    []models.BloodTestResult{},
 		FamilyHistoryExists: params.NewPatient.FamilyHistoryExists,
+		CarrierStatus:       models.CarrierStatus(params.NewPatient.CarrierStatus),
+	}
+	err := validatePatient(newPatient)
+	if err != nil {
+		return CreatePatientPayload{}, err
 	}
 
-	residencyAddresses, _ := a.app.GetAllAddressesALike(models.Address{
-		Governorate: params.NewPatient.Residency.Governorate,
-		Suburb:      params.NewPatient.Residency.Suburb,
-		Street:      params.NewPatient.Residency.Street,
-	})
+	// INFO: in case of minors without a national id, the password will be the patient's phone number without the country code
+	password := params.NewPatient.NationalId
+	if password == "" {
+		password = cleanPhoneNumberCountryCode(params.NewPatient.PhoneNumber)
+	}
 
-	if len(residencyAddresses) == 1 {
-		newPatient.Residency.Id = residencyAddresses[0].Id
-		newPatient.ResidencyId = residencyAddresses[0].Id
-	} else {
-		residency, err := a.app.CreateAddress(params.NewPatient.Residency.IntoModel())
+	err = a.app.WithTx(func(txApp *app.App) error {
+		residency, err := findOrCreateAddress(txApp, params.NewPatient.Residency)
 		if err != nil {
-			return CreatePatientPayload{}, err
+			return err
 		}
 		newPatient.Residency = residency
-	}
+		newPatient.ResidencyId = residency.Id
 
-	placesOfBirth, _ := a.app.GetAllAddressesALike(models.Address{
-		Governorate: params.NewPatient.PlaceOfBirth.Governorate,
-		Suburb:      params.NewPatient.PlaceOfBirth.Suburb,
-		Street:      params.NewPatient.PlaceOfBirth.Street,
-	})
-
-	if len(placesOfBirth) == 1 {
-		newPatient.PlaceOfBirth.Id = placesOfBirth[0].Id
-		newPatient.PlaceOfBirthId = placesOfBirth[0].Id
-	} else {
-		placeOfBirth, err := a.app.CreateAddress(params.NewPatient.PlaceOfBirth.IntoModel())
+		placeOfBirth, err := findOrCreateAddress(txApp, params.NewPatient.PlaceOfBirth)
 		if err != nil {
-			return CreatePatientPayload{}, err
+			return err
 		}
 		newPatient.PlaceOfBirth = placeOfBirth
-	}
+		newPatient.PlaceOfBirthId = placeOfBirth.Id
 
-	newPatient, err := a.app.CreatePatient(newPatient)
-	if err != nil {
-		return CreatePatientPayload{}, err
-	}
+		newPatient, err = txApp.CreatePatient(newPatient)
+		if err != nil {
+			return err
+		}
 
-	// INFO: in case of minors without a national id, the password will be the patient's phone number without the country code
-	password := params.NewPatient.NationalId
-	if password == "" {
-		password = cleanPhoneNumberCountryCode(params.NewPatient.PhoneNumber)
-	}
+		_, err = txApp.CreateAccount(models.Account{
+			DisplayName: newPatient.FirstName + " " + newPatient.LastName,
+			Username:    newPatient.PublicId,
+			Password:    password,
+			Type:        models.AccountTypePatient,
+			Permissions: patientPermissions,
+		})
 
-	_, err = a.app.CreateAccount(models.Account{
-		DisplayName: newPatient.FirstName + " " + newPatient.LastName,
-		Username:    newPatient.PublicId,
-		Password:    password,
-		Type:        models.AccountTypePatient,
-		Permissions: patientPermissions,
+		return err
 	})
 	if err != nil {
 		return CreatePatientPayload{}, err
@@ -357,11 +478,22 @@ func (a *Actions) CreatePatientBloodTestResult(params CreatePatientBloodTestResu
 
 	bloodTestResultFields := make([]models.BloodTestFilledField, 0, len(params.BloodTest.FilledFields))
 	for _, field := range params.BloodTest.FilledFields {
-		bloodTestResultFields = append(bloodTestResultFields, models.BloodTestFilledField{
-			BloodTestFieldId: field.BloodTestFieldId,
-			ValueNumber:      field.ValueNumber,
-			ValueString:      field.ValueString,
-		})
+		bloodTestResultFields = append(bloodTestResultFields, field.IntoModel())
+	}
+
+	bt, err := a.app.GetBloodTest(params.BloodTest.BloodTestId)
+	if err != nil {
+		return CreatePatientBloodTestResultPayload{}, err
+	}
+	if bt.IsArchived() {
+		return CreatePatientBloodTestResultPayload{}, &app.ErrArchived{
+			ResourceName: "blood_test",
+		}
+	}
+
+	err = validateBloodTestFilledFields(bt, bloodTestResultFields, nil, params.BloodTest.Pending)
+	if err != nil {
+		return CreatePatientBloodTestResultPayload{}, err
 	}
 
 	_, err = a.app.CreateBloodTestResult(models.BloodTestResult{
@@ -399,11 +531,22 @@ func (a *Actions) CreatePatientDiagnosisResult(params CreatePatientDiagnosisResu
 		return CreatePatientDiagnosisResultPayload{}, err
 	}
 
-	_, err = a.app.CreateDiagnosisResult(models.DiagnosisResult{
-		DiagnosisId: params.Diagnosis.DiagnosisId,
-		PatientId:   patient.Id,
-		DiagnosedAt: params.Diagnosis.DiagnosedAt,
-	})
+	diagnosis, err := a.app.GetDiagnosis(params.Diagnosis.DiagnosisId)
+	if err != nil {
+		return CreatePatientDiagnosisResultPayload{}, err
+	}
+	if diagnosis.IsArchived() {
+		return CreatePatientDiagnosisResultPayload{}, &app.ErrArchived{
+			ResourceName: "diagnosis",
+		}
+	}
+
+	dr, err := diagnosisResultFromParams(diagnosis, patient.Id, params.Account.Id, params.Diagnosis)
+	if err != nil {
+		return CreatePatientDiagnosisResultPayload{}, err
+	}
+
+	_, err = a.app.CreateDiagnosisResult(dr)
 	if err != nil {
 		return CreatePatientDiagnosisResultPayload{}, err
 	}
@@ -434,24 +577,32 @@ func (a *Actions) UpdatePatientPendingBloodTestResult(params UpdatePatientPendin
 		return UpdatePatientPendingBloodTestResultPayload{}, err
 	}
 
-	if !slices.ContainsFunc(patient.BloodTestResults, func(btr models.BloodTestResult) bool {
+	btrIdx := slices.IndexFunc(patient.BloodTestResults, func(btr models.BloodTestResult) bool {
 		return btr.Id == params.BloodTestResultId
-	}) {
+	})
+	if btrIdx < 0 {
 		return UpdatePatientPendingBloodTestResultPayload{}, app.ErrNotFound{
 			ResourceName: "blood_test_result",
 		}
 	}
+	btr := patient.BloodTestResults[btrIdx]
 
 	bloodTestResultFields := make([]models.BloodTestFilledField, 0, len(params.FilledFields))
 	for _, field := range params.FilledFields {
-		bloodTestResultFields = append(bloodTestResultFields, models.BloodTestFilledField{
-			BloodTestFieldId: field.BloodTestFieldId,
-			ValueNumber:      field.ValueNumber,
-			ValueString:      field.ValueString,
-		})
+		bloodTestResultFields = append(bloodTestResultFields, field.IntoModel())
+	}
+
+	bt, err := a.app.GetBloodTest(btr.BloodTestId)
+	if err != nil {
+		return UpdatePatientPendingBloodTestResultPayload{}, err
+	}
+
+	err = validateBloodTestFilledFields(bt, bloodTestResultFields, btr.FilledFields, false)
+	if err != nil {
+		return UpdatePatientPendingBloodTestResultPayload{}, err
 	}
 
-	err = a.app.UpdatePatientPendingBloodTestResultFields(params.BloodTestResultId, bloodTestResultFields)
+	err = a.app.UpdatePatientPendingBloodTestResultFields(patient.Id, params.BloodTestResultId, bloodTestResultFields)
 	if err != nil {
 		return UpdatePatientPendingBloodTestResultPayload{}, err
 	}
@@ -459,107 +610,299 @@ func (a *Actions) UpdatePatientPendingBloodTestResult(params UpdatePatientPendin
 	return UpdatePatientPendingBloodTestResultPayload{}, nil
 }
 
-type FindPatientsParams struct {
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+type ListedPatient struct {
+	Patient
+	// Score is the patient's relevance to the query, it's only set when the patients are sorted by relevance.
+	Score int `json:"score,omitempty"`
+}
+
+type ListPatientsParams struct {
 	ActionContext
-	PublicId     string  `json:"public_id"`
-	NationalId   string  `json:"national_id"`
-	FirstName    string  `json:"first_name"`
-	LastName     string  `json:"last_name"`
-	FatherName   string  `json:"father_name"`
-	MotherName   string  `json:"mother_name"`
-	PlaceOfBirth Address `json:"place_of_birth"`
-	Residency    Address `json:"residency"`
-	PhoneNumber  string  `json:"phone_number"`
+	Query              string
+	Gender             string
+	Governorate        string
+	DiagnosisId        uint
+	HemophiliaType     string
+	HemophiliaSeverity string
+	// MinAge and MaxAge are in whole years, and they're nil when they're not set.
+	MinAge      *int
+	MaxAge      *int
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	VisitedFrom time.Time
+	VisitedTo   time.Time
+	// Sort defaults to relevance when there's a query, and to created_at otherwise,
+	// and Order defaults to asc for name and to desc otherwise.
+	Sort  string
+	Order string
+	// Cursor is a previous page's next cursor, which is rejected when any of the other params except Limit changed.
+	Cursor string
+	Limit  int
 }
 
-func (p *FindPatientsParams) clean() {
-	p.PublicId = strings.TrimSpace(p.PublicId)
-	p.NationalId = strings.TrimSpace(p.NationalId)
-	p.FirstName = strings.TrimSpace(p.FirstName)
-	p.LastName = strings.TrimSpace(p.LastName)
-	p.FatherName = strings.TrimSpace(p.FatherName)
-	p.MotherName = strings.TrimSpace(p.MotherName)
-	p.PhoneNumber = strings.TrimSpace(p.PhoneNumber)
+type ListPatientsPayload struct {
+	Data  []ListedPatient `json:"data"`
+	Total int64           `json:"total"`
+	// NextCursor is empty for the last page.
+	NextCursor string `json:"next_cursor"`
+	Limit      int    `json:"limit"`
 }
 
-func (p *FindPatientsParams) empty() bool {
-	return p.PublicId == "" && p.NationalId == "" &&
-		p.FirstName == "" && p.LastName == "" &&
-		p.FatherName == "" && p.MotherName == "" &&
-		p.PhoneNumber == ""
+// patientCursor is the JSON form of models.PatientCursor, which is sent to the clients base64 encoded,
+// with the hash of the list's params, see patientListParamsHash.
+type patientCursor struct {
+	Sort       string `json:"s"`
+	Descending bool   `json:"d"`
+	Value      string `json:"v"`
+	Id         uint   `json:"i"`
+	ParamsHash string `json:"h"`
 }
 
-type FindPatientsPayload struct {
-	Data []Patient `json:"data"`
+// patientListParamsHash hashes the params that decide which patients are listed and in what order,
+// so that a cursor isn't used to continue a list with different params.
+func patientListParamsHash(params ListPatientsParams) string {
+	params.ActionContext = ActionContext{}
+	params.Cursor = ""
+	params.Limit = 0
+	paramsJson, _ := json.Marshal(params)
+	hash := sha256.Sum256(paramsJson)
+
+	return base64.RawURLEncoding.EncodeToString(hash[:12])
 }
 
-func (a *Actions) FindPatients(params FindPatientsParams) (FindPatientsPayload, error) {
-	params.clean()
+func encodePatientCursor(cursor models.PatientCursor, paramsHash string) string {
+	cursorJson, _ := json.Marshal(patientCursor{
+		Sort:       string(cursor.Sort),
+		Descending: cursor.Descending,
+		Value:      cursor.Value,
+		Id:         cursor.Id,
+		ParamsHash: paramsHash,
+	})
 
-	if params.empty() {
-		return FindPatientsPayload{}, app.ErrNotFound{
-			ResourceName: "patient",
-		}
+	return base64.RawURLEncoding.EncodeToString(cursorJson)
+}
+
+// decodePatientCursor decodes the cursor, which must've been encoded for a list with the same params hash.
+func decodePatientCursor(encoded, paramsHash string) (models.PatientCursor, error) {
+	cursorJson, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return models.PatientCursor{}, ErrValidation{Field: "cursor"}
+	}
+
+	var cursor patientCursor
+	err = json.Unmarshal(cursorJson, &cursor)
+	if err != nil || cursor.ParamsHash != paramsHash {
+		return models.PatientCursor{}, ErrValidation{Field: "cursor"}
+	}
+
+	decoded := models.PatientCursor{
+		Sort:       models.PatientSort(cursor.Sort),
+		Descending: cursor.Descending,
+		Value:      cursor.Value,
+		Id:         cursor.Id,
 	}
+	_, err = decoded.SortValue()
+	if err != nil {
+		return models.PatientCursor{}, ErrValidation{Field: "cursor"}
+	}
+
+	return decoded, nil
+}
 
+func (a *Actions) ListPatients(params ListPatientsParams) (ListPatientsPayload, error) {
 	if !params.Account.HasPermission(models.AccountPermissionReadPatient) {
-		return FindPatientsPayload{}, ErrPermissionDenied{}
-	}
-
-	patients, err := a.app.FindPatientsByIndexFields(models.PatientIndexFields{
-		PublicId:     params.PublicId,
-		NationalId:   params.NationalId,
-		FirstName:    params.FirstName,
-		LastName:     params.LastName,
-		FatherName:   params.FatherName,
-		MotherName:   params.MotherName,
-		PlaceOfBirth: models.Address{},
-		Residency:    models.Address{},
-		PhoneNumber:  params.PhoneNumber,
-	})
+		return ListPatientsPayload{}, ErrPermissionDenied{}
+	}
+
+	filter := models.PatientListFilter{
+		Terms:              models.ParsePatientSearchTerms(params.Query),
+		Governorate:        strings.TrimSpace(params.Governorate),
+		DiagnosisId:        params.DiagnosisId,
+		HemophiliaType:     models.HemophiliaType(strings.ToUpper(strings.TrimSpace(params.HemophiliaType))),
+		HemophiliaSeverity: models.HemophiliaSeverity(strings.ToLower(strings.TrimSpace(params.HemophiliaSeverity))),
+		CreatedFrom:        params.CreatedFrom,
+		CreatedTo:          params.CreatedTo,
+		VisitedFrom:        params.VisitedFrom,
+		VisitedTo:          params.VisitedTo,
+		Sort:               models.PatientSort(params.Sort),
+		Limit:              params.Limit,
+	}
+
+	switch params.Gender {
+	case "":
+		// both genders are listed
+	case "male", "female":
+		male := params.Gender == "male"
+		filter.Gender = &male
+	default:
+		return ListPatientsPayload{}, ErrValidation{Field: "gender"}
+	}
+	if filter.HemophiliaType != "" && !slices.Contains(models.HemophiliaTypes(), filter.HemophiliaType) {
+		return ListPatientsPayload{}, ErrValidation{Field: "hemophilia_type"}
+	}
+	if filter.HemophiliaSeverity != "" && !slices.Contains(models.HemophiliaSeverities(), filter.HemophiliaSeverity) {
+		return ListPatientsPayload{}, ErrValidation{Field: "hemophilia_severity"}
+	}
+
+	now := time.Now().UTC()
+	if params.MinAge != nil {
+		if *params.MinAge < 0 {
+			return ListPatientsPayload{}, ErrValidation{Field: "min_age"}
+		}
+		filter.BornBefore = now.AddDate(-*params.MinAge, 0, 0)
+	}
+	if params.MaxAge != nil {
+		if *params.MaxAge < 0 || (params.MinAge != nil && *params.MaxAge < *params.MinAge) {
+			return ListPatientsPayload{}, ErrValidation{Field: "max_age"}
+		}
+		filter.BornAfter = now.AddDate(-*params.MaxAge-1, 0, 0)
+	}
+	if !params.CreatedFrom.IsZero() && !params.CreatedTo.IsZero() && !params.CreatedTo.After(params.CreatedFrom) {
+		return ListPatientsPayload{}, ErrValidation{Field: "created_to"}
+	}
+	if !params.VisitedFrom.IsZero() && !params.VisitedTo.IsZero() && !params.VisitedTo.After(params.VisitedFrom) {
+		return ListPatientsPayload{}, ErrValidation{Field: "visited_to"}
+	}
+
+	if filter.Sort == "" {
+		filter.Sort = models.PatientSortCreatedAt
+		if len(filter.Terms) > 0 {
+			filter.Sort = models.PatientSortRelevance
+		}
+	}
+	if !slices.Contains(models.PatientSorts(), filter.Sort) ||
+		(filter.Sort == models.PatientSortRelevance && len(filter.Terms) == 0) {
+		return ListPatientsPayload{}, ErrValidation{Field: "sort"}
+	}
+	switch params.Order {
+	case "":
+		filter.Descending = filter.Sort != models.PatientSortName
+	case "asc", "desc":
+		filter.Descending = params.Order == "desc"
+	default:
+		return ListPatientsPayload{}, ErrValidation{Field: "order"}
+	}
+	if filter.Sort == models.PatientSortRelevance {
+		filter.Descending = true
+	}
+
+	paramsHash := patientListParamsHash(params)
+	if params.Cursor != "" {
+		cursor, err := decodePatientCursor(params.Cursor, paramsHash)
+		if err != nil {
+			return ListPatientsPayload{}, err
+		}
+		if cursor.Sort != filter.Sort || cursor.Descending != filter.Descending {
+			return ListPatientsPayload{}, ErrValidation{Field: "cursor"}
+		}
+		filter.Cursor = &cursor
+	}
+	if filter.Limit < 1 || filter.Limit > maxPageSize {
+		filter.Limit = defaultPageSize
+	}
+
+	list, err := a.app.ListPatients(filter)
 	if err != nil {
-		return FindPatientsPayload{}, err
+		return ListPatientsPayload{}, err
 	}
 
-	outPatients := make([]Patient, 0, len(patients))
-	for _, patient := range patients {
+	outPatients := make([]ListedPatient, 0, len(list.Results))
+	for _, result := range list.Results {
 		outPatient := new(Patient)
-		outPatient.FromModel(patient)
-		outPatients = append(outPatients, *outPatient)
+		outPatient.FromModel(result.Patient)
+		outPatients = append(outPatients, ListedPatient{
+			Patient: *outPatient,
+			Score:   result.Score,
+		})
 	}
 
-	return FindPatientsPayload{
-		Data: outPatients,
-	}, nil
+	payload := ListPatientsPayload{
+		Data:  outPatients,
+		Total: list.Total,
+		Limit: filter.Limit,
+	}
+	if list.NextCursor != nil {
+		payload.NextCursor = encodePatientCursor(*list.NextCursor, paramsHash)
+	}
+
+	return payload, nil
+}
+
+type VisitedPatient struct {
+	Patient
+	VisitsCount int       `json:"visits_count"`
+	LastVisitAt time.Time `json:"last_visit_at"`
 }
 
-type ListLastPatientsParams struct {
+type ListVisitedPatientsParams struct {
 	ActionContext
+	From        time.Time
+	To          time.Time
+	VisitReason string
+	Page        int
+	PageSize    int
 }
 
-type ListLastPatientsPayload struct {
-	Data []Patient `json:"data"`
+type ListVisitedPatientsPayload struct {
+	Data     []VisitedPatient `json:"data"`
+	Total    int64            `json:"total"`
+	Page     int              `json:"page"`
+	PageSize int              `json:"page_size"`
 }
 
-func (a *Actions) ListLastPatients(params ListLastPatientsParams) (ListLastPatientsPayload, error) {
-	if !params.Account.HasPermission(models.AccountPermissionReadPatient) {
-		return ListLastPatientsPayload{}, ErrPermissionDenied{}
+func (a *Actions) ListVisitedPatients(params ListVisitedPatientsParams) (ListVisitedPatientsPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionReadOtherVisits) {
+		return ListVisitedPatientsPayload{}, ErrPermissionDenied{}
+	}
+
+	if params.From.IsZero() {
+		return ListVisitedPatientsPayload{}, ErrValidation{Field: "from"}
+	}
+	if params.To.IsZero() || !params.To.After(params.From) {
+		return ListVisitedPatientsPayload{}, ErrValidation{Field: "to"}
+	}
+	if params.VisitReason != "" && !slices.Contains(models.VisitReasons(), models.VisitReason(params.VisitReason)) {
+		return ListVisitedPatientsPayload{}, ErrValidation{Field: "reason"}
+	}
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.PageSize < 1 || params.PageSize > maxPageSize {
+		params.PageSize = defaultPageSize
 	}
 
-	patients, err := a.app.ListLastPatients(200)
+	summaries, total, err := a.app.FindPatientsByVisitRange(models.VisitDateRangeFilter{
+		From:   params.From,
+		To:     params.To,
+		Reason: models.VisitReason(params.VisitReason),
+		Offset: (params.Page - 1) * params.PageSize,
+		Limit:  params.PageSize,
+	})
 	if err != nil {
-		return ListLastPatientsPayload{}, err
+		return ListVisitedPatientsPayload{}, err
 	}
 
-	outPatients := make([]Patient, 0, len(patients))
-	for _, patient := range patients {
+	outPatients := make([]VisitedPatient, 0, len(summaries))
+	for _, summary := range summaries {
 		outPatient := new(Patient)
-		outPatient.FromModel(patient)
-		outPatients = append(outPatients, *outPatient)
+		outPatient.FromModel(summary.Patient)
+		outPatients = append(outPatients, VisitedPatient{
+			Patient:     *outPatient,
+			VisitsCount: summary.VisitsCount,
+			LastVisitAt: summary.LastVisitAt,
+		})
 	}
 
-	return ListLastPatientsPayload{
-		Data: outPatients,
+	return ListVisitedPatientsPayload{
+		Data:     outPatients,
+		Total:    total,
+		Page:     params.Page,
+		PageSize: params.PageSize,
 	}, nil
 }
 
@@ -635,6 +978,183 @@ func (a *Actions) DeletePatient(params DeletePatientParams) (DeletePatientPayloa
 	return DeletePatientPayload{}, nil
 }
 
+// PatientUpdate is a partial patient, where the fields that aren't set keep their current values.
+type PatientUpdate struct {
+	NationalId          *string    `json:"national_id"`
+	Nationality         *string    `json:"nationality"`
+	FirstName           *string    `json:"first_name"`
+	LastName            *string    `json:"last_name"`
+	FatherName          *string    `json:"father_name"`
+	MotherName          *string    `json:"mother_name"`
+	PlaceOfBirth        *Address   `json:"place_of_birth"`
+	DateOfBirth         *time.Time `json:"date_of_birth"`
+	Residency           *Address   `json:"residency"`
+	Gender              *bool      `json:"gender"`
+	PhoneNumber         *string    `json:"phone_number"`
+	BATScore            *uint      `json:"bat_score"`
+	FamilyHistoryExists *bool      `json:"family_history_exists"`
+	CarrierStatus       *string    `json:"carrier_status"`
+	FirstVisitReason    *string    `json:"first_visit_reason"`
+}
+
+// applyTo sets the update's set fields on the patient, except for the addresses which need to be found or created first.
+func (u PatientUpdate) applyTo(patient *models.Patient) {
+	setString := func(field *string, value *string) {
+		if value != nil {
+			*field = strings.TrimSpace(*value)
+		}
+	}
+
+	setString(&patient.NationalId, u.NationalId)
+	setString(&patient.Nationality, u.Nationality)
+	setString(&patient.FirstName, u.FirstName)
+	setString(&patient.LastName, u.LastName)
+	setString(&patient.FatherName, u.FatherName)
+	setString(&patient.MotherName, u.MotherName)
+	setString(&patient.PhoneNumber, u.PhoneNumber)
+	if u.DateOfBirth != nil {
+		patient.DateOfBirth = *u.DateOfBirth
+	}
+	if u.Gender != nil {
+		patient.Gender = *u.Gender
+	}
+	if u.BATScore != nil {
+		patient.BATScore = *u.BATScore
+	}
+	if u.FamilyHistoryExists != nil {
+		patient.FamilyHistoryExists = *u.FamilyHistoryExists
+	}
+	if u.CarrierStatus != nil {
+		patient.CarrierStatus = models.CarrierStatus(strings.TrimSpace(*u.CarrierStatus))
+	}
+	if u.FirstVisitReason != nil {
+		patient.FirstVisitReason = models.PatientFirstVisitReason(strings.TrimSpace(*u.FirstVisitReason))
+	}
+}
+
+type UpdatePatientParams struct {
+	ActionContext
+	PublicId   string
+	NewPatient PatientUpdate `json:"new_patient"`
+}
+
+type UpdatePatientPayload struct {
+	Changes []PatientFieldChange `json:"changes"`
+}
+
+func (a *Actions) UpdatePatient(params UpdatePatientParams) (UpdatePatientPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionWritePatient) {
+		return UpdatePatientPayload{}, ErrPermissionDenied{}
+	}
+
+	patient, err := a.app.GetMinimalPatientByPublicId(params.PublicId)
+	if err != nil {
+		return UpdatePatientPayload{}, err
+	}
+
+	newPatient := patient
+	params.NewPatient.applyTo(&newPatient)
+	err = validatePatient(newPatient)
+	if err != nil {
+		return UpdatePatientPayload{}, err
+	}
+
+	var changes []models.PatientFieldChange
+	err = a.app.WithTx(func(txApp *app.App) error {
+		if params.NewPatient.Residency != nil {
+			residency, err := findOrCreateAddress(txApp, *params.NewPatient.Residency)
+			if err != nil {
+				return err
+			}
+			newPatient.Residency = residency
+			newPatient.ResidencyId = residency.Id
+		}
+
+		if params.NewPatient.PlaceOfBirth != nil {
+			placeOfBirth, err := findOrCreateAddress(txApp, *params.NewPatient.PlaceOfBirth)
+			if err != nil {
+				return err
+			}
+			newPatient.PlaceOfBirth = placeOfBirth
+			newPatient.PlaceOfBirthId = placeOfBirth.Id
+		}
+
+		var err error
+		changes, err = txApp.UpdatePatient(patient.Id, newPatient, params.Account.Id)
+		return err
+	})
+	if err != nil {
+		return UpdatePatientPayload{}, err
+	}
+
+	outChanges := make([]PatientFieldChange, 0, len(changes))
+	for _, change := range changes {
+		outChange := new(PatientFieldChange)
+		outChange.FromModel(change)
+		outChanges = append(outChanges, *outChange)
+	}
+
+	return UpdatePatientPayload{
+		Changes: outChanges,
+	}, nil
+}
+
+type PatientFieldChange struct {
+	Id        uint      `json:"id"`
+	AccountId uint      `json:"account_id"`
+	FieldName string    `json:"field_name"`
+	OldValue  string    `json:"old_value"`
+	NewValue  string    `json:"new_value"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (c *PatientFieldChange) FromModel(change models.PatientFieldChange) {
+	(*c) = PatientFieldChange{
+		Id:        change.Id,
+		AccountId: change.AccountId,
+		FieldName: change.FieldName,
+		OldValue:  change.OldValue,
+		NewValue:  change.NewValue,
+		CreatedAt: change.CreatedAt,
+	}
+}
+
+type ListPatientFieldChangesParams struct {
+	ActionContext
+	PublicId string
+}
+
+type ListPatientFieldChangesPayload struct {
+	Data []PatientFieldChange `json:"data"`
+}
+
+func (a *Actions) ListPatientFieldChanges(params ListPatientFieldChangesParams) (ListPatientFieldChangesPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionReadPatient) {
+		return ListPatientFieldChangesPayload{}, ErrPermissionDenied{}
+	}
+
+	patient, err := a.app.GetMinimalPatientByPublicId(params.PublicId)
+	if err != nil {
+		return ListPatientFieldChangesPayload{}, err
+	}
+
+	changes, err := a.app.ListPatientFieldChanges(patient.Id)
+	if err != nil {
+		return ListPatientFieldChangesPayload{}, err
+	}
+
+	outChanges := make([]PatientFieldChange, 0, len(changes))
+	for _, change := range changes {
+		outChange := new(PatientFieldChange)
+		outChange.FromModel(change)
+		outChanges = append(outChanges, *outChange)
+	}
+
+	return ListPatientFieldChangesPayload{
+		Data: outChanges,
+	}, nil
+}
+
 type GeneratePatientCardParams struct {
 	ActionContext
 	PatientId string
@@ -0,0 +1,261 @@
+package actions
+
+import (
+	"shs/app/models"
+	"testing"
+	"time"
+)
+
+func TestValidatePatient(t *testing.T) {
+	valid := models.Patient{
+		FirstName:        "Ahmad",
+		LastName:         "Khalil",
+		DateOfBirth:      time.Date(2010, time.March, 4, 0, 0, 0, 0, time.UTC),
+		FirstVisitReason: models.PatientFirstVisitReasonBleeding,
+	}
+
+	tests := []struct {
+		name      string
+		patch     func(p *models.Patient)
+		wantField string
+	}{
+		{
+			name:  "valid",
+			patch: func(p *models.Patient) {},
+		},
+		{
+			name:      "blank first name",
+			patch:     func(p *models.Patient) { p.FirstName = "  " },
+			wantField: "first_name",
+		},
+		{
+			name:      "blank last name",
+			patch:     func(p *models.Patient) { p.LastName = "" },
+			wantField: "last_name",
+		},
+		{
+			name:      "missing date of birth",
+			patch:     func(p *models.Patient) { p.DateOfBirth = time.Time{} },
+			wantField: "date_of_birth",
+		},
+		{
+			name:      "date of birth in the future",
+			patch:     func(p *models.Patient) { p.DateOfBirth = time.Now().AddDate(0, 0, 1) },
+			wantField: "date_of_birth",
+		},
+		{
+			name:      "unknown first visit reason",
+			patch:     func(p *models.Patient) { p.FirstVisitReason = "checkup" },
+			wantField: "first_visit_reason",
+		},
+		{
+			name:  "no first visit reason",
+			patch: func(p *models.Patient) { p.FirstVisitReason = "" },
+		},
+		{
+			name:      "unknown carrier status",
+			patch:     func(p *models.Patient) { p.CarrierStatus = "unknown" },
+			wantField: "carrier_status",
+		},
+		{
+			name: "carrier status of a female patient",
+			patch: func(p *models.Patient) {
+				p.CarrierStatus = models.CarrierStatusCarrier
+			},
+		},
+		{
+			name: "carrier status of a male patient",
+			patch: func(p *models.Patient) {
+				p.Gender = true
+				p.CarrierStatus = models.CarrierStatusCarrier
+			},
+			wantField: "carrier_status",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patient := valid
+			tt.patch(&patient)
+
+			err := validatePatient(patient)
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("validatePatient() error = %v", err)
+				}
+				return
+			}
+			validationErr, ok := err.(ErrValidation)
+			if !ok || validationErr.Field != tt.wantField {
+				t.Errorf("validatePatient() error = %v, want a validation error of %q", err, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestPatientUpdateApplyTo(t *testing.T) {
+	ptr := func(s string) *string { return &s }
+	male := true
+	dateOfBirth := time.Date(2011, time.May, 6, 0, 0, 0, 0, time.UTC)
+
+	current := models.Patient{
+		NationalId:       "01020304050",
+		FirstName:        "Ahmad",
+		LastName:         "Khalil",
+		PhoneNumber:      "0933000000",
+		DateOfBirth:      time.Date(2010, time.March, 4, 0, 0, 0, 0, time.UTC),
+		CarrierStatus:    models.CarrierStatusPossibleCarrier,
+		FirstVisitReason: models.PatientFirstVisitReasonBleeding,
+	}
+
+	tests := []struct {
+		name   string
+		update PatientUpdate
+		want   models.Patient
+	}{
+		{
+			name: "empty update keeps everything",
+			want: current,
+		},
+		{
+			name: "set fields are trimmed and applied",
+			update: PatientUpdate{
+				FirstName:        ptr(" Omar "),
+				PhoneNumber:      ptr(""),
+				DateOfBirth:      &dateOfBirth,
+				Gender:           &male,
+				FirstVisitReason: ptr("referral"),
+			},
+			want: func() models.Patient {
+				p := current
+				p.FirstName = "Omar"
+				p.PhoneNumber = ""
+				p.DateOfBirth = dateOfBirth
+				p.Gender = true
+				p.FirstVisitReason = models.PatientFirstVisitReasonReferral
+				return p
+			}(),
+		},
+		{
+			name:   "carrier status can be cleared",
+			update: PatientUpdate{CarrierStatus: ptr("")},
+			want: func() models.Patient {
+				p := current
+				p.CarrierStatus = ""
+				return p
+			}(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patient := current
+			tt.update.applyTo(&patient)
+			if changes := tt.want.Diff(patient); len(changes) > 0 {
+				t.Errorf("applyTo() changed %+v from the wanted patient", changes)
+			}
+		})
+	}
+}
+
+func TestUpdateImportedPatient(t *testing.T) {
+	ptr := func(s string) *string { return &s }
+
+	// imported patients don't have a first visit reason, and their empty fields are placeholders.
+	imported := models.Patient{
+		PublicId:    "AB12CD",
+		FirstName:   "Ahmad",
+		LastName:    "Khalil",
+		DateOfBirth: time.Date(2010, time.March, 4, 0, 0, 0, 0, time.UTC),
+	}
+	imported.FillEmptyFieldsUsingPublicId()
+
+	tests := []struct {
+		name      string
+		update    PatientUpdate
+		wantField string
+	}{
+		{
+			name:   "placeholders are replaced",
+			update: PatientUpdate{NationalId: ptr("01020304050"), PhoneNumber: ptr("0933000000")},
+		},
+		{
+			name:   "first visit reason is set",
+			update: PatientUpdate{FirstVisitReason: ptr("bleeding")},
+		},
+		{
+			name:      "unknown first visit reason",
+			update:    PatientUpdate{FirstVisitReason: ptr("checkup")},
+			wantField: "first_visit_reason",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patient := imported
+			tt.update.applyTo(&patient)
+
+			err := validatePatient(patient)
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("validatePatient() error = %v", err)
+				}
+				return
+			}
+			validationErr, ok := err.(ErrValidation)
+			if !ok || validationErr.Field != tt.wantField {
+				t.Errorf("validatePatient() error = %v, want a validation error of %q", err, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestPatientCursorEncoding(t *testing.T) {
+	params := ListPatientsParams{Query: "ahmad", Gender: "male", Sort: "relevance"}
+	paramsHash := patientListParamsHash(params)
+	cursor := models.PatientCursor{Sort: models.PatientSortRelevance, Descending: true, Value: "30", Id: 7}
+	encoded := encodePatientCursor(cursor, paramsHash)
+
+	otherParams := params
+	otherParams.Query = "omar"
+	otherPage := params
+	otherPage.Cursor = encoded
+	otherPage.Limit = 10
+	otherPage.Account = models.Account{Id: 3}
+
+	tests := []struct {
+		name       string
+		encoded    string
+		paramsHash string
+		wantErr    bool
+	}{
+		{name: "same params", encoded: encoded, paramsHash: paramsHash},
+		{name: "another page of the same params", encoded: encoded, paramsHash: patientListParamsHash(otherPage)},
+		{name: "different params", encoded: encoded, paramsHash: patientListParamsHash(otherParams), wantErr: true},
+		{name: "not base64", encoded: "not a cursor!", paramsHash: paramsHash, wantErr: true},
+		{
+			name:       "invalid sort value",
+			encoded:    encodePatientCursor(models.PatientCursor{Sort: models.PatientSortRelevance, Value: "high"}, paramsHash),
+			paramsHash: paramsHash,
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, err := decodePatientCursor(tt.encoded, tt.paramsHash)
+			if tt.wantErr {
+				if _, ok := err.(ErrValidation); !ok {
+					t.Errorf("decodePatientCursor() error = %v, want a validation error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodePatientCursor() error = %v", err)
+			}
+			if decoded != cursor {
+				t.Errorf("decodePatientCursor() = %+v, want %+v", decoded, cursor)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package actions
+
+import (
+	"shs/app/models"
+	"slices"
+	"time"
+)
+
+type StockMovement struct {
+	Id                   uint      `json:"id"`
+	MedicineId           uint      `json:"medicine_id"`
+	AccountId            uint      `json:"account_id"`
+	Type                 string    `json:"type"`
+	Quantity             int       `json:"quantity"`
+	Reason               string    `json:"reason"`
+	PrescribedMedicineId uint      `json:"prescribed_medicine_id"`
+	Destination          string    `json:"destination"`
+	CreatedAt            time.Time `json:"created_at"`
+}
+
+func (m *StockMovement) FromModel(movement models.StockMovement) {
+	(*m) = StockMovement{
+		Id:                   movement.Id,
+		MedicineId:           movement.MedicineId,
+		AccountId:            movement.AccountId,
+		Type:                 string(movement.Type),
+		Quantity:             movement.Quantity,
+		Reason:               movement.Reason,
+		PrescribedMedicineId: movement.PrescribedMedicineId,
+		Destination:          movement.Destination,
+		CreatedAt:            movement.CreatedAt,
+	}
+}
+
+type CreateStockMovementParams struct {
+	ActionContext
+	MedicineId uint   `json:"medicine_id"`
+	Type       string `json:"type"`
+	// Quantity is the number of packages received, written off or transferred,
+	// and a signed difference for adjustments.
+	Quantity    int    `json:"quantity"`
+	Reason      string `json:"reason"`
+	Destination string `json:"destination"`
+}
+
+type CreateStockMovementPayload struct {
+	Id uint `json:"id"`
+}
+
+// CreateStockMovement records a manual stock movement, dispensations are only recorded through visits.
+func (a *Actions) CreateStockMovement(params CreateStockMovementParams) (CreateStockMovementPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionWriteMedicine) {
+		return CreateStockMovementPayload{}, ErrPermissionDenied{}
+	}
+
+	movementType := models.StockMovementType(params.Type)
+	if !slices.Contains(models.StockMovementTypes(), movementType) ||
+		movementType == models.StockMovementTypeDispensation {
+		return CreateStockMovementPayload{}, ErrValidation{Field: "type"}
+	}
+
+	quantity := params.Quantity
+	switch movementType {
+	case models.StockMovementTypeAdjustment:
+		if quantity == 0 {
+			return CreateStockMovementPayload{}, ErrValidation{Field: "quantity"}
+		}
+		if params.Reason == "" {
+			return CreateStockMovementPayload{}, ErrValidation{Field: "reason"}
+		}
+	case models.StockMovementTypeTransfer:
+		if params.Destination == "" {
+			return CreateStockMovementPayload{}, ErrValidation{Field: "destination"}
+		}
+		fallthrough
+	case models.StockMovementTypeExpiryWriteOff:
+		quantity = -quantity
+		fallthrough
+	default:
+		if params.Quantity < 1 {
+			return CreateStockMovementPayload{}, ErrValidation{Field: "quantity"}
+		}
+	}
+
+	movement, err := a.app.CreateStockMovement(models.StockMovement{
+		MedicineId:  params.MedicineId,
+		AccountId:   params.Account.Id,
+		Type:        movementType,
+		Quantity:    quantity,
+		Reason:      params.Reason,
+		Destination: params.Destination,
+	})
+	if err != nil {
+		return CreateStockMovementPayload{}, err
+	}
+
+	return CreateStockMovementPayload{
+		Id: movement.Id,
+	}, nil
+}
+
+type ListMedicineStockMovementsParams struct {
+	ActionContext
+	MedicineId uint
+	Type       string
+	From       time.Time
+	To         time.Time
+	Page       int
+	PageSize   int
+}
+
+type ListMedicineStockMovementsPayload struct {
+	Data []StockMovement `json:"data"`
+	// Amount is the medicine's current amount, and LedgerAmount is the sum of all of its movements,
+	// they're expected to be equal.
+	Amount       int   `json:"amount"`
+	LedgerAmount int   `json:"ledger_amount"`
+	Total        int64 `json:"total"`
+	Page         int   `json:"page"`
+	PageSize     int   `json:"page_size"`
+}
+
+func (a *Actions) ListMedicineStockMovements(params ListMedicineStockMovementsParams) (ListMedicineStockMovementsPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionReadMedicine) {
+		return ListMedicineStockMovementsPayload{}, ErrPermissionDenied{}
+	}
+
+	if params.Type != "" && !slices.Contains(models.StockMovementTypes(), models.StockMovementType(params.Type)) {
+		return ListMedicineStockMovementsPayload{}, ErrValidation{Field: "type"}
+	}
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.PageSize < 1 || params.PageSize > maxPageSize {
+		params.PageSize = defaultPageSize
+	}
+
+	medicine, err := a.app.GetMedicine(params.MedicineId)
+	if err != nil {
+		return ListMedicineStockMovementsPayload{}, err
+	}
+
+	ledgerAmount, err := a.app.GetMedicineLedgerAmount(medicine.Id)
+	if err != nil {
+		return ListMedicineStockMovementsPayload{}, err
+	}
+
+	movements, total, err := a.app.ListMedicineStockMovements(models.StockMovementFilter{
+		MedicineId: medicine.Id,
+		Type:       models.StockMovementType(params.Type),
+		From:       params.From,
+		To:         params.To,
+		Offset:     (params.Page - 1) * params.PageSize,
+		Limit:      params.PageSize,
+	})
+	if err != nil {
+		return ListMedicineStockMovementsPayload{}, err
+	}
+
+	outMovements := make([]StockMovement, 0, len(movements))
+	for _, movement := range movements {
+		outMovement := new(StockMovement)
+		outMovement.FromModel(movement)
+		outMovements = append(outMovements, *outMovement)
+	}
+
+	return ListMedicineStockMovementsPayload{
+		Data:         outMovements,
+		Amount:       medicine.Amount,
+		LedgerAmount: ledgerAmount,
+		Total:        total,
+		Page:         params.Page,
+		PageSize:     params.PageSize,
+	}, nil
+}
@@ -1,12 +1,16 @@
 package actions
 
-import "shs/app/models"
+import (
+	"shs/app/models"
+	"time"
+)
 
 type Virus struct {
 	Id           uint   `json:"id"`
 	Name         string `json:"name"`
 	BloodTestIds []uint `json:"blood_test_ids"`
 	// TODO: expose blood tests as a whole
+	ArchivedAt *time.Time `json:"archived_at"`
 }
 
 func (v Virus) IntoModel() models.Virus {
@@ -25,8 +29,9 @@ func (v Virus) IntoModel() models.Virus {
 
 func (v *Virus) FromModel(virus models.Virus) {
 	(*v) = Virus{
-		Id:   virus.Id,
-		Name: virus.Name,
+		Id:         virus.Id,
+		Name:       virus.Name,
+		ArchivedAt: virus.ArchivedAt,
 	}
 }
 
@@ -74,7 +79,7 @@ func (a *Actions) DeleteVirus(params DeleteVirusParams) (DeleteVirusPayload, err
 
 type ListAllVirusesParams struct {
 	ActionContext
-	NewVirus Virus `json:"new_virus"`
+	IncludeArchived bool
 }
 
 type ListAllVirusesPayload struct {
@@ -93,6 +98,9 @@ func (a *Actions) ListAllViruses(params ListAllVirusesParams) (ListAllVirusesPay
 
 	outViruses := make([]Virus, 0, len(viruses))
 	for _, virus := range viruses {
+		if virus.IsArchived() && !params.IncludeArchived {
+			continue
+		}
 		outVirus := new(Virus)
 		outVirus.FromModel(virus)
 		outViruses = append(outViruses, *outVirus)
@@ -102,3 +110,27 @@ func (a *Actions) ListAllViruses(params ListAllVirusesParams) (ListAllVirusesPay
 		Data: outViruses,
 	}, nil
 }
+
+type SetVirusArchivedParams struct {
+	ActionContext
+	VirusId  uint
+	Archived bool
+}
+
+type SetVirusArchivedPayload struct {
+}
+
+// SetVirusArchived archives the virus, which hides it from new infections while the records that have it still show it,
+// or unarchives it.
+func (a *Actions) SetVirusArchived(params SetVirusArchivedParams) (SetVirusArchivedPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionWriteVirus) {
+		return SetVirusArchivedPayload{}, ErrPermissionDenied{}
+	}
+
+	err := a.app.SetVirusArchived(params.VirusId, params.Archived)
+	if err != nil {
+		return SetVirusArchivedPayload{}, err
+	}
+
+	return SetVirusArchivedPayload{}, nil
+}
@@ -1,6 +1,8 @@
 package actions
 
 import (
+	"fmt"
+	"shs/app"
 	"shs/app/models"
 	"time"
 )
@@ -13,19 +15,58 @@ type Visit struct {
 	PatientWeight      float64              `json:"patient_weight"`
 	PatientHeight      float64              `json:"patient_height"`
 	PrescribedMedicine []PrescribedMedicine `json:"prescribed_medicine"`
+	Prescriptions      []Prescription       `json:"prescriptions"`
+}
+
+// PrescriptionLine prescribes a medicine either by its batch's id,
+// or by its product's id to have the product's batches allocated automatically.
+type PrescriptionLine struct {
+	Id           uint    `json:"id"`
+	ProductId    uint    `json:"product_id"`
+	Amount       int     `json:"amount"`
+	DosePerKg    float64 `json:"dose_per_kg"`
+	Instructions string  `json:"instructions"`
+}
+
+type Prescription struct {
+	Id           uint    `json:"id"`
+	ProductId    uint    `json:"product_id"`
+	Quantity     int     `json:"quantity"`
+	DosePerKg    float64 `json:"dose_per_kg"`
+	TotalIU      float64 `json:"total_iu"`
+	Instructions string  `json:"instructions"`
+}
+
+func (p *Prescription) FromModel(prescription models.Prescription) {
+	(*p) = Prescription{
+		Id:           prescription.Id,
+		ProductId:    prescription.ProductId,
+		Quantity:     prescription.Quantity,
+		DosePerKg:    prescription.DosePerKg,
+		TotalIU:      prescription.TotalIU,
+		Instructions: prescription.Instructions,
+	}
 }
 
 type CreatePatientVisitParams struct {
 	ActionContext
 	PatientId           string
-	VisitReason         string     `json:"visit_reason"`
-	VisitExtraDetails   string     `json:"visit_extra_details"`
-	PatientWeight       float64    `json:"patient_weight"`
-	PatientHeight       float64    `json:"patient_height"`
-	PrescribedMedicines []Medicine `json:"prescribed_medicines"`
+	VisitReason         string             `json:"visit_reason"`
+	VisitExtraDetails   string             `json:"visit_extra_details"`
+	PatientWeight       float64            `json:"patient_weight"`
+	PatientHeight       float64            `json:"patient_height"`
+	PrescribedMedicines []PrescriptionLine `json:"prescribed_medicines"`
+}
+
+// VisitWarning is a concern about a visit that didn't stop it from being created.
+type VisitWarning struct {
+	Type      string `json:"type"`
+	ProductId uint   `json:"product_id"`
+	Message   string `json:"message"`
 }
 
 type CreatePatientVisitPayload struct {
+	Warnings []VisitWarning `json:"warnings"`
 }
 
 func (a *Actions) CreatePatientVisit(params CreatePatientVisitParams) (CreatePatientVisitPayload, error) {
@@ -38,60 +79,256 @@ func (a *Actions) CreatePatientVisit(params CreatePatientVisitParams) (CreatePat
 		return CreatePatientVisitPayload{}, err
 	}
 
-	medIds := make([]uint, 0, len(params.PrescribedMedicines))
-	for _, med := range params.PrescribedMedicines {
-		medIds = append(medIds, med.Id)
+	for _, line := range params.PrescribedMedicines {
+		if line.Amount < 1 {
+			return CreatePatientVisitPayload{}, ErrValidation{Field: "amount"}
+		}
+		if line.Id == 0 && line.ProductId == 0 {
+			return CreatePatientVisitPayload{}, ErrValidation{Field: "id"}
+		}
+		if line.DosePerKg < 0 {
+			return CreatePatientVisitPayload{}, ErrValidation{Field: "dose_per_kg"}
+		}
 	}
 
-	meds, err := a.app.ListMedicinesByIds(medIds)
+	allocations, err := a.allocateMedicineBatches(params.PrescribedMedicines)
 	if err != nil {
 		return CreatePatientVisitPayload{}, err
 	}
 
 	prescribedMedicinesAmount := make(map[uint]int)
-	for _, med := range params.PrescribedMedicines {
-		prescribedMedicinesAmount[med.Id] += med.Amount
+	for _, batches := range allocations {
+		for _, batch := range batches {
+			prescribedMedicinesAmount[batch.Id] += batch.Amount
+		}
 	}
 
-	for _, med := range meds {
-		if prescribedMedicinesAmount[med.Id] > med.Amount {
-			return CreatePatientVisitPayload{}, ErrInsufficientMedicine{
-				MedicineName:    med.Name,
-				ExceedingAmount: prescribedMedicinesAmount[med.Id],
-				LeftPackages:    med.Amount,
+	err = a.checkFirstExpiredFirstOut(prescribedMedicinesAmount)
+	if err != nil {
+		return CreatePatientVisitPayload{}, err
+	}
+
+	warnings := make([]VisitWarning, 0)
+	if patient.InhibitorPositive {
+		for _, batches := range allocations {
+			product := batches[0].Product
+			if !product.IsPlainFactorConcentrate() {
+				continue
 			}
+			warnings = append(warnings, VisitWarning{
+				Type:      "inhibitor",
+				ProductId: product.Id,
+				Message: fmt.Sprintf("%s is a plain factor %s concentrate, and the patient has a positive inhibitor titer of %g BU",
+					product.Name, product.FactorType, patient.InhibitorTiter),
+			})
 		}
 	}
 
-	visit, err := a.app.CreatePatientVisit(models.Visit{
-		PatientId:     patient.Id,
-		Reason:        models.VisitReason(params.VisitReason),
-		Notes:         params.VisitExtraDetails,
-		PatientWeight: params.PatientWeight,
-		PatientHeight: params.PatientHeight,
+	err = a.app.WithTx(func(txApp *app.App) error {
+		visit, err := txApp.CreatePatientVisit(models.Visit{
+			PatientId:     patient.Id,
+			Reason:        models.VisitReason(params.VisitReason),
+			Notes:         params.VisitExtraDetails,
+			PatientWeight: params.PatientWeight,
+			PatientHeight: params.PatientHeight,
+		})
+		if err != nil {
+			return err
+		}
+
+		for i, line := range params.PrescribedMedicines {
+			batches := allocations[i]
+
+			totalIU := 0.0
+			for _, batch := range batches {
+				if batch.Product.IsDosedInIU() {
+					totalIU += float64(batch.Product.Dose * batch.Amount)
+				}
+			}
+			dosePerKg := line.DosePerKg
+			if dosePerKg == 0 && params.PatientWeight > 0 {
+				dosePerKg = totalIU / params.PatientWeight
+			}
+
+			prescription, err := txApp.CreatePrescription(models.Prescription{
+				VisitId:      visit.Id,
+				PatientId:    patient.Id,
+				ProductId:    batches[0].ProductId,
+				Quantity:     line.Amount,
+				DosePerKg:    dosePerKg,
+				TotalIU:      totalIU,
+				Instructions: line.Instructions,
+			})
+			if err != nil {
+				return err
+			}
+
+			for _, batch := range batches {
+				for dispensed := range batch.Amount {
+					prescribedMedicine, err := txApp.CreatePrescribedMedicine(models.PrescribedMedicine{
+						VisitId:        visit.Id,
+						PrescriptionId: prescription.Id,
+						PatientId:      patient.Id,
+						MedicineId:     batch.Id,
+					})
+					if err != nil {
+						return err
+					}
+
+					err = txApp.DispenseMedicine(prescribedMedicine, params.Account.Id)
+					if insufficientErr, ok := err.(*app.ErrInsufficientMedicine); ok {
+						// report the whole prescribed amount, since this batch's dispensations are rolled back.
+						insufficientErr.ExceedingAmount = batch.Amount
+						insufficientErr.LeftPackages += dispensed
+						return insufficientErr
+					}
+					if err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		return nil
 	})
 	if err != nil {
 		return CreatePatientVisitPayload{}, err
 	}
 
-	for _, med := range params.PrescribedMedicines {
-		for range med.Amount {
-			_, err = a.app.CreatePrescribedMedicine(models.PrescribedMedicine{
-				VisitId:    visit.Id,
-				PatientId:  patient.Id,
-				MedicineId: med.Id,
-			})
+	return CreatePatientVisitPayload{
+		Warnings: warnings,
+	}, nil
+}
+
+// allocateMedicineBatches returns the batches of every prescription line, with each batch's Amount set to the packages taken from it,
+// lines prescribed by their batch take from that batch only, and lines prescribed by their product take from the product's batches,
+// soonest to expire first.
+func (a *Actions) allocateMedicineBatches(lines []PrescriptionLine) ([][]models.Medicine, error) {
+	allocations := make([][]models.Medicine, len(lines))
+	allocatedAmount := make(map[uint]int)
+	for i, line := range lines {
+		if line.Id == 0 {
+			continue
+		}
+
+		batch, err := a.app.GetMedicine(line.Id)
+		if err != nil {
+			return nil, err
+		}
+		if batch.IsArchived() {
+			return nil, &app.ErrArchived{
+				ResourceName: "medicine",
+			}
+		}
+		batch.Amount = line.Amount
+		allocations[i] = []models.Medicine{batch}
+		allocatedAmount[batch.Id] += line.Amount
+	}
+
+	now := time.Now().UTC()
+	for i, line := range lines {
+		if line.Id != 0 {
+			continue
 		}
+
+		product, err := a.app.GetMedicineProduct(line.ProductId)
 		if err != nil {
-			return CreatePatientVisitPayload{}, err
+			return nil, err
 		}
-		err = a.app.DecrementMedicineAmount(med.Id, med.Amount)
+
+		batches, err := a.app.ListMedicineBatchesInStock(product.Id)
 		if err != nil {
-			return CreatePatientVisitPayload{}, err
+			return nil, err
+		}
+
+		leftToAllocate := line.Amount
+		for _, batch := range batches {
+			if leftToAllocate == 0 {
+				break
+			}
+			if batch.IsExpired(now) {
+				continue
+			}
+
+			available := min(batch.Amount-allocatedAmount[batch.Id], leftToAllocate)
+			if available <= 0 {
+				continue
+			}
+
+			allocatedAmount[batch.Id] += available
+			leftToAllocate -= available
+			batch.Amount = available
+			allocations[i] = append(allocations[i], batch)
+		}
+
+		if leftToAllocate > 0 {
+			return nil, &app.ErrInsufficientMedicine{
+				MedicineName:    product.Name,
+				ExceedingAmount: line.Amount,
+				LeftPackages:    line.Amount - leftToAllocate,
+			}
 		}
 	}
 
-	return CreatePatientVisitPayload{}, nil
+	return allocations, nil
+}
+
+// checkFirstExpiredFirstOut makes sure that expired batches aren't prescribed,
+// and that a batch isn't prescribed while a batch of the same product that expires sooner still has packages left.
+func (a *Actions) checkFirstExpiredFirstOut(prescribedMedicinesAmount map[uint]int) error {
+	medIds := make([]uint, 0, len(prescribedMedicinesAmount))
+	for medId := range prescribedMedicinesAmount {
+		medIds = append(medIds, medId)
+	}
+
+	meds, err := a.app.ListMedicinesByIds(medIds)
+	if err != nil {
+		return err
+	}
+	if len(meds) != len(medIds) {
+		return &app.ErrNotFound{
+			ResourceName: "medicine",
+		}
+	}
+
+	now := time.Now().UTC()
+	for _, med := range meds {
+		if med.IsExpired(now) {
+			return ErrExpiredMedicine{
+				MedicineName: med.Product.Name,
+				BatchNumber:  med.BatchNumber,
+				ExpiresAt:    med.ExpiresAt,
+			}
+		}
+
+		batches, err := a.app.ListMedicineBatchesInStock(med.ProductId)
+		if err != nil {
+			return err
+		}
+
+		for _, batch := range batches {
+			if !batch.ExpiresAt.Before(med.ExpiresAt) {
+				break
+			}
+			if batch.IsExpired(now) {
+				continue
+			}
+
+			leftPackages := batch.Amount - prescribedMedicinesAmount[batch.Id]
+			if leftPackages > 0 {
+				return ErrFirstExpiredFirstOut{
+					MedicineName:          med.Product.Name,
+					BatchNumber:           med.BatchNumber,
+					SuggestedMedicineId:   batch.Id,
+					SuggestedBatchNumber:  batch.BatchNumber,
+					SuggestedExpiresAt:    batch.ExpiresAt,
+					SuggestedLeftPackages: leftPackages,
+				}
+			}
+		}
+	}
+
+	return nil
 }
 
 type PrescribedMedicine struct {
@@ -259,12 +496,25 @@ func (a *Actions) ListPatientVisits(params ListPatientVisitsParams) (ListPatient
 			outMeds = append(outMeds, *outMed)
 		}
 
+		prescriptions, err := a.app.ListVisitPrescriptions(visit.Id)
+		if err != nil {
+			return ListPatientVisitsPayload{}, err
+		}
+
+		outPrescriptions := make([]Prescription, 0, len(prescriptions))
+		for _, prescription := range prescriptions {
+			outPrescription := new(Prescription)
+			outPrescription.FromModel(prescription)
+			outPrescriptions = append(outPrescriptions, *outPrescription)
+		}
+
 		outVisits = append(outVisits, Visit{
 			Id:                 visit.Id,
 			Reason:             string(visit.Reason),
 			ExtraNote:          visit.Notes,
 			VisitedAt:          visit.CreatedAt,
 			PrescribedMedicine: outMeds,
+			Prescriptions:      outPrescriptions,
 			PatientWeight:      visit.PatientWeight,
 			PatientHeight:      visit.PatientHeight,
 		})
@@ -274,3 +524,80 @@ func (a *Actions) ListPatientVisits(params ListPatientVisitsParams) (ListPatient
 		Data: outVisits,
 	}, nil
 }
+
+type FactorConsumption struct {
+	From               time.Time `json:"from"`
+	To                 time.Time `json:"to"`
+	PrescriptionsCount int       `json:"prescriptions_count"`
+	TotalIU            float64   `json:"total_iu"`
+	IUPerKg            float64   `json:"iu_per_kg"`
+	IUPerKgPerYear     float64   `json:"iu_per_kg_per_year"`
+	UnweightedIU       float64   `json:"unweighted_iu"`
+}
+
+func (c *FactorConsumption) FromModel(consumption models.FactorConsumption) {
+	(*c) = FactorConsumption{
+		From:               consumption.From,
+		To:                 consumption.To,
+		PrescriptionsCount: consumption.PrescriptionsCount,
+		TotalIU:            consumption.TotalIU,
+		IUPerKg:            consumption.IUPerKg,
+		IUPerKgPerYear:     consumption.IUPerKgPerYear,
+		UnweightedIU:       consumption.UnweightedIU,
+	}
+}
+
+type GetPatientFactorConsumptionParams struct {
+	ActionContext
+	PatientId string
+	From      time.Time
+	To        time.Time
+}
+
+type GetPatientFactorConsumptionPayload struct {
+	Total FactorConsumption   `json:"total"`
+	Years []FactorConsumption `json:"years"`
+}
+
+// GetPatientFactorConsumption reports the patient's factor consumption in IU/kg/year over the given range,
+// which defaults to the last year, and over each calendar year in it.
+func (a *Actions) GetPatientFactorConsumption(params GetPatientFactorConsumptionParams) (GetPatientFactorConsumptionPayload, error) {
+	if !params.Account.HasPermission(models.AccountPermissionReadOtherVisits) {
+		return GetPatientFactorConsumptionPayload{}, ErrPermissionDenied{}
+	}
+
+	if params.To.IsZero() {
+		params.To = time.Now().UTC()
+	}
+	if params.From.IsZero() {
+		params.From = params.To.AddDate(-1, 0, 0)
+	}
+	if !params.From.Before(params.To) {
+		return GetPatientFactorConsumptionPayload{}, ErrValidation{Field: "from"}
+	}
+
+	patient, err := a.app.GetMinimalPatientByPublicId(params.PatientId)
+	if err != nil {
+		return GetPatientFactorConsumptionPayload{}, err
+	}
+
+	total, years, err := a.app.GetPatientFactorConsumption(patient.Id, params.From, params.To)
+	if err != nil {
+		return GetPatientFactorConsumptionPayload{}, err
+	}
+
+	outTotal := new(FactorConsumption)
+	outTotal.FromModel(total)
+
+	outYears := make([]FactorConsumption, 0, len(years))
+	for _, year := range years {
+		outYear := new(FactorConsumption)
+		outYear.FromModel(year)
+		outYears = append(outYears, *outYear)
+	}
+
+	return GetPatientFactorConsumptionPayload{
+		Total: *outTotal,
+		Years: outYears,
+	}, nil
+}
@@ -0,0 +1,157 @@
+package app
+
+import (
+	"fmt"
+	"shs/app/models"
+	"shs/log"
+	"slices"
+	"time"
+)
+
+const (
+	dispensationAverageDays = 90
+	projectedRunOutDays     = 30
+)
+
+// CreateAlerts stores the alerts, then sends them through the notifier once they're committed.
+func (a *App) CreateAlerts(alerts []models.Alert) ([]models.Alert, error) {
+	if len(alerts) == 0 {
+		return alerts, nil
+	}
+
+	createdAlerts := make([]models.Alert, 0, len(alerts))
+	for _, alert := range alerts {
+		createdAlert, err := a.repo.CreateAlert(alert)
+		if err != nil {
+			return nil, err
+		}
+		createdAlerts = append(createdAlerts, createdAlert)
+	}
+
+	a.runAfterCommit(func() {
+		if a.notifier == nil {
+			return
+		}
+		err := a.notifier.Notify(createdAlerts)
+		if err != nil {
+			log.Errorf("Failed to notify %d alerts, error: %s\n", len(createdAlerts), err.Error())
+		}
+	})
+
+	return createdAlerts, nil
+}
+
+func (a *App) ListAlerts(filter models.AlertFilter) ([]models.Alert, int64, error) {
+	return a.repo.ListAlerts(filter)
+}
+
+// checkLowStock raises a low stock alert when taking quantity packages out of the medicine
+// has dropped its product's usable stock from its minimum stock or above to under it.
+func (a *App) checkLowStock(medicineId uint, takenQuantity int) error {
+	medicine, err := a.repo.GetMedicine(medicineId)
+	if err != nil {
+		return err
+	}
+
+	product, err := a.repo.GetMedicineProduct(medicine.ProductId)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	amountAfter := product.UsableAmount(now)
+	amountBefore := amountAfter
+	if !medicine.IsArchived() && !medicine.IsExpired(now) {
+		amountBefore += takenQuantity
+	}
+	if product.MinStock <= 0 || amountBefore < product.MinStock || amountAfter >= product.MinStock {
+		return nil
+	}
+
+	_, err = a.CreateAlerts([]models.Alert{{
+		Type:        models.AlertTypeLowStock,
+		ProductId:   product.Id,
+		StockAmount: amountAfter,
+		MinStock:    product.MinStock,
+		Message:     fmt.Sprintf("%s (%s) has %d packages left, under its minimum stock of %d", product.Name, product.Manufacturer, amountAfter, product.MinStock),
+	}})
+
+	return err
+}
+
+// ListMedicineProductProjections projects when each product runs out,
+// based on its average daily dispensation over the last 90 days, soonest to run out first.
+func (a *App) ListMedicineProductProjections(now time.Time) ([]models.MedicineProductProjection, error) {
+	products, err := a.repo.ListAllMedicineProducts()
+	if err != nil {
+		return nil, err
+	}
+
+	dispensations, err := a.repo.ListMedicineProductsDispensations(now.AddDate(0, 0, -dispensationAverageDays))
+	if err != nil {
+		return nil, err
+	}
+
+	dispensedAmounts := make(map[uint]int, len(dispensations))
+	for _, dispensation := range dispensations {
+		dispensedAmounts[dispensation.ProductId] = dispensation.Dispensed
+	}
+
+	projections := make([]models.MedicineProductProjection, 0, len(products))
+	for _, product := range products {
+		projection := models.MedicineProductProjection{
+			Product:           product,
+			Amount:            product.UsableAmount(now),
+			DailyDispensation: float64(dispensedAmounts[product.Id]) / dispensationAverageDays,
+		}
+		if projection.DailyDispensation > 0 {
+			daysLeft := float64(projection.Amount) / projection.DailyDispensation
+			projection.RunOutAt = now.Add(time.Duration(daysLeft * float64(24*time.Hour)))
+		}
+		projections = append(projections, projection)
+	}
+
+	slices.SortStableFunc(projections, func(a, b models.MedicineProductProjection) int {
+		switch {
+		case a.RunOutAt.IsZero() && b.RunOutAt.IsZero():
+			return 0
+		case a.RunOutAt.IsZero():
+			return 1
+		case b.RunOutAt.IsZero():
+			return -1
+		default:
+			return a.RunOutAt.Compare(b.RunOutAt)
+		}
+	})
+
+	return projections, nil
+}
+
+// SendDailyStockSummary raises a projected run out alert for every product that's projected to run out
+// within the next 30 days, and sends them as a single notification.
+func (a *App) SendDailyStockSummary(now time.Time) ([]models.Alert, error) {
+	projections, err := a.ListMedicineProductProjections(now)
+	if err != nil {
+		return nil, err
+	}
+
+	runOutBefore := now.AddDate(0, 0, projectedRunOutDays)
+	alerts := make([]models.Alert, 0)
+	for _, projection := range projections {
+		if projection.RunOutAt.IsZero() || projection.RunOutAt.After(runOutBefore) {
+			continue
+		}
+
+		alerts = append(alerts, models.Alert{
+			Type:              models.AlertTypeProjectedRunOut,
+			ProductId:         projection.Product.Id,
+			StockAmount:       projection.Amount,
+			MinStock:          projection.Product.MinStock,
+			ProjectedRunOutAt: projection.RunOutAt,
+			Message: fmt.Sprintf("%s (%s) is projected to run out on %s, %d packages are left and %.1f are dispensed per day",
+				projection.Product.Name, projection.Product.Manufacturer, projection.RunOutAt.Format(time.DateOnly), projection.Amount, projection.DailyDispensation),
+		})
+	}
+
+	return a.CreateAlerts(alerts)
+}
@@ -0,0 +1,212 @@
+package app
+
+import (
+	"shs/app/models"
+	"slices"
+	"time"
+)
+
+const (
+	// visitBleedMatchWindow is how far an active bleeding visit can be from a logged bleed episode
+	// to be considered the same bleed.
+	visitBleedMatchWindow = 24 * time.Hour
+
+	targetJointBleedsCount   = 3
+	targetJointWindowMonths  = 6
+	targetJointResolveMonths = 12
+)
+
+// bleed is a bleed episode or an active bleeding visit that wasn't logged as an episode,
+// where a visit has an empty site and cause.
+type bleed struct {
+	occurredAt time.Time
+	site       models.BleedSite
+	cause      models.BleedCause
+	fromVisit  bool
+}
+
+// GetPatientBleedAnalytics computes the patient's bleeding rates over each of the given windows that end at `to`,
+// the patient's target joints until `to`, and the factor consumption over the longest window.
+func (a *App) GetPatientBleedAnalytics(patientId uint, to time.Time, windowsMonths []int) (models.PatientBleedAnalytics, error) {
+	bleeds, err := a.listPatientBleeds(patientId, to)
+	if err != nil {
+		return models.PatientBleedAnalytics{}, err
+	}
+
+	jointsEvaluations, err := a.repo.ListJointEvaluationsForPatient(patientId)
+	if err != nil {
+		return models.PatientBleedAnalytics{}, err
+	}
+
+	var latestJointsEvaluation *models.JointsEvaluation
+	for i := range jointsEvaluations {
+		if !jointsEvaluations[i].CreatedAt.Before(to) {
+			continue
+		}
+		if latestJointsEvaluation == nil || jointsEvaluations[i].CreatedAt.After(latestJointsEvaluation.CreatedAt) {
+			latestJointsEvaluation = &jointsEvaluations[i]
+		}
+	}
+
+	windows := make([]models.BleedRates, 0, len(windowsMonths))
+	longestFrom := to
+	for _, months := range windowsMonths {
+		from := to.AddDate(0, -months, 0)
+		if from.Before(longestFrom) {
+			longestFrom = from
+		}
+		windows = append(windows, computeBleedRates(bleeds, from, to))
+	}
+
+	consumption, _, err := a.GetPatientFactorConsumption(patientId, longestFrom, to)
+	if err != nil {
+		return models.PatientBleedAnalytics{}, err
+	}
+
+	return models.PatientBleedAnalytics{
+		Windows:                windows,
+		TargetJoints:           detectTargetJoints(bleeds, to),
+		LatestJointsEvaluation: latestJointsEvaluation,
+		Consumption:            consumption,
+	}, nil
+}
+
+// listPatientBleeds lists the patient's bleeds before `to` ordered by occurrence,
+// active bleeding visits are only included when no episode was logged around them.
+func (a *App) listPatientBleeds(patientId uint, to time.Time) ([]bleed, error) {
+	episodes, err := a.repo.ListPatientBleedEpisodes(patientId, time.Time{}, to)
+	if err != nil {
+		return nil, err
+	}
+
+	visits, err := a.repo.ListPatientVisits(patientId)
+	if err != nil {
+		return nil, err
+	}
+
+	bleeds := make([]bleed, 0, len(episodes))
+	for _, episode := range episodes {
+		bleeds = append(bleeds, bleed{
+			occurredAt: episode.OccurredAt,
+			site:       episode.Site,
+			cause:      episode.Cause,
+		})
+	}
+
+	for _, visit := range visits {
+		if visit.Reason != models.VisitReasonActiveBleeding || !visit.CreatedAt.Before(to) {
+			continue
+		}
+
+		logged := slices.ContainsFunc(episodes, func(episode models.BleedEpisode) bool {
+			diff := visit.CreatedAt.Sub(episode.OccurredAt)
+			return diff >= -visitBleedMatchWindow && diff <= visitBleedMatchWindow
+		})
+		if logged {
+			continue
+		}
+
+		bleeds = append(bleeds, bleed{
+			occurredAt: visit.CreatedAt,
+			fromVisit:  true,
+		})
+	}
+
+	slices.SortFunc(bleeds, func(a, b bleed) int {
+		return a.occurredAt.Compare(b.occurredAt)
+	})
+
+	return bleeds, nil
+}
+
+func computeBleedRates(bleeds []bleed, from, to time.Time) models.BleedRates {
+	rates := models.BleedRates{From: from, To: to}
+	for _, b := range bleeds {
+		if b.occurredAt.Before(from) || !b.occurredAt.Before(to) {
+			continue
+		}
+
+		rates.BleedsCount++
+		if b.fromVisit {
+			rates.VisitBleedsCount++
+		}
+		if b.site.IsJoint() && !b.fromVisit {
+			rates.JointBleedsCount++
+		}
+		if b.cause == models.BleedCauseSpontaneous {
+			rates.SpontaneousBleedsCount++
+		}
+	}
+
+	spanYears := to.Sub(from).Hours() / 24 / daysPerYear
+	if spanYears > 0 {
+		rates.ABR = float64(rates.BleedsCount) / spanYears
+		rates.AJBR = float64(rates.JointBleedsCount) / spanYears
+		rates.ASBR = float64(rates.SpontaneousBleedsCount) / spanYears
+	}
+
+	return rates
+}
+
+// detectTargetJoints finds the joints that had 3 or more bleeds within 6 months,
+// a target joint stays active for 12 months after it was detected, and then while it has 3 or more bleeds in the 12 months before `to`.
+func detectTargetJoints(bleeds []bleed, to time.Time) []models.TargetJoint {
+	jointBleeds := make(map[models.BleedSite][]time.Time)
+	for _, b := range bleeds {
+		if b.fromVisit || !b.site.IsJoint() {
+			continue
+		}
+		jointBleeds[b.site] = append(jointBleeds[b.site], b.occurredAt)
+	}
+
+	targetJoints := make([]models.TargetJoint, 0)
+	for _, site := range models.BleedSites() {
+		times := jointBleeds[site]
+		if len(times) < targetJointBleedsCount {
+			continue
+		}
+
+		var targetJoint *models.TargetJoint
+		windowStart := 0
+		for i, occurredAt := range times {
+			for times[windowStart].Before(occurredAt.AddDate(0, -targetJointWindowMonths, 0)) {
+				windowStart++
+			}
+			if i-windowStart+1 >= targetJointBleedsCount {
+				windowEnd := times[windowStart].AddDate(0, targetJointWindowMonths, 0)
+				bleedsCount := 0
+				for _, t := range times[windowStart:] {
+					if t.After(windowEnd) {
+						break
+					}
+					bleedsCount++
+				}
+
+				targetJoint = &models.TargetJoint{
+					Site:        site,
+					BleedsCount: bleedsCount,
+					DetectedAt:  occurredAt,
+				}
+				break
+			}
+		}
+		if targetJoint == nil {
+			continue
+		}
+
+		resolveFrom := to.AddDate(0, -targetJointResolveMonths, 0)
+		recentBleeds := 0
+		for _, occurredAt := range times {
+			if !occurredAt.Before(resolveFrom) {
+				recentBleeds++
+			}
+		}
+
+		targetJoint.LastBleedAt = times[len(times)-1]
+		targetJoint.Active = recentBleeds >= targetJointBleedsCount ||
+			targetJoint.DetectedAt.After(resolveFrom)
+		targetJoints = append(targetJoints, *targetJoint)
+	}
+
+	return targetJoints
+}
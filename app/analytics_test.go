@@ -0,0 +1,83 @@
+package app
+
+import (
+	"shs/app/models"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDetectTargetJoints(t *testing.T) {
+	day := func(month time.Month, d int) time.Time {
+		return time.Date(2024, month, d, 0, 0, 0, 0, time.UTC)
+	}
+	knee := func(at time.Time) bleed {
+		return bleed{occurredAt: at, site: models.BleedSiteRightKnee}
+	}
+
+	tests := []struct {
+		name   string
+		bleeds []bleed
+		to     time.Time
+		want   []models.TargetJoint
+	}{
+		{
+			name:   "less than 3 bleeds",
+			bleeds: []bleed{knee(day(time.January, 1)), knee(day(time.February, 1))},
+			to:     day(time.March, 1),
+			want:   []models.TargetJoint{},
+		},
+		{
+			name:   "bleeds that are more than 6 months apart",
+			bleeds: []bleed{knee(day(time.January, 1)), knee(day(time.May, 1)), knee(day(time.September, 1))},
+			to:     day(time.October, 1),
+			want:   []models.TargetJoint{},
+		},
+		{
+			name: "visit bleeds and non joint sites are ignored",
+			bleeds: []bleed{
+				knee(day(time.January, 1)),
+				{occurredAt: day(time.January, 2), site: models.BleedSiteRightKnee, fromVisit: true},
+				{occurredAt: day(time.January, 3), site: models.BleedSiteMuscle},
+				knee(day(time.January, 4)),
+			},
+			to:   day(time.March, 1),
+			want: []models.TargetJoint{},
+		},
+		{
+			name: "only the bleeds within the detecting 6 months are counted",
+			bleeds: []bleed{
+				knee(day(time.January, 1)), knee(day(time.February, 1)), knee(day(time.March, 1)),
+				knee(day(time.June, 1)),
+				knee(day(time.November, 1)),
+			},
+			to: day(time.December, 1),
+			want: []models.TargetJoint{{
+				Site:        models.BleedSiteRightKnee,
+				BleedsCount: 4,
+				DetectedAt:  day(time.March, 1),
+				LastBleedAt: day(time.November, 1),
+				Active:      true,
+			}},
+		},
+		{
+			name:   "a joint without bleeds for 12 months after its detection is resolved",
+			bleeds: []bleed{knee(day(time.January, 1)), knee(day(time.January, 2)), knee(day(time.January, 3))},
+			to:     time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC),
+			want: []models.TargetJoint{{
+				Site:        models.BleedSiteRightKnee,
+				BleedsCount: 3,
+				DetectedAt:  day(time.January, 3),
+				LastBleedAt: day(time.January, 3),
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectTargetJoints(tt.bleeds, tt.to); !slices.Equal(got, tt.want) {
+				t.Errorf("detectTargetJoints() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
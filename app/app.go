@@ -1,13 +1,53 @@
 package app
 
 type App struct {
-	repo  Repository
-	cache Cache
+	repo     Repository
+	cache    Cache
+	notifier Notifier
+	// afterCommit holds the callbacks to run once the App's transaction is committed,
+	// it's nil for Apps that aren't in a transaction.
+	afterCommit *[]func()
 }
 
-func New(repo Repository, cache Cache) *App {
+func New(repo Repository, cache Cache, notifier Notifier) *App {
 	return &App{
-		repo:  repo,
-		cache: cache,
+		repo:     repo,
+		cache:    cache,
+		notifier: notifier,
 	}
 }
+
+// WithTx runs fn using an App whose repository calls are done in a single transaction,
+// so multi-step writes are either committed or rolled back as a whole.
+// A nested transaction's callbacks are handed to its parent only when it succeeds,
+// so the callbacks of a rolled back nested transaction never run.
+func (a *App) WithTx(fn func(txApp *App) error) error {
+	afterCommit := make([]func(), 0)
+	err := a.repo.WithTx(func(tx Repository) error {
+		return fn(&App{repo: tx, cache: a.cache, notifier: a.notifier, afterCommit: &afterCommit})
+	})
+	if err != nil {
+		return err
+	}
+
+	if a.afterCommit != nil {
+		*a.afterCommit = append(*a.afterCommit, afterCommit...)
+		return nil
+	}
+
+	for _, callback := range afterCommit {
+		callback()
+	}
+
+	return nil
+}
+
+// runAfterCommit runs callback once the App's transaction is committed, or right away when it's not in one.
+func (a *App) runAfterCommit(callback func()) {
+	if a.afterCommit == nil {
+		callback()
+		return
+	}
+
+	*a.afterCommit = append(*a.afterCommit, callback)
+}
@@ -0,0 +1,93 @@
+package app
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// txRepository runs the transactions right away, where a transaction that fails is considered rolled back.
+type txRepository struct {
+	Repository
+}
+
+func (r txRepository) WithTx(fn func(tx Repository) error) error {
+	return fn(r)
+}
+
+func TestWithTxAfterCommit(t *testing.T) {
+	errRollback := errors.New("rollback")
+
+	tests := []struct {
+		name string
+		// tx runs in the outer transaction, and records the callbacks that ran when it's done.
+		tx      func(txApp *App, ran *[]string) error
+		wantRan []string
+	}{
+		{
+			name: "callbacks run after the commit",
+			tx: func(txApp *App, ran *[]string) error {
+				txApp.runAfterCommit(func() { *ran = append(*ran, "outer") })
+				if len(*ran) > 0 {
+					return errors.New("the callback ran before the commit")
+				}
+				return nil
+			},
+			wantRan: []string{"outer"},
+		},
+		{
+			name: "callbacks of a rolled back transaction don't run",
+			tx: func(txApp *App, ran *[]string) error {
+				txApp.runAfterCommit(func() { *ran = append(*ran, "outer") })
+				return errRollback
+			},
+		},
+		{
+			name: "callbacks of a committed nested transaction run after the outer commit",
+			tx: func(txApp *App, ran *[]string) error {
+				err := txApp.WithTx(func(nestedApp *App) error {
+					nestedApp.runAfterCommit(func() { *ran = append(*ran, "nested") })
+					return nil
+				})
+				if err != nil || len(*ran) > 0 {
+					return errors.New("the nested callback ran before the outer commit")
+				}
+				txApp.runAfterCommit(func() { *ran = append(*ran, "outer") })
+				return nil
+			},
+			wantRan: []string{"nested", "outer"},
+		},
+		{
+			name: "callbacks of a rolled back nested transaction don't run",
+			tx: func(txApp *App, ran *[]string) error {
+				err := txApp.WithTx(func(nestedApp *App) error {
+					nestedApp.runAfterCommit(func() { *ran = append(*ran, "nested") })
+					return errRollback
+				})
+				if !errors.Is(err, errRollback) {
+					return err
+				}
+				txApp.runAfterCommit(func() { *ran = append(*ran, "outer") })
+				return nil
+			},
+			wantRan: []string{"outer"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ran []string
+			a := New(txRepository{}, nil, nil)
+
+			err := a.WithTx(func(txApp *App) error {
+				return tt.tx(txApp, &ran)
+			})
+			if err != nil && !errors.Is(err, errRollback) {
+				t.Fatalf("WithTx() error = %v", err)
+			}
+			if !slices.Equal(ran, tt.wantRan) {
+				t.Errorf("WithTx() ran %v, want %v", ran, tt.wantRan)
+			}
+		})
+	}
+}
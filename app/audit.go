@@ -0,0 +1,11 @@
+package app
+
+import "shs/app/models"
+
+func (a *App) CreateAuditLog(auditLog models.AuditLog) (models.AuditLog, error) {
+	return a.repo.CreateAuditLog(auditLog)
+}
+
+func (a *App) ListAuditLogs(filter models.AuditLogFilter) ([]models.AuditLog, int64, error) {
+	return a.repo.ListAuditLogs(filter)
+}
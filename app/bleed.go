@@ -0,0 +1,43 @@
+package app
+
+import (
+	"shs/app/models"
+	"time"
+)
+
+// CreateBleedEpisode creates the episode with its infusions,
+// and marks the infused prescribed medicines that weren't marked as used yet.
+func (a *App) CreateBleedEpisode(episode models.BleedEpisode, infusedMedicines []models.PrescribedMedicine) (models.BleedEpisode, error) {
+	err := a.repo.WithTx(func(tx Repository) error {
+		var err error
+		episode, err = tx.CreateBleedEpisode(episode)
+		if err != nil {
+			return err
+		}
+
+		for _, pm := range infusedMedicines {
+			if !pm.UsedAt.IsZero() {
+				continue
+			}
+			err = tx.UseMedicineForVisit(pm.Id, pm.VisitId)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return models.BleedEpisode{}, err
+	}
+
+	return episode, nil
+}
+
+func (a *App) ListPatientBleedEpisodes(patientId uint, from, to time.Time) ([]models.BleedEpisode, error) {
+	return a.repo.ListPatientBleedEpisodes(patientId, from, to)
+}
+
+func (a *App) ListPrescribedMedicinesByIds(ids []uint) ([]models.PrescribedMedicine, error) {
+	return a.repo.ListPrescribedMedicinesByIds(ids)
+}
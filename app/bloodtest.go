@@ -1,48 +1,254 @@
 package app
 
 import (
+	"fmt"
 	"shs/app/models"
 	"time"
 )
 
+// CreateBloodTest creates the blood test as its first version, with the first version of each of its fields.
 func (a *App) CreateBloodTest(bt models.BloodTest) (models.BloodTest, error) {
+	now := time.Now().UTC()
+	bt.Version = 1
+	for i := range bt.Fields {
+		bt.Fields[i].Id = 0
+		bt.Fields[i].Version = bt.Version
+		bt.Fields[i].Versions = []models.BloodTestFieldVersion{bt.Fields[i].Snapshot(now)}
+	}
+
 	return a.repo.CreateBloodTest(bt)
 }
 
+// UpdateBloodTest applies the blood test's name and fields as its next version,
+// where fields without an id are added, changed fields get a definition that's in force from now on,
+// and fields that aren't given are left as they are.
+// The version isn't increased when nothing changed.
+func (a *App) UpdateBloodTest(id uint, bt models.BloodTest) (models.BloodTest, error) {
+	var updated models.BloodTest
+	err := a.WithTx(func(txApp *App) error {
+		current, err := txApp.repo.GetBloodTest(id)
+		if err != nil {
+			return err
+		}
+
+		currentFields := make(map[uint]models.BloodTestField, len(current.Fields))
+		for _, field := range current.Fields {
+			currentFields[field.Id] = field
+		}
+
+		now := time.Now().UTC()
+		version := current.Version + 1
+		changed := bt.Name != current.Name
+		for _, field := range bt.Fields {
+			field.BloodTestId = id
+			field.Version = version
+			field.Versions = nil
+
+			if field.Id == 0 {
+				field, err = txApp.repo.CreateBloodTestField(field)
+				if err != nil {
+					return err
+				}
+			} else {
+				currentField, ok := currentFields[field.Id]
+				if !ok {
+					return &ErrNotFound{
+						ResourceName: "blood_test_field",
+					}
+				}
+				if currentField.SameDefinition(field) {
+					continue
+				}
+
+				err = txApp.repo.UpdateBloodTestField(field)
+				if err != nil {
+					return err
+				}
+			}
+
+			err = txApp.repo.CreateBloodTestFieldVersion(field.Snapshot(now))
+			if err != nil {
+				return err
+			}
+			changed = true
+		}
+
+		if !changed {
+			updated = current
+			return nil
+		}
+
+		updated, err = txApp.repo.UpdateBloodTest(id, models.BloodTest{
+			Name:    bt.Name,
+			Version: version,
+		})
+		return err
+	})
+	if err != nil {
+		return models.BloodTest{}, err
+	}
+
+	return updated, nil
+}
+
 func (a *App) GetBloodTest(id uint) (models.BloodTest, error) {
 	return a.repo.GetBloodTest(id)
 }
 
+// DeleteBloodTest deletes the blood test with its fields' versions when nothing references it,
+// otherwise it fails with ErrInUse, and the blood test can be archived instead.
 func (a *App) DeleteBloodTest(id uint) error {
-	return a.repo.DeleteBloodTest(id)
+	return a.WithTx(func(txApp *App) error {
+		usage, err := txApp.repo.GetBloodTestUsage(id)
+		if err != nil {
+			return err
+		}
+		if usage.InUse() {
+			return &ErrInUse{
+				ResourceName: "blood_test",
+				Usage:        usage,
+			}
+		}
+
+		return txApp.repo.DeleteBloodTest(id)
+	})
+}
+
+// SetBloodTestArchived archives the blood test, so that it can't be used in new records while the existing ones still resolve it,
+// or unarchives it.
+func (a *App) SetBloodTestArchived(id uint, archived bool) error {
+	var archivedAt *time.Time
+	if archived {
+		now := time.Now().UTC()
+		archivedAt = &now
+	}
+
+	return a.repo.SetBloodTestArchivedAt(id, archivedAt)
 }
 
 func (a *App) ListAllBloodTests() ([]models.BloodTest, error) {
 	return a.repo.ListAllBloodTests()
 }
 
+// CreateBloodTestResult creates the result, and when it isn't pending, reclassifies the patient
+// and alerts about the infection changes that it suggests.
 func (a *App) CreateBloodTestResult(btr models.BloodTestResult) (models.BloodTestResult, error) {
-	return a.repo.CreateBloodTestResult(btr)
+	err := a.WithTx(func(txApp *App) error {
+		var err error
+		btr, err = txApp.repo.CreateBloodTestResult(btr)
+		if err != nil {
+			return err
+		}
+
+		if btr.Pending {
+			return nil
+		}
+
+		err = txApp.reclassifyPatient(btr.PatientId)
+		if err != nil {
+			return err
+		}
+
+		return txApp.alertInfectionSuggestions(btr.PatientId, btr.Id)
+	})
+	if err != nil {
+		return models.BloodTestResult{}, err
+	}
+
+	return btr, nil
 }
 
 func (a *App) ListPatientBloodTestResults(patientId uint) ([]models.BloodTestResult, error) {
 	return a.repo.ListPatientBloodTestResults(patientId)
 }
 
-func (a *App) UpdatePatientPendingBloodTestResultFields(btrId uint, fields []models.BloodTestFilledField) error {
-	err := a.repo.SetBloodTestResultPending(btrId, false)
+// UpdatePatientPendingBloodTestResultFields completes the pending result with the given fields,
+// reclassifies the patient, and alerts about the infection changes that the result suggests.
+func (a *App) UpdatePatientPendingBloodTestResultFields(patientId, btrId uint, fields []models.BloodTestFilledField) error {
+	return a.WithTx(func(txApp *App) error {
+		err := txApp.repo.SetBloodTestResultPending(btrId, false)
+		if err != nil {
+			return err
+		}
+
+		err = txApp.repo.UpdateBloodTestResultCreatedAt(btrId, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+
+		for i := range fields {
+			fields[i].BloodTestResultId = btrId
+		}
+
+		err = txApp.repo.CreateBloodTestResultFilledFields(fields)
+		if err != nil {
+			return err
+		}
+
+		err = txApp.reclassifyPatient(patientId)
+		if err != nil {
+			return err
+		}
+
+		return txApp.alertInfectionSuggestions(patientId, btrId)
+	})
+}
+
+// reclassifyPatient reclassifies the patient's hemophilia and inhibitor from all of their blood test results,
+// and raises an alert when the patient's inhibitor turns positive.
+func (a *App) reclassifyPatient(patientId uint) error {
+	patient, err := a.repo.GetPatientById(patientId)
+	if err != nil {
+		return err
+	}
+
+	results, err := a.repo.ListPatientBloodTestResults(patientId)
+	if err != nil {
+		return err
+	}
+
+	bloodTests, err := a.repo.ListAllBloodTests()
 	if err != nil {
 		return err
 	}
 
-	err = a.repo.UpdateBloodTestResultCreatedAt(btrId, time.Now().UTC())
+	err = a.repo.UpdatePatientHemophilia(patientId, models.ClassifyHemophilia(results, bloodTests))
 	if err != nil {
 		return err
 	}
 
-	for i := range fields {
-		fields[i].BloodTestResultId = btrId
+	inhibitor := models.ClassifyInhibitor(models.ListInhibitorTiters(results, bloodTests))
+	err = a.repo.UpdatePatientInhibitor(patientId, inhibitor)
+	if err != nil {
+		return err
+	}
+
+	if patient.InhibitorPositive || !inhibitor.Positive {
+		return nil
+	}
+
+	_, err = a.CreateAlerts([]models.Alert{{
+		Type:      models.AlertTypeInhibitorDetected,
+		PatientId: patientId,
+		Message: fmt.Sprintf("Patient %s (%s %s) has a positive inhibitor titer of %g BU, as a %s responder",
+			patient.PublicId, patient.FirstName, patient.LastName, inhibitor.LatestTiter, inhibitor.Responder),
+	}})
+
+	return err
+}
+
+func (a *App) GetBloodTestField(id uint) (models.BloodTestField, error) {
+	return a.repo.GetBloodTestField(id)
+}
+
+// GetPatientBloodTestFieldSeries returns the field's values in the patient's results in [from, to), oldest first,
+// downsampled to at most maxPoints points when maxPoints is positive, and the number of values before downsampling.
+// Pending results are left out until they're completed, and then they're placed at their completion time.
+func (a *App) GetPatientBloodTestFieldSeries(patientId, fieldId uint, from, to time.Time, maxPoints int) ([]models.BloodTestFieldPoint, int, error) {
+	points, err := a.repo.ListPatientBloodTestFieldPoints(patientId, fieldId, from, to)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	return a.repo.CreateBloodTestResultFilledFields(fields)
+	return models.DownsampleBloodTestFieldPoints(points, maxPoints), len(points), nil
 }
@@ -0,0 +1,76 @@
+package app
+
+import (
+	"shs/app/models"
+	"time"
+)
+
+const daysPerYear = 365.25
+
+// GetPatientFactorConsumption computes the patient's factor consumption over [from, to), and over each calendar year in it,
+// using the patient's weight at every visit, or the last weight known before it.
+func (a *App) GetPatientFactorConsumption(patientId uint, from, to time.Time) (models.FactorConsumption, []models.FactorConsumption, error) {
+	prescriptions, err := a.repo.ListPatientPrescriptions(patientId, time.Time{}, to)
+	if err != nil {
+		return models.FactorConsumption{}, nil, err
+	}
+
+	total := models.FactorConsumption{From: from, To: to}
+	years := make([]models.FactorConsumption, 0)
+	for year := from.Year(); year <= to.Year(); year++ {
+		yearFrom := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+		yearTo := yearFrom.AddDate(1, 0, 0)
+		if yearFrom.Before(from) {
+			yearFrom = from
+		}
+		if yearTo.After(to) {
+			yearTo = to
+		}
+		if !yearFrom.Before(yearTo) {
+			continue
+		}
+		years = append(years, models.FactorConsumption{From: yearFrom, To: yearTo})
+	}
+
+	lastKnownWeight := 0.0
+	for _, prescription := range prescriptions {
+		if prescription.Visit.PatientWeight > 0 {
+			lastKnownWeight = prescription.Visit.PatientWeight
+		}
+		if prescription.CreatedAt.Before(from) || prescription.TotalIU == 0 {
+			continue
+		}
+
+		addPrescription(&total, prescription, lastKnownWeight)
+		for i := range years {
+			if !prescription.CreatedAt.Before(years[i].From) && prescription.CreatedAt.Before(years[i].To) {
+				addPrescription(&years[i], prescription, lastKnownWeight)
+				break
+			}
+		}
+	}
+
+	setPerYear(&total)
+	for i := range years {
+		setPerYear(&years[i])
+	}
+
+	return total, years, nil
+}
+
+func addPrescription(consumption *models.FactorConsumption, prescription models.Prescription, weight float64) {
+	consumption.PrescriptionsCount++
+	consumption.TotalIU += prescription.TotalIU
+	if weight > 0 {
+		consumption.IUPerKg += prescription.TotalIU / weight
+	} else {
+		consumption.UnweightedIU += prescription.TotalIU
+	}
+}
+
+func setPerYear(consumption *models.FactorConsumption) {
+	spanYears := consumption.To.Sub(consumption.From).Hours() / 24 / daysPerYear
+	if spanYears > 0 {
+		consumption.IUPerKgPerYear = consumption.IUPerKg / spanYears
+	}
+}
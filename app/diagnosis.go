@@ -1,13 +1,45 @@
 package app
 
-import "shs/app/models"
+import (
+	"shs/app/models"
+	"time"
+)
 
 func (a *App) CreateDiagnosis(d models.Diagnosis) (models.Diagnosis, error) {
 	return a.repo.CreateDiagnosis(d)
 }
 
+// DeleteDiagnosis deletes the diagnosis unless it was given to patients.
 func (a *App) DeleteDiagnosis(id uint) error {
-	return a.repo.DeleteDiagnisis(id)
+	return a.WithTx(func(txApp *App) error {
+		usage, err := txApp.repo.GetDiagnosisUsage(id)
+		if err != nil {
+			return err
+		}
+		if usage.InUse() {
+			return &ErrInUse{
+				ResourceName: "diagnosis",
+				Usage:        usage,
+			}
+		}
+
+		return txApp.repo.DeleteDiagnisis(id)
+	})
+}
+
+// SetDiagnosisArchived archives or unarchives the diagnosis.
+func (a *App) SetDiagnosisArchived(id uint, archived bool) error {
+	var archivedAt *time.Time
+	if archived {
+		now := time.Now().UTC()
+		archivedAt = &now
+	}
+
+	return a.repo.SetDiagnosisArchivedAt(id, archivedAt)
+}
+
+func (a *App) GetDiagnosis(id uint) (models.Diagnosis, error) {
+	return a.repo.GetDiagnosis(id)
 }
 
 func (a *App) ListAllDiagnoses() ([]models.Diagnosis, error) {
@@ -21,3 +53,32 @@ func (a *App) CreateDiagnosisResult(dr models.DiagnosisResult) (models.Diagnosis
 func (a *App) ListPatientDiagnosisResults(patientId uint) ([]models.DiagnosisResult, error) {
 	return a.repo.ListPatientDiagnosisResults(patientId)
 }
+
+func (a *App) GetDiagnosisResult(id uint) (models.DiagnosisResult, error) {
+	return a.repo.GetDiagnosisResult(id)
+}
+
+// ReplaceDiagnosisResult creates the updated result and marks the result that it replaces as replaced by it,
+// the replaced result is kept for the patient's history.
+func (a *App) ReplaceDiagnosisResult(id uint, dr models.DiagnosisResult) (models.DiagnosisResult, error) {
+	err := a.WithTx(func(txApp *App) error {
+		var err error
+		dr.Id = 0
+		dr.ReplacesId = id
+		dr, err = txApp.repo.CreateDiagnosisResult(dr)
+		if err != nil {
+			return err
+		}
+
+		return txApp.repo.SetDiagnosisResultReplacedBy(id, dr.Id)
+	})
+	if err != nil {
+		return models.DiagnosisResult{}, err
+	}
+
+	return dr, nil
+}
+
+func (a *App) RevokeDiagnosisResult(id, revokedById uint, reason string) error {
+	return a.repo.RevokeDiagnosisResult(id, revokedById, reason)
+}
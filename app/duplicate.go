@@ -0,0 +1,92 @@
+package app
+
+import (
+	"shs/app/models"
+	"time"
+)
+
+// ListDuplicatePatients finds the pairs of patients that are likely the same person, see models.FindDuplicates,
+// where only the patients that share their phone number or their names with another patient are compared,
+// so that the whole patients table isn't loaded.
+func (a *App) ListDuplicatePatients(minScore float64) ([]models.PatientDuplicate, error) {
+	patients, err := a.repo.ListDuplicateCandidatePatients()
+	if err != nil {
+		return nil, err
+	}
+
+	return models.FindDuplicates(patients, minScore), nil
+}
+
+// ListPatientDuplicates finds the patients that are likely the same person as the patient, see models.FindPatientDuplicates.
+func (a *App) ListPatientDuplicates(patient models.Patient, minScore float64) ([]models.PatientDuplicate, error) {
+	patients, err := a.repo.ListAllPatients()
+	if err != nil {
+		return nil, err
+	}
+
+	return models.FindPatientDuplicates(patient, patients, minScore), nil
+}
+
+// MergePatients moves the merged patient's records to the surviving patient, records the merged patient's public id
+// as an alias of the surviving patient, disables the merged patient's account, deletes the merged patient,
+// and then reclassifies the surviving patient with the moved results, all in a single transaction.
+// It returns the disabled account's id, which is zero when the merged patient doesn't have an account.
+func (a *App) MergePatients(survivorId, mergedId, mergerAccountId uint) (models.PatientAlias, uint, error) {
+	var alias models.PatientAlias
+	var disabledAccountId uint
+
+	err := a.WithTx(func(txApp *App) error {
+		merged, err := txApp.repo.GetPatientById(mergedId)
+		if err != nil {
+			return err
+		}
+
+		err = txApp.repo.MovePatientRecords(mergedId, survivorId)
+		if err != nil {
+			return err
+		}
+
+		alias, err = txApp.repo.CreatePatientAlias(models.PatientAlias{
+			PatientId:       survivorId,
+			AliasPublicId:   merged.PublicId,
+			MergedPatientId: merged.Id,
+			NationalId:      merged.NationalId,
+			FirstName:       merged.FirstName,
+			LastName:        merged.LastName,
+			FatherName:      merged.FatherName,
+			MotherName:      merged.MotherName,
+			PhoneNumber:     merged.PhoneNumber,
+			AccountId:       mergerAccountId,
+		})
+		if err != nil {
+			return err
+		}
+
+		mergedAccount, err := txApp.repo.GetAccountByUsername(merged.PublicId)
+		switch err.(type) {
+		case nil:
+			now := time.Now().UTC()
+			err = txApp.repo.SetAccountDisabledAt(mergedAccount.Id, &now)
+			if err != nil {
+				return err
+			}
+			disabledAccountId = mergedAccount.Id
+		case *ErrNotFound:
+			// imported patients don't have accounts
+		default:
+			return err
+		}
+
+		err = txApp.repo.DeletePatient(mergedId)
+		if err != nil {
+			return err
+		}
+
+		return txApp.reclassifyPatient(survivorId)
+	})
+	if err != nil {
+		return models.PatientAlias{}, 0, err
+	}
+
+	return alias, disabledAccountId, nil
+}
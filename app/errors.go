@@ -3,6 +3,7 @@ package app
 import (
 	"fmt"
 	"net/http"
+	"shs/app/models"
 	"strings"
 )
 
@@ -56,3 +57,98 @@ func (e ErrExists) ExtraData() map[string]any {
 func (e ErrExists) ExposeToClients() bool {
 	return true
 }
+
+type ErrInsufficientMedicine struct {
+	MedicineName    string
+	ExceedingAmount int
+	LeftPackages    int
+}
+
+func (e ErrInsufficientMedicine) Error() string {
+	return "insufficient-medicine-amount"
+}
+
+func (e ErrInsufficientMedicine) ClientStatusCode() int {
+	return http.StatusForbidden
+}
+
+func (e ErrInsufficientMedicine) ExtraData() map[string]any {
+	return map[string]any{
+		"medicine_name":    e.MedicineName,
+		"exceeding_amount": e.ExceedingAmount,
+		"left_packages":    e.LeftPackages,
+	}
+}
+
+func (e ErrInsufficientMedicine) ExposeToClients() bool {
+	return true
+}
+
+// ErrInUse is returned when deleting a resource would orphan the records that reference it,
+// the resource can be archived instead.
+type ErrInUse struct {
+	ResourceName string
+	Usage        models.Usage
+}
+
+func (e ErrInUse) Error() string {
+	return fmt.Sprintf("%s-in-use", strings.ToLower(e.ResourceName))
+}
+
+func (e ErrInUse) ClientStatusCode() int {
+	return http.StatusConflict
+}
+
+func (e ErrInUse) ExtraData() map[string]any {
+	return map[string]any{
+		"usage": e.Usage,
+	}
+}
+
+func (e ErrInUse) ExposeToClients() bool {
+	return true
+}
+
+// ErrArchived is returned when an archived resource is used in a new record.
+type ErrArchived struct {
+	ResourceName string
+}
+
+func (e ErrArchived) Error() string {
+	return fmt.Sprintf("%s-archived", strings.ToLower(e.ResourceName))
+}
+
+func (e ErrArchived) ClientStatusCode() int {
+	return http.StatusConflict
+}
+
+func (e ErrArchived) ExtraData() map[string]any {
+	return nil
+}
+
+func (e ErrArchived) ExposeToClients() bool {
+	return true
+}
+
+// ErrInactiveDiagnosisResult is returned when updating or revoking a diagnosis result that was already revoked or replaced.
+type ErrInactiveDiagnosisResult struct {
+	ReplacedById uint
+}
+
+func (e ErrInactiveDiagnosisResult) Error() string {
+	return "inactive-diagnosis-result"
+}
+
+func (e ErrInactiveDiagnosisResult) ClientStatusCode() int {
+	return http.StatusConflict
+}
+
+func (e ErrInactiveDiagnosisResult) ExtraData() map[string]any {
+	return map[string]any{
+		"replaced_by_id": e.ReplacedById,
+	}
+}
+
+func (e ErrInactiveDiagnosisResult) ExposeToClients() bool {
+	return true
+}
@@ -0,0 +1,154 @@
+package app
+
+import "shs/app/models"
+
+// maxPedigreeMembers caps the members of a pedigree, so that wrongly linked families don't load the whole registry.
+const maxPedigreeMembers = 500
+
+// CreatePatientRelationship links the patient to the relative,
+// where the relative is created first when the relationship doesn't have a relative patient or an existing relative.
+func (a *App) CreatePatientRelationship(relationship models.PatientRelationship, relative models.Relative) (models.PatientRelationship, error) {
+	err := a.WithTx(func(txApp *App) error {
+		if relationship.RelativePatientId == 0 && relationship.RelativeId == 0 {
+			newRelative, err := txApp.repo.CreateRelative(relative)
+			if err != nil {
+				return err
+			}
+			relationship.RelativeId = newRelative.Id
+		}
+
+		var err error
+		relationship, err = txApp.repo.CreatePatientRelationship(relationship)
+		return err
+	})
+	if err != nil {
+		return models.PatientRelationship{}, err
+	}
+
+	return relationship, nil
+}
+
+func (a *App) GetPatientRelationship(id uint) (models.PatientRelationship, error) {
+	return a.repo.GetPatientRelationship(id)
+}
+
+// DeletePatientRelationship unlinks the relationship's relative,
+// and deletes the relative when it isn't a patient and isn't linked to any other patient.
+func (a *App) DeletePatientRelationship(id uint) error {
+	return a.WithTx(func(txApp *App) error {
+		relationship, err := txApp.repo.GetPatientRelationship(id)
+		if err != nil {
+			return err
+		}
+
+		err = txApp.repo.DeletePatientRelationship(id)
+		if err != nil {
+			return err
+		}
+
+		if relationship.RelativeId == 0 {
+			return nil
+		}
+
+		remaining, err := txApp.repo.ListRelationshipsOf(nil, []uint{relationship.RelativeId})
+		if err != nil {
+			return err
+		}
+		if len(remaining) > 0 {
+			return nil
+		}
+
+		return txApp.repo.DeleteRelative(relationship.RelativeId)
+	})
+}
+
+func (a *App) GetRelative(id uint) (models.Relative, error) {
+	return a.repo.GetRelative(id)
+}
+
+func (a *App) UpdateRelative(relative models.Relative) (models.Relative, error) {
+	err := a.repo.UpdateRelative(relative)
+	if err != nil {
+		return models.Relative{}, err
+	}
+
+	return a.repo.GetRelative(relative.Id)
+}
+
+// GetPedigree walks the relationships from the patient in both directions, until every reachable member is found
+// or the pedigree reaches maxPedigreeMembers.
+func (a *App) GetPedigree(patientId uint) (models.Pedigree, error) {
+	seenPatients := map[uint]bool{patientId: true}
+	seenRelatives := make(map[uint]bool)
+	seenRelationships := make(map[uint]bool)
+
+	relationships := make([]models.PatientRelationship, 0)
+	patientIds := []uint{patientId}
+	relativeIds := []uint{}
+	for len(patientIds)+len(relativeIds) > 0 && len(seenPatients)+len(seenRelatives) < maxPedigreeMembers {
+		found, err := a.repo.ListRelationshipsOf(patientIds, relativeIds)
+		if err != nil {
+			return models.Pedigree{}, err
+		}
+
+		patientIds, relativeIds = nil, nil
+		for _, relationship := range found {
+			if seenRelationships[relationship.Id] {
+				continue
+			}
+			seenRelationships[relationship.Id] = true
+			relationships = append(relationships, relationship)
+
+			for _, id := range []uint{relationship.PatientId, relationship.RelativePatientId} {
+				if id != 0 && !seenPatients[id] {
+					seenPatients[id] = true
+					patientIds = append(patientIds, id)
+				}
+			}
+			if relationship.RelativeId != 0 && !seenRelatives[relationship.RelativeId] {
+				seenRelatives[relationship.RelativeId] = true
+				relativeIds = append(relativeIds, relationship.RelativeId)
+			}
+		}
+	}
+
+	allPatientIds := make([]uint, 0, len(seenPatients))
+	for id := range seenPatients {
+		allPatientIds = append(allPatientIds, id)
+	}
+	patients, err := a.repo.ListPatientsByIds(allPatientIds)
+	if err != nil {
+		return models.Pedigree{}, err
+	}
+
+	allRelativeIds := make([]uint, 0, len(seenRelatives))
+	for id := range seenRelatives {
+		allRelativeIds = append(allRelativeIds, id)
+	}
+	relatives, err := a.repo.ListRelativesByIds(allRelativeIds)
+	if err != nil {
+		return models.Pedigree{}, err
+	}
+
+	return models.Pedigree{
+		Patients:      patients,
+		Relatives:     relatives,
+		Relationships: relationships,
+	}, nil
+}
+
+// ListPatientRelationshipProposals proposes family links to the patients who share the patient's last name,
+// see models.ProposeRelationships.
+func (a *App) ListPatientRelationshipProposals(patient models.Patient) ([]models.RelationshipProposal, error) {
+	candidates, err := a.repo.ListPatientsWithLastName(patient.LastName)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := a.repo.ListRelationshipsOf([]uint{patient.Id}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return models.ProposeRelationships(patient, candidates, existing), nil
+}
@@ -0,0 +1,42 @@
+package app
+
+import "shs/app/models"
+
+// ListPatientInhibitorTiters lists the patient's inhibitor titers, oldest first.
+func (a *App) ListPatientInhibitorTiters(patientId uint) ([]models.InhibitorTiter, error) {
+	results, err := a.repo.ListPatientBloodTestResults(patientId)
+	if err != nil {
+		return nil, err
+	}
+
+	bloodTests, err := a.repo.ListAllBloodTests()
+	if err != nil {
+		return nil, err
+	}
+
+	return models.ListInhibitorTiters(results, bloodTests), nil
+}
+
+// ListPatientsWithInhibitorTiters lists the patients with positive inhibitors, with each patient's titers, oldest first.
+func (a *App) ListPatientsWithInhibitorTiters() ([]models.Patient, map[uint][]models.InhibitorTiter, error) {
+	patients, err := a.repo.ListPatientsWithInhibitors()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bloodTests, err := a.repo.ListAllBloodTests()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	titers := make(map[uint][]models.InhibitorTiter, len(patients))
+	for _, patient := range patients {
+		results, err := a.repo.ListPatientBloodTestResults(patient.Id)
+		if err != nil {
+			return nil, nil, err
+		}
+		titers[patient.Id] = models.ListInhibitorTiters(results, bloodTests)
+	}
+
+	return patients, titers, nil
+}
@@ -1,11 +1,59 @@
 package app
 
-import "shs/app/models"
+import (
+	"shs/app/models"
+	"slices"
+)
 
 func (a *App) CreateJointsEvaluation(je models.JointsEvaluation) (models.JointsEvaluation, error) {
+	if je.Kind == models.JointsEvaluationKindHJHS {
+		je.SetJointScoresFromItems()
+	}
+
 	return a.repo.CreateJointEvaluation(je)
 }
 
 func (a *App) ListPatientJointsEvaluations(patientId uint) ([]models.JointsEvaluation, error) {
 	return a.repo.ListJointEvaluationsForPatient(patientId)
 }
+
+// GetPatientJointsTrend lists every evaluated joint's scores over the patient's evaluations,
+// and the evaluations themselves for the totals and global gait trend, both oldest first.
+func (a *App) GetPatientJointsTrend(patientId uint) ([]models.JointTrend, []models.JointsEvaluation, error) {
+	jes, err := a.repo.ListJointEvaluationsForPatient(patientId)
+	if err != nil {
+		return nil, nil, err
+	}
+	slices.Reverse(jes)
+
+	trends := make([]models.JointTrend, 0, len(models.EvaluatedJoints()))
+	for _, joint := range models.EvaluatedJoints() {
+		trend := models.JointTrend{
+			Joint:  joint,
+			Points: make([]models.JointTrendPoint, 0, len(jes)),
+		}
+
+		for _, je := range jes {
+			point := models.JointTrendPoint{
+				EvaluationId: je.Id,
+				EvaluatedAt:  je.CreatedAt,
+				Kind:         je.Kind,
+				Score:        je.JointScore(joint),
+			}
+			for _, items := range je.Joints {
+				if items.Joint == joint {
+					point.Items = &items
+					break
+				}
+			}
+			trend.Points = append(trend.Points, point)
+		}
+
+		if len(trend.Points) > 0 {
+			trend.Change = trend.Points[len(trend.Points)-1].Score - trend.Points[0].Score
+		}
+		trends = append(trends, trend)
+	}
+
+	return trends, jes, nil
+}
@@ -1,13 +1,87 @@
 package app
 
-import "shs/app/models"
+import (
+	"shs/app/models"
+	"time"
+)
 
-func (a *App) CreateMedicine(medicine models.Medicine) (models.Medicine, error) {
-	return a.repo.CreateMedicine(medicine)
+// CreateMedicine creates a batch with no packages under its product, the product is either given by its id,
+// or found by its attributes and created if it's new, then records the batch's amount as a receipt, so that the amount matches its stock ledger from the start.
+func (a *App) CreateMedicine(medicine models.Medicine, receiverAccountId uint) (models.Medicine, error) {
+	receivedAmount := medicine.Amount
+	medicine.Amount = 0
+
+	err := a.repo.WithTx(func(tx Repository) error {
+		var product models.MedicineProduct
+		var err error
+		if medicine.ProductId != 0 {
+			product, err = tx.GetMedicineProduct(medicine.ProductId)
+		} else {
+			product, err = tx.FindOrCreateMedicineProduct(medicine.Product)
+		}
+		if err != nil {
+			return err
+		}
+		product.Batches = nil
+		medicine.ProductId = product.Id
+
+		medicine, err = tx.CreateMedicine(medicine)
+		if err != nil {
+			return err
+		}
+		medicine.Product = product
+		if receivedAmount == 0 {
+			return nil
+		}
+
+		_, err = tx.CreateStockMovement(models.StockMovement{
+			MedicineId: medicine.Id,
+			AccountId:  receiverAccountId,
+			Type:       models.StockMovementTypeReceipt,
+			Quantity:   receivedAmount,
+		})
+		if err != nil {
+			return err
+		}
+		medicine.Amount = receivedAmount
+
+		return nil
+	})
+	if err != nil {
+		return models.Medicine{}, err
+	}
+
+	return medicine, nil
 }
 
+// DeleteMedicine deletes the batch unless it was prescribed or has stock movements,
+// which every batch that was received with packages has, so such batches are archived instead.
 func (a *App) DeleteMedicine(id uint) error {
-	return a.repo.DeleteMedicine(id)
+	return a.WithTx(func(txApp *App) error {
+		usage, err := txApp.repo.GetMedicineUsage(id)
+		if err != nil {
+			return err
+		}
+		if usage.InUse() {
+			return &ErrInUse{
+				ResourceName: "medicine",
+				Usage:        usage,
+			}
+		}
+
+		return txApp.repo.DeleteMedicine(id)
+	})
+}
+
+// SetMedicineArchived archives the batch, so that it's not prescribed anymore, or unarchives it.
+func (a *App) SetMedicineArchived(id uint, archived bool) error {
+	var archivedAt *time.Time
+	if archived {
+		now := time.Now().UTC()
+		archivedAt = &now
+	}
+
+	return a.repo.SetMedicineArchivedAt(id, archivedAt)
 }
 
 func (a *App) ListAllMedicines() ([]models.Medicine, error) {
@@ -18,12 +92,86 @@ func (a *App) ListMedicinesByIds(ids []uint) ([]models.Medicine, error) {
 	return a.repo.ListMedicinesByIds(ids)
 }
 
-func (a *App) UpdateMedicineAmount(id uint, newAmount int) error {
-	return a.repo.UpdateMedicineAmount(id, newAmount)
+func (a *App) ListMedicineBatchesInStock(productId uint) ([]models.Medicine, error) {
+	return a.repo.ListMedicineBatchesInStock(productId)
+}
+
+func (a *App) GetMedicineProduct(id uint) (models.MedicineProduct, error) {
+	return a.repo.GetMedicineProduct(id)
+}
+
+func (a *App) ListAllMedicineProducts() ([]models.MedicineProduct, error) {
+	return a.repo.ListAllMedicineProducts()
+}
+
+func (a *App) ListMedicinesInStockExpiringBefore(before time.Time) ([]models.Medicine, error) {
+	return a.repo.ListMedicinesInStockExpiringBefore(before)
+}
+
+// SetMedicineAmount records the difference between the medicine's current and new amounts as an adjustment,
+// where the medicine is locked while the difference is computed, so that concurrent movements aren't lost or counted twice.
+func (a *App) SetMedicineAmount(id uint, newAmount int, reason string, accountId uint) error {
+	return a.WithTx(func(txApp *App) error {
+		medicine, err := txApp.repo.GetMedicineForUpdate(id)
+		if err != nil {
+			return err
+		}
+		if medicine.Amount == newAmount {
+			return nil
+		}
+
+		_, err = txApp.CreateStockMovement(models.StockMovement{
+			MedicineId: id,
+			AccountId:  accountId,
+			Type:       models.StockMovementTypeAdjustment,
+			Quantity:   newAmount - medicine.Amount,
+			Reason:     reason,
+		})
+
+		return err
+	})
+}
+
+// DispenseMedicine takes a single package of the prescribed medicine out of stock.
+func (a *App) DispenseMedicine(prescribedMedicine models.PrescribedMedicine, accountId uint) error {
+	_, err := a.CreateStockMovement(models.StockMovement{
+		MedicineId:           prescribedMedicine.MedicineId,
+		AccountId:            accountId,
+		Type:                 models.StockMovementTypeDispensation,
+		Quantity:             -1,
+		PrescribedMedicineId: prescribedMedicine.Id,
+	})
+
+	return err
+}
+
+// CreateStockMovement records the movement, and raises a low stock alert when it takes the medicine's product under its minimum stock.
+func (a *App) CreateStockMovement(movement models.StockMovement) (models.StockMovement, error) {
+	movement, err := a.repo.CreateStockMovement(movement)
+	if err != nil {
+		return models.StockMovement{}, err
+	}
+
+	if movement.Quantity < 0 {
+		err = a.checkLowStock(movement.MedicineId, -movement.Quantity)
+		if err != nil {
+			return models.StockMovement{}, err
+		}
+	}
+
+	return movement, nil
+}
+
+func (a *App) UpdateMedicineProductMinStock(id uint, minStock int) error {
+	return a.repo.UpdateMedicineProductMinStock(id, minStock)
+}
+
+func (a *App) ListMedicineStockMovements(filter models.StockMovementFilter) ([]models.StockMovement, int64, error) {
+	return a.repo.ListMedicineStockMovements(filter)
 }
 
-func (a *App) DecrementMedicineAmount(id uint, amount int) error {
-	return a.repo.DecrementMedicineAmount(id, amount)
+func (a *App) GetMedicineLedgerAmount(medicineId uint) (int, error) {
+	return a.repo.GetMedicineLedgerAmount(medicineId)
 }
 
 func (a *App) GetMedicine(id uint) (models.Medicine, error) {
@@ -37,6 +37,7 @@ const (
 	AccountPermissionWriteDiagnoses
 	AccountPermissionReadJoints
 	AccountPermissionWriteJoints
+	AccountPermissionReadAuditLogs
 )
 
 type Account struct {
@@ -46,6 +47,8 @@ type Account struct {
 	Password    string             `gorm:"not null"`
 	Type        AccountType        `gorm:"not null"`
 	Permissions AccountPermissions `gorm:"not null"`
+	// DisabledAt is set for accounts that can't log in anymore, like the accounts of merged patients.
+	DisabledAt *time.Time
 
 	CreatedAt time.Time `gorm:"index;not null"`
 	UpdatedAt time.Time
@@ -55,6 +58,10 @@ func (Account) TableName() string {
 	return "accounts"
 }
 
+func (a Account) IsDisabled() bool {
+	return a.DisabledAt != nil
+}
+
 func (a Account) CheckType(accountTypes ...AccountType) error {
 	if a.Type == AccountTypeSuperAdmin {
 		return nil
@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 type Address struct {
 	Id          uint   `gorm:"primaryKey;autoIncrement"`
@@ -15,3 +18,7 @@ type Address struct {
 func (Address) TableName() string {
 	return "addresses"
 }
+
+func (a Address) String() string {
+	return fmt.Sprintf("%s, %s, %s", a.Governorate, a.Suburb, a.Street)
+}
@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+type AlertType string
+
+const (
+	// AlertTypeLowStock is raised when a product's stock drops below its minimum stock.
+	AlertTypeLowStock AlertType = "low_stock"
+	// AlertTypeProjectedRunOut is raised by the daily stock summary for products that are projected to run out soon.
+	AlertTypeProjectedRunOut AlertType = "projected_run_out"
+	// AlertTypeInhibitorDetected is raised when a patient's inhibitor titer turns positive.
+	AlertTypeInhibitorDetected AlertType = "inhibitor_detected"
+	// AlertTypeInfectionSuggested is raised when a patient's blood test result suggests a change in their infection with a virus.
+	AlertTypeInfectionSuggested AlertType = "infection_suggested"
+)
+
+func AlertTypes() []AlertType {
+	return []AlertType{
+		AlertTypeLowStock,
+		AlertTypeProjectedRunOut,
+		AlertTypeInhibitorDetected,
+		AlertTypeInfectionSuggested,
+	}
+}
+
+type Alert struct {
+	Id                uint      `gorm:"primaryKey;autoIncrement"`
+	Type              AlertType `gorm:"index;not null"`
+	ProductId         uint      `gorm:"index;not null"`
+	PatientId         uint      `gorm:"index"`
+	StockAmount       int       `gorm:"not null"`
+	MinStock          int
+	ProjectedRunOutAt time.Time
+	Message           string `gorm:"not null"`
+
+	CreatedAt time.Time `gorm:"index;not null"`
+}
+
+func (Alert) TableName() string {
+	return "alerts"
+}
+
+// AlertFilter filters alerts, zero valued fields are ignored.
+type AlertFilter struct {
+	Type      AlertType
+	ProductId uint
+	PatientId uint
+	From      time.Time
+	To        time.Time
+	Offset    int
+	Limit     int
+}
+
+// MedicineProductDispensation is the number of packages dispensed of a product.
+type MedicineProductDispensation struct {
+	ProductId uint
+	Dispensed int
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// AuditLog is an append-only record of a write action done by an account.
+type AuditLog struct {
+	Id           uint   `gorm:"primaryKey;autoIncrement"`
+	AccountId    uint   `gorm:"index;not null"`
+	Action       string `gorm:"index;not null"`
+	ResourceType string `gorm:"index;not null"`
+	ResourceId   string `gorm:"index"`
+	StatusCode   int    `gorm:"not null"`
+	Payload      string `gorm:"type:text"`
+
+	CreatedAt time.Time `gorm:"index;not null"`
+}
+
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
+
+// AuditLogFilter filters audit logs, zero valued fields are ignored.
+type AuditLogFilter struct {
+	AccountId    uint
+	Action       string
+	ResourceType string
+	ResourceId   string
+	From         time.Time
+	To           time.Time
+	Offset       int
+	Limit        int
+}
@@ -0,0 +1,134 @@
+package models
+
+import "time"
+
+type BleedSite string
+
+const (
+	BleedSiteRightAnkle BleedSite = "right_ankle"
+	BleedSiteLeftAnkle  BleedSite = "left_ankle"
+	BleedSiteRightKnee  BleedSite = "right_knee"
+	BleedSiteLeftKnee   BleedSite = "left_knee"
+	BleedSiteRightElbow BleedSite = "right_elbow"
+	BleedSiteLeftElbow  BleedSite = "left_elbow"
+	BleedSiteMuscle     BleedSite = "muscle"
+	BleedSiteOther      BleedSite = "other"
+)
+
+func BleedSites() []BleedSite {
+	return []BleedSite{
+		BleedSiteRightAnkle,
+		BleedSiteLeftAnkle,
+		BleedSiteRightKnee,
+		BleedSiteLeftKnee,
+		BleedSiteRightElbow,
+		BleedSiteLeftElbow,
+		BleedSiteMuscle,
+		BleedSiteOther,
+	}
+}
+
+// IsJoint reports whether the site is one of the joints that are evaluated in JointsEvaluation.
+func (s BleedSite) IsJoint() bool {
+	return s != BleedSiteMuscle && s != BleedSiteOther
+}
+
+type BleedCause string
+
+const (
+	BleedCauseSpontaneous BleedCause = "spontaneous"
+	BleedCauseTraumatic   BleedCause = "traumatic"
+)
+
+func BleedCauses() []BleedCause {
+	return []BleedCause{
+		BleedCauseSpontaneous,
+		BleedCauseTraumatic,
+	}
+}
+
+type BleedSeverity string
+
+const (
+	BleedSeverityMild     BleedSeverity = "mild"
+	BleedSeverityModerate BleedSeverity = "moderate"
+	BleedSeveritySevere   BleedSeverity = "severe"
+)
+
+func BleedSeverities() []BleedSeverity {
+	return []BleedSeverity{
+		BleedSeverityMild,
+		BleedSeverityModerate,
+		BleedSeveritySevere,
+	}
+}
+
+type BleedEpisode struct {
+	Id         uint      `gorm:"primaryKey;autoIncrement"`
+	PatientId  uint      `gorm:"index;not null"`
+	AccountId  uint      `gorm:"not null"`
+	OccurredAt time.Time `gorm:"index;not null"`
+	Site       BleedSite `gorm:"not null"`
+	// SiteDetails describes muscle and other sites, e.g. "iliopsoas".
+	SiteDetails string
+	Cause       BleedCause    `gorm:"not null"`
+	Severity    BleedSeverity `gorm:"not null"`
+	Notes       string
+	Infusions   []BleedInfusion `gorm:"foreignKey:BleedEpisodeId"`
+
+	CreatedAt time.Time `gorm:"index;not null"`
+	UpdatedAt time.Time
+}
+
+func (BleedEpisode) TableName() string {
+	return "bleed_episodes"
+}
+
+// BleedInfusion is a prescribed medicine's package that was infused to treat a bleed episode.
+type BleedInfusion struct {
+	Id                   uint `gorm:"primaryKey;autoIncrement"`
+	BleedEpisodeId       uint `gorm:"index;not null"`
+	PrescribedMedicineId uint `gorm:"uniqueIndex;not null"`
+
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+func (BleedInfusion) TableName() string {
+	return "bleed_infusions"
+}
+
+// BleedRates is a patient's annualized bleeding rates over [From, To).
+type BleedRates struct {
+	From                   time.Time
+	To                     time.Time
+	BleedsCount            int
+	JointBleedsCount       int
+	SpontaneousBleedsCount int
+	// VisitBleedsCount is the number of active bleeding visits that don't have a logged bleed episode around them,
+	// they're included in BleedsCount only, since their site and cause aren't known.
+	VisitBleedsCount int
+	ABR              float64
+	AJBR             float64
+	// ASBR is the annualized spontaneous bleeding rate.
+	ASBR float64
+}
+
+// TargetJoint is a joint that had 3 or more bleeds within 6 months.
+type TargetJoint struct {
+	Site BleedSite
+	// BleedsCount is the number of the joint's bleeds within the 6 months when it was detected,
+	// which start with the first of the 3 bleeds that it was detected with.
+	BleedsCount int
+	DetectedAt  time.Time
+	LastBleedAt time.Time
+	// Active is false when the joint was detected more than 12 months ago and had 2 or less bleeds in the last 12 months.
+	Active bool
+}
+
+type PatientBleedAnalytics struct {
+	Windows                []BleedRates
+	TargetJoints           []TargetJoint
+	LatestJointsEvaluation *JointsEvaluation
+	// Consumption is the factor consumption over the longest window.
+	Consumption FactorConsumption
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"slices"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -71,15 +73,37 @@ func BloodTestUnits() []BlootTestUnit {
 	}
 }
 
+type BloodTestValueFlag string
+
+const (
+	BloodTestValueFlagLow      BloodTestValueFlag = "low"
+	BloodTestValueFlagNormal   BloodTestValueFlag = "normal"
+	BloodTestValueFlagHigh     BloodTestValueFlag = "high"
+	BloodTestValueFlagCritical BloodTestValueFlag = "critical"
+)
+
+// BloodTestField is a blood test's field, where fields with a unit are numeric,
+// and [MinValueNumber, MaxValueNumber] is the numeric field's normal range when MaxValueNumber > MinValueNumber.
+// The field holds its latest definition, and Versions holds every definition it had, oldest first.
 type BloodTestField struct {
 	Id             uint          `gorm:"primaryKey;autoIncrement"`
 	BloodTestId    uint          `gorm:"not null"`
 	Name           string        `gorm:"not null"`
 	Unit           BlootTestUnit `gorm:"not null"`
+	Required       bool          `gorm:"not null;default:false"`
 	MinValueNumber float64
 	MinValueString string
 	MaxValueNumber float64
 	MaxValueString string
+	// CriticalMinValueNumber and CriticalMaxValueNumber are the optional bounds
+	// that a value outside of them is critical.
+	CriticalMinValueNumber *float64
+	CriticalMaxValueNumber *float64
+	// Version is the blood test's version that the field's latest definition was introduced in.
+	Version int `gorm:"not null;default:1"`
+	// Retired fields can't be filled in new results, but they're kept to interpret the results they were filled in.
+	Retired  bool                    `gorm:"not null;default:false"`
+	Versions []BloodTestFieldVersion `gorm:"foreignKey:BloodTestFieldId"`
 
 	CreatedAt time.Time `gorm:"index;not null"`
 	UpdatedAt time.Time
@@ -89,10 +113,173 @@ func (BloodTestField) TableName() string {
 	return "blood_test_fields"
 }
 
+// SameDefinition reports whether the fields have the same name, unit, ranges and status,
+// regardless of their ids and versions.
+func (f BloodTestField) SameDefinition(other BloodTestField) bool {
+	return f.Name == other.Name &&
+		f.Unit == other.Unit &&
+		f.Required == other.Required &&
+		f.MinValueNumber == other.MinValueNumber &&
+		f.MinValueString == other.MinValueString &&
+		f.MaxValueNumber == other.MaxValueNumber &&
+		f.MaxValueString == other.MaxValueString &&
+		equalFloatPtrs(f.CriticalMinValueNumber, other.CriticalMinValueNumber) &&
+		equalFloatPtrs(f.CriticalMaxValueNumber, other.CriticalMaxValueNumber) &&
+		f.Retired == other.Retired
+}
+
+// Snapshot returns the field's current definition as a version that's in force from effectiveFrom.
+func (f BloodTestField) Snapshot(effectiveFrom time.Time) BloodTestFieldVersion {
+	return BloodTestFieldVersion{
+		BloodTestFieldId:       f.Id,
+		Version:                f.Version,
+		Name:                   f.Name,
+		Unit:                   f.Unit,
+		Required:               f.Required,
+		MinValueNumber:         f.MinValueNumber,
+		MinValueString:         f.MinValueString,
+		MaxValueNumber:         f.MaxValueNumber,
+		MaxValueString:         f.MaxValueString,
+		CriticalMinValueNumber: f.CriticalMinValueNumber,
+		CriticalMaxValueNumber: f.CriticalMaxValueNumber,
+		Retired:                f.Retired,
+		EffectiveFrom:          effectiveFrom,
+	}
+}
+
+// At returns the field's definition that was in force at t,
+// where times before the field's first version are interpreted with its first version,
+// and a field without loaded versions is returned as is.
+func (f BloodTestField) At(t time.Time) BloodTestField {
+	if len(f.Versions) == 0 {
+		return f
+	}
+
+	version := f.Versions[0]
+	for _, v := range f.Versions[1:] {
+		if v.EffectiveFrom.After(t) {
+			break
+		}
+		version = v
+	}
+
+	f.Version = version.Version
+	f.Name = version.Name
+	f.Unit = version.Unit
+	f.Required = version.Required
+	f.MinValueNumber = version.MinValueNumber
+	f.MinValueString = version.MinValueString
+	f.MaxValueNumber = version.MaxValueNumber
+	f.MaxValueString = version.MaxValueString
+	f.CriticalMinValueNumber = version.CriticalMinValueNumber
+	f.CriticalMaxValueNumber = version.CriticalMaxValueNumber
+	f.Retired = version.Retired
+
+	return f
+}
+
+func equalFloatPtrs(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// BloodTestFieldVersion is a field's definition that was in force from EffectiveFrom until the field's next version,
+// versions are never changed, so that results are always interpreted with the ranges they were taken under,
+// and they're only deleted with their blood test when no results reference it.
+type BloodTestFieldVersion struct {
+	Id                     uint          `gorm:"primaryKey;autoIncrement"`
+	BloodTestFieldId       uint          `gorm:"uniqueIndex:idx_blood_test_field_version;not null"`
+	Version                int           `gorm:"uniqueIndex:idx_blood_test_field_version;not null"`
+	Name                   string        `gorm:"not null"`
+	Unit                   BlootTestUnit `gorm:"not null"`
+	Required               bool          `gorm:"not null;default:false"`
+	MinValueNumber         float64
+	MinValueString         string
+	MaxValueNumber         float64
+	MaxValueString         string
+	CriticalMinValueNumber *float64
+	CriticalMaxValueNumber *float64
+	Retired                bool      `gorm:"not null;default:false"`
+	EffectiveFrom          time.Time `gorm:"index;not null"`
+
+	CreatedAt time.Time `gorm:"index;not null"`
+}
+
+func (BloodTestFieldVersion) TableName() string {
+	return "blood_test_field_versions"
+}
+
+func (f BloodTestField) IsNumeric() bool {
+	return f.Unit != BlootTestUnitNoUnit
+}
+
+func (f BloodTestField) HasNormalRange() bool {
+	return f.MaxValueNumber > f.MinValueNumber
+}
+
+// Flag classifies a numeric field's value against its critical bounds and normal range,
+// it's empty for non numeric fields, and for values that have nothing to be compared against.
+func (f BloodTestField) Flag(value float64) BloodTestValueFlag {
+	if !f.IsNumeric() {
+		return ""
+	}
+
+	if (f.CriticalMinValueNumber != nil && value < *f.CriticalMinValueNumber) ||
+		(f.CriticalMaxValueNumber != nil && value > *f.CriticalMaxValueNumber) {
+		return BloodTestValueFlagCritical
+	}
+
+	if !f.HasNormalRange() {
+		return ""
+	}
+
+	switch {
+	case value < f.MinValueNumber:
+		return BloodTestValueFlagLow
+	case value > f.MaxValueNumber:
+		return BloodTestValueFlagHigh
+	default:
+		return BloodTestValueFlagNormal
+	}
+}
+
+var (
+	positiveValues = []string{"positive", "pos", "+", "reactive", "detected", "إيجابي"}
+	negativeValues = []string{"negative", "neg", "-", "non-reactive", "non reactive", "nonreactive", "not detected", "undetected", "سلبي"}
+)
+
+// IsPositive reports whether the field's value indicates a detection, which is a non numeric value like "positive" or "reactive",
+// or a numeric value that's above the field's normal range or critical maximum.
+func (f BloodTestField) IsPositive(value BloodTestFilledField) bool {
+	if !f.IsNumeric() {
+		return slices.Contains(positiveValues, strings.ToLower(strings.TrimSpace(value.ValueString)))
+	}
+
+	return (f.HasNormalRange() && value.ValueNumber > f.MaxValueNumber) ||
+		(f.CriticalMaxValueNumber != nil && value.ValueNumber > *f.CriticalMaxValueNumber)
+}
+
+// IsNegative reports whether the field's value rules out a detection, which is a non numeric value like "negative",
+// or a numeric value that's within or below the field's normal range.
+func (f BloodTestField) IsNegative(value BloodTestFilledField) bool {
+	if !f.IsNumeric() {
+		return slices.Contains(negativeValues, strings.ToLower(strings.TrimSpace(value.ValueString)))
+	}
+
+	return f.HasNormalRange() && !f.IsPositive(value)
+}
+
 type BloodTest struct {
-	Id     uint             `gorm:"primaryKey;autoIncrement"`
-	Name   string           `gorm:"not null"`
-	Fields []BloodTestField `gorm:"foreignKey:BloodTestId"`
+	Id   uint   `gorm:"primaryKey;autoIncrement"`
+	Name string `gorm:"not null"`
+	// Version is increased on every update to the blood test's name or fields.
+	Version int              `gorm:"not null;default:1"`
+	Fields  []BloodTestField `gorm:"foreignKey:BloodTestId"`
+	// ArchivedAt is set for blood tests that can't be used in new results anymore,
+	// they're kept so that the results that used them can still be rendered.
+	ArchivedAt *time.Time `gorm:"index"`
 
 	CreatedAt time.Time `gorm:"index;not null"`
 	UpdatedAt time.Time
@@ -102,6 +289,10 @@ func (BloodTest) TableName() string {
 	return "blood_tests"
 }
 
+func (bt BloodTest) IsArchived() bool {
+	return bt.ArchivedAt != nil
+}
+
 func (bt *BloodTest) AfterDelete(tx *gorm.DB) error {
 	for i := range bt.Fields {
 		err := tx.
@@ -146,3 +337,66 @@ type BloodTestResult struct {
 func (BloodTestResult) TableName() string {
 	return "blood_test_results"
 }
+
+// BloodTestFieldPoint is a field's numeric value in a patient's blood test result,
+// or the aggregate of several values when it's downsampled.
+type BloodTestFieldPoint struct {
+	BloodTestResultId uint
+	Value             float64
+	MeasuredAt        time.Time
+	// Min, Max and Count describe the aggregated values, and they're set on downsampled points only.
+	Min   float64
+	Max   float64
+	Count int
+}
+
+// DownsampleBloodTestFieldPoints averages the time ordered points into at most maxPoints points,
+// where each point aggregates the points in an equal slice of the points' time span,
+// and is placed at the average time of its points.
+func DownsampleBloodTestFieldPoints(points []BloodTestFieldPoint, maxPoints int) []BloodTestFieldPoint {
+	if maxPoints < 1 || len(points) <= maxPoints {
+		return points
+	}
+
+	first := points[0].MeasuredAt
+	span := points[len(points)-1].MeasuredAt.Sub(first)
+	buckets := make([][]BloodTestFieldPoint, maxPoints)
+	for _, point := range points {
+		bucket := 0
+		if span > 0 {
+			bucket = int(float64(point.MeasuredAt.Sub(first)) / float64(span) * float64(maxPoints))
+		}
+		bucket = min(bucket, maxPoints-1)
+		buckets[bucket] = append(buckets[bucket], point)
+	}
+
+	downsampled := make([]BloodTestFieldPoint, 0, maxPoints)
+	for _, bucket := range buckets {
+		if len(bucket) == 0 {
+			continue
+		}
+
+		aggregate := BloodTestFieldPoint{
+			Min:   bucket[0].Value,
+			Max:   bucket[0].Value,
+			Count: len(bucket),
+		}
+		sum := 0.0
+		var timeOffsets time.Duration
+		for _, point := range bucket {
+			sum += point.Value
+			aggregate.Min = min(aggregate.Min, point.Value)
+			aggregate.Max = max(aggregate.Max, point.Value)
+			timeOffsets += point.MeasuredAt.Sub(first) / time.Duration(len(bucket))
+		}
+		aggregate.Value = sum / float64(len(bucket))
+		aggregate.MeasuredAt = first.Add(timeOffsets)
+		if len(bucket) == 1 {
+			aggregate.BloodTestResultId = bucket[0].BloodTestResultId
+		}
+
+		downsampled = append(downsampled, aggregate)
+	}
+
+	return downsampled
+}
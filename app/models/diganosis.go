@@ -1,12 +1,70 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+)
+
+type DiagnosisFieldType string
+
+const (
+	DiagnosisFieldTypeText    DiagnosisFieldType = "text"
+	DiagnosisFieldTypeNumber  DiagnosisFieldType = "number"
+	DiagnosisFieldTypeBoolean DiagnosisFieldType = "boolean"
+	DiagnosisFieldTypeDate    DiagnosisFieldType = "date"
+	DiagnosisFieldTypeChoice  DiagnosisFieldType = "choice"
+)
+
+func DiagnosisFieldTypes() []DiagnosisFieldType {
+	return []DiagnosisFieldType{
+		DiagnosisFieldTypeText,
+		DiagnosisFieldTypeNumber,
+		DiagnosisFieldTypeBoolean,
+		DiagnosisFieldTypeDate,
+		DiagnosisFieldTypeChoice,
+	}
+}
+
+// DiagnosisField is a typed value that's filled when the diagnosis is given,
+// e.g. the hemophilia type as a choice, or the carrier status as a boolean.
+type DiagnosisField struct {
+	Id          uint               `gorm:"primaryKey;autoIncrement"`
+	DiagnosisId uint               `gorm:"index;not null"`
+	Name        string             `gorm:"not null"`
+	Type        DiagnosisFieldType `gorm:"not null"`
+	Required    bool               `gorm:"not null;default:false"`
+	// Choices are the newline separated values of a choice field.
+	Choices string
+	// Unit is the optional unit of a number field.
+	Unit string
+
+	CreatedAt time.Time `gorm:"index;not null"`
+	UpdatedAt time.Time
+}
+
+func (DiagnosisField) TableName() string {
+	return "diagnosis_fields"
+}
+
+func (f DiagnosisField) ChoicesList() []string {
+	choices := make([]string, 0)
+	for _, choice := range strings.Split(f.Choices, "\n") {
+		choice = strings.TrimSpace(choice)
+		if choice != "" {
+			choices = append(choices, choice)
+		}
+	}
+
+	return choices
+}
 
 type Diagnosis struct {
-	Id        uint   `gorm:"primaryKey;autoIncrement"`
-	GroupName string `gorm:"not null"`
-	Title     string `gorm:"not null"`
-	// TODO: add filled fields
+	Id        uint             `gorm:"primaryKey;autoIncrement"`
+	GroupName string           `gorm:"not null"`
+	Title     string           `gorm:"not null"`
+	Fields    []DiagnosisField `gorm:"foreignKey:DiagnosisId"`
+	// ArchivedAt is set for diagnoses that can't be given to patients anymore.
+	ArchivedAt *time.Time `gorm:"index"`
 
 	CreatedAt time.Time `gorm:"index;not null"`
 	UpdatedAt time.Time
@@ -16,13 +74,28 @@ func (Diagnosis) TableName() string {
 	return "diagnoses"
 }
 
+func (d Diagnosis) IsArchived() bool {
+	return d.ArchivedAt != nil
+}
+
+// DiagnosisResult is a diagnosis given to a patient, results are never changed besides being revoked or replaced,
+// where updating a result replaces it with a new result, so that the patient's diagnoses keep their history.
 type DiagnosisResult struct {
-	Id          uint `gorm:"primaryKey;autoIncrement"`
-	DiagnosisId uint `gorm:"not null"`
-	Diagnosis   Diagnosis
-	PatientId   uint      `gorm:"index;not null"`
-	DiagnosedAt time.Time `gorm:"not null"`
-	// TODO: add filled fields
+	Id            uint `gorm:"primaryKey;autoIncrement"`
+	DiagnosisId   uint `gorm:"not null"`
+	Diagnosis     Diagnosis
+	PatientId     uint                   `gorm:"index;not null"`
+	DiagnosedAt   time.Time              `gorm:"not null"`
+	DiagnosedById uint                   `gorm:"index"`
+	FilledFields  []DiagnosisFilledField `gorm:"foreignKey:DiagnosisResultId"`
+	Notes         string
+	// ReplacesId is the result that this result was updated from.
+	ReplacesId uint `gorm:"index"`
+	// ReplacedById is the result that this result was updated to.
+	ReplacedById     uint `gorm:"index"`
+	RevokedAt        *time.Time
+	RevokedById      uint
+	RevocationReason string
 
 	CreatedAt time.Time `gorm:"index;not null"`
 	UpdatedAt time.Time
@@ -31,3 +104,24 @@ type DiagnosisResult struct {
 func (DiagnosisResult) TableName() string {
 	return "diagnoses_results"
 }
+
+// IsActive reports whether the result is the patient's current diagnosis, i.e. it's neither revoked nor replaced.
+func (dr DiagnosisResult) IsActive() bool {
+	return dr.RevokedAt == nil && dr.ReplacedById == 0
+}
+
+// DiagnosisFilledField is a diagnosis field's value in a result, ValueString holds every value in its canonical form,
+// i.e. "true" or "false" for booleans, and YYYY-MM-DD for dates, and number values are also held in ValueNumber.
+type DiagnosisFilledField struct {
+	Id                uint   `gorm:"primaryKey;autoIncrement"`
+	DiagnosisResultId uint   `gorm:"index;not null"`
+	DiagnosisFieldId  uint   `gorm:"not null"`
+	ValueString       string `gorm:"not null"`
+	ValueNumber       float64
+
+	CreatedAt time.Time `gorm:"index;not null"`
+}
+
+func (DiagnosisFilledField) TableName() string {
+	return "diagnosis_filled_fields"
+}
@@ -0,0 +1,266 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// PatientAlias is the public id of a patient that was merged into another patient,
+// it keeps the merged patient's identifying fields so that they can still be looked up.
+type PatientAlias struct {
+	Id              uint   `gorm:"primaryKey;autoIncrement"`
+	PatientId       uint   `gorm:"index;not null"`
+	AliasPublicId   string `gorm:"not null;unique"`
+	MergedPatientId uint   `gorm:"not null"`
+	NationalId      string
+	FirstName       string
+	LastName        string
+	FatherName      string
+	MotherName      string
+	PhoneNumber     string
+	AccountId       uint
+
+	CreatedAt time.Time `gorm:"index;not null"`
+}
+
+func (PatientAlias) TableName() string {
+	return "patient_aliases"
+}
+
+// placeholderPrefix is the prefix of the values set by Patient.FillEmptyFieldsUsingPublicId.
+const placeholderPrefix = "please_change_"
+
+func identifyingValue(value string) string {
+	if strings.HasPrefix(value, placeholderPrefix) {
+		return ""
+	}
+
+	return strings.TrimSpace(value)
+}
+
+// PhoneSuffixLength is the length of the phone numbers' suffix that's compared, which skips the country and trunk codes.
+const PhoneSuffixLength = 9
+
+// PhoneKey is the phone number's last PhoneSuffixLength digits, which phone numbers are compared with.
+func PhoneKey(phoneNumber string) string {
+	digits := OnlyDigits(identifyingValue(phoneNumber))
+	if len(digits) > PhoneSuffixLength {
+		digits = digits[len(digits)-PhoneSuffixLength:]
+	}
+
+	return digits
+}
+
+func dateOfBirthSimilarity(a, b time.Time) float64 {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	switch {
+	case ay == by && am == bm && ad == bd:
+		return 1
+	// swapped day and month, or a typo in one of the date's parts.
+	case ay == by && int(am) == bd && ad == int(bm),
+		ay == by && (am == bm || ad == bd),
+		am == bm && ad == bd && (ay-by == 1 || by-ay == 1):
+		return 0.5
+	default:
+		return 0
+	}
+}
+
+type duplicateEvidence struct {
+	name     string
+	weight   float64
+	score    float64
+	matchAt  float64
+	provided bool
+}
+
+// DuplicateScore is how likely two patients are the same person between 0 and 1,
+// with the fields that matched.
+type DuplicateScore struct {
+	Score     float64
+	MatchedOn []string
+}
+
+// ScoreDuplicate scores the similarity of the patients' names, dates of birth, national ids and phone numbers,
+// where fields that either patient doesn't have are left out of the score instead of counting as mismatches.
+func ScoreDuplicate(a, b Patient) DuplicateScore {
+	evidence := []duplicateEvidence{
+		{name: "first_name", weight: 0.16, matchAt: 0.85},
+		{name: "last_name", weight: 0.13, matchAt: 0.85},
+		{name: "father_name", weight: 0.09, matchAt: 0.85},
+		{name: "mother_name", weight: 0.07, matchAt: 0.85},
+		{name: "date_of_birth", weight: 0.2, matchAt: 1, provided: true},
+		{name: "national_id", weight: 0.2, matchAt: 1},
+		{name: "phone_number", weight: 0.15, matchAt: 1},
+	}
+
+	names := [][2]string{
+		{a.FirstName, b.FirstName},
+		{a.LastName, b.LastName},
+		{a.FatherName, b.FatherName},
+		{a.MotherName, b.MotherName},
+	}
+	for i, name := range names {
+		if strings.TrimSpace(name[0]) != "" && strings.TrimSpace(name[1]) != "" {
+			evidence[i].provided = true
+			evidence[i].score = NameSimilarity(name[0], name[1])
+		}
+	}
+
+	evidence[4].score = dateOfBirthSimilarity(a.DateOfBirth, b.DateOfBirth)
+
+	nationalIdA, nationalIdB := OnlyDigits(identifyingValue(a.NationalId)), OnlyDigits(identifyingValue(b.NationalId))
+	if nationalIdA != "" && nationalIdB != "" {
+		evidence[5].provided = true
+		switch distance := levenshtein([]rune(nationalIdA), []rune(nationalIdB)); distance {
+		case 0:
+			evidence[5].score = 1
+		case 1:
+			evidence[5].score = 0.7
+		}
+	}
+
+	phoneA, phoneB := PhoneKey(a.PhoneNumber), PhoneKey(b.PhoneNumber)
+	if phoneA != "" && phoneB != "" {
+		evidence[6].provided = true
+		if phoneA == phoneB {
+			evidence[6].score = 1
+		}
+	}
+
+	var total, weights float64
+	matchedOn := make([]string, 0, len(evidence))
+	for _, e := range evidence {
+		if !e.provided {
+			continue
+		}
+		total += e.weight * e.score
+		weights += e.weight
+		if e.score >= e.matchAt {
+			matchedOn = append(matchedOn, e.name)
+		}
+	}
+	if weights == 0 {
+		return DuplicateScore{}
+	}
+
+	return DuplicateScore{
+		Score:     total / weights,
+		MatchedOn: matchedOn,
+	}
+}
+
+// PatientDuplicate is a pair of patients that are likely the same person.
+type PatientDuplicate struct {
+	Patient   Patient
+	Duplicate Patient
+	DuplicateScore
+}
+
+// maxDuplicateBlockSize skips blocks that are too common to tell patients apart, like a popular date of birth.
+const maxDuplicateBlockSize = 100
+
+// duplicateBlockingKeys are the keys of the blocks that the patient is compared within,
+// so that not every pair of patients is scored.
+func duplicateBlockingKeys(p Patient) []string {
+	keys := make([]string, 0, 4)
+	if nationalId := OnlyDigits(identifyingValue(p.NationalId)); nationalId != "" {
+		keys = append(keys, "national_id:"+nationalId)
+	}
+	if phone := PhoneKey(p.PhoneNumber); phone != "" {
+		keys = append(keys, "phone_number:"+phone)
+	}
+	if !p.DateOfBirth.IsZero() {
+		keys = append(keys, "date_of_birth:"+p.DateOfBirth.Format(time.DateOnly))
+	}
+	firstName, lastName := nameSkeleton(NormalizeArabic(p.FirstName)), nameSkeleton(NormalizeArabic(p.LastName))
+	if firstName != "" && lastName != "" {
+		keys = append(keys, "name:"+firstName+"|"+lastName)
+	}
+
+	return keys
+}
+
+// FindDuplicates finds the pairs of patients that score at least minScore, best scored first,
+// only patients that share a national id, a phone number, a date of birth or their names' skeletons are compared.
+func FindDuplicates(patients []Patient, minScore float64) []PatientDuplicate {
+	blocks := make(map[string][]int)
+	for i, p := range patients {
+		for _, key := range duplicateBlockingKeys(p) {
+			blocks[key] = append(blocks[key], i)
+		}
+	}
+
+	scored := make(map[[2]int]bool)
+	duplicates := make([]PatientDuplicate, 0)
+	for _, block := range blocks {
+		if len(block) > maxDuplicateBlockSize {
+			continue
+		}
+		for i := 0; i < len(block); i++ {
+			for j := i + 1; j < len(block); j++ {
+				pair := [2]int{block[i], block[j]}
+				if scored[pair] {
+					continue
+				}
+				scored[pair] = true
+
+				score := ScoreDuplicate(patients[pair[0]], patients[pair[1]])
+				if score.Score < minScore {
+					continue
+				}
+				duplicates = append(duplicates, PatientDuplicate{
+					Patient:        patients[pair[0]],
+					Duplicate:      patients[pair[1]],
+					DuplicateScore: score,
+				})
+			}
+		}
+	}
+
+	sortDuplicates(duplicates)
+
+	return duplicates
+}
+
+// FindPatientDuplicates scores the patient against every other patient, and keeps the ones that score at least minScore,
+// best scored first.
+func FindPatientDuplicates(patient Patient, patients []Patient, minScore float64) []PatientDuplicate {
+	duplicates := make([]PatientDuplicate, 0)
+	for _, other := range patients {
+		if other.Id == patient.Id {
+			continue
+		}
+
+		score := ScoreDuplicate(patient, other)
+		if score.Score < minScore {
+			continue
+		}
+		duplicates = append(duplicates, PatientDuplicate{
+			Patient:        patient,
+			Duplicate:      other,
+			DuplicateScore: score,
+		})
+	}
+
+	sortDuplicates(duplicates)
+
+	return duplicates
+}
+
+func sortDuplicates(duplicates []PatientDuplicate) {
+	slices.SortFunc(duplicates, func(a, b PatientDuplicate) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		case a.Patient.Id != b.Patient.Id:
+			return int(a.Patient.Id) - int(b.Patient.Id)
+		default:
+			return int(a.Duplicate.Id) - int(b.Duplicate.Id)
+		}
+	})
+}
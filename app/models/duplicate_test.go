@@ -0,0 +1,110 @@
+package models
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestScoreDuplicate(t *testing.T) {
+	dateOfBirth := time.Date(2010, time.March, 4, 0, 0, 0, 0, time.UTC)
+	patient := Patient{
+		FirstName:   "Ahmad",
+		LastName:    "Khalil",
+		FatherName:  "Omar",
+		MotherName:  "Huda",
+		DateOfBirth: dateOfBirth,
+		NationalId:  "01020304050",
+		PhoneNumber: "+963 933 123 456",
+	}
+
+	tests := []struct {
+		name          string
+		other         Patient
+		wantMinScore  float64
+		wantMaxScore  float64
+		wantMatchedOn []string
+	}{
+		{
+			name:          "same person",
+			other:         patient,
+			wantMinScore:  1,
+			wantMaxScore:  1,
+			wantMatchedOn: []string{"first_name", "last_name", "father_name", "mother_name", "date_of_birth", "national_id", "phone_number"},
+		},
+		{
+			name: "local phone format and missing national id",
+			other: Patient{
+				FirstName: "Ahmad", LastName: "Khalil", FatherName: "Omar", MotherName: "Huda",
+				DateOfBirth: dateOfBirth, PhoneNumber: "0933123456",
+			},
+			wantMinScore:  1,
+			wantMaxScore:  1,
+			wantMatchedOn: []string{"first_name", "last_name", "father_name", "mother_name", "date_of_birth", "phone_number"},
+		},
+		{
+			name: "swapped day and month",
+			other: Patient{
+				FirstName: "Ahmad", LastName: "Khalil", FatherName: "Omar", MotherName: "Huda",
+				DateOfBirth: time.Date(2010, time.April, 3, 0, 0, 0, 0, time.UTC),
+			},
+			wantMinScore:  0.8,
+			wantMaxScore:  0.95,
+			wantMatchedOn: []string{"first_name", "last_name", "father_name", "mother_name"},
+		},
+		{
+			name: "different person",
+			other: Patient{
+				FirstName: "Sara", LastName: "Haddad", FatherName: "Nabil", MotherName: "Rima",
+				DateOfBirth: time.Date(2001, time.July, 20, 0, 0, 0, 0, time.UTC),
+				NationalId:  "09080706050", PhoneNumber: "0944000000",
+			},
+			wantMinScore:  0,
+			wantMaxScore:  0.3,
+			wantMatchedOn: []string{},
+		},
+		{
+			name: "placeholder values aren't compared",
+			other: Patient{
+				FirstName: "Ahmad", LastName: "Khalil", FatherName: "Omar", MotherName: "Huda",
+				DateOfBirth: dateOfBirth, NationalId: placeholderPrefix + "01020304050", PhoneNumber: placeholderPrefix + "phone",
+			},
+			wantMinScore:  1,
+			wantMaxScore:  1,
+			wantMatchedOn: []string{"first_name", "last_name", "father_name", "mother_name", "date_of_birth"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ScoreDuplicate(patient, tt.other)
+			if got.Score < tt.wantMinScore || got.Score > tt.wantMaxScore {
+				t.Errorf("ScoreDuplicate().Score = %v, want between %v and %v", got.Score, tt.wantMinScore, tt.wantMaxScore)
+			}
+			if !slices.Equal(got.MatchedOn, tt.wantMatchedOn) {
+				t.Errorf("ScoreDuplicate().MatchedOn = %v, want %v", got.MatchedOn, tt.wantMatchedOn)
+			}
+		})
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	dateOfBirth := time.Date(2010, time.March, 4, 0, 0, 0, 0, time.UTC)
+	patients := []Patient{
+		{Id: 1, FirstName: "Ahmad", LastName: "Khalil", FatherName: "Omar", DateOfBirth: dateOfBirth, PhoneNumber: "0933123456"},
+		{Id: 2, FirstName: "Sara", LastName: "Haddad", FatherName: "Nabil", DateOfBirth: time.Date(2001, time.July, 20, 0, 0, 0, 0, time.UTC)},
+		{Id: 3, FirstName: "Ahmed", LastName: "Khalil", FatherName: "Omar", DateOfBirth: dateOfBirth},
+		{Id: 4, FirstName: "Rami", LastName: "Saleh", FatherName: "Fadi", DateOfBirth: dateOfBirth, PhoneNumber: "+963933123456"},
+	}
+
+	got := FindDuplicates(patients, 0.8)
+	pairs := make([][2]uint, 0, len(got))
+	for _, duplicate := range got {
+		pairs = append(pairs, [2]uint{duplicate.Patient.Id, duplicate.Duplicate.Id})
+	}
+
+	want := [][2]uint{{1, 3}}
+	if !slices.Equal(pairs, want) {
+		t.Errorf("FindDuplicates() pairs = %v, want %v", pairs, want)
+	}
+}
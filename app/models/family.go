@@ -0,0 +1,192 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+type RelationshipType string
+
+const (
+	RelationshipTypeFather              RelationshipType = "father"
+	RelationshipTypeMother              RelationshipType = "mother"
+	RelationshipTypeSon                 RelationshipType = "son"
+	RelationshipTypeDaughter            RelationshipType = "daughter"
+	RelationshipTypeBrother             RelationshipType = "brother"
+	RelationshipTypeSister              RelationshipType = "sister"
+	RelationshipTypeMaternalHalfBrother RelationshipType = "maternal_half_brother"
+	RelationshipTypeMaternalHalfSister  RelationshipType = "maternal_half_sister"
+	RelationshipTypeMaternalGrandfather RelationshipType = "maternal_grandfather"
+	RelationshipTypeMaternalGrandmother RelationshipType = "maternal_grandmother"
+	RelationshipTypeMaternalUncle       RelationshipType = "maternal_uncle"
+	RelationshipTypeMaternalAunt        RelationshipType = "maternal_aunt"
+	RelationshipTypeMaternalCousin      RelationshipType = "maternal_cousin"
+	RelationshipTypeNephew              RelationshipType = "nephew"
+	RelationshipTypeNiece               RelationshipType = "niece"
+	RelationshipTypeOther               RelationshipType = "other"
+)
+
+func RelationshipTypes() []RelationshipType {
+	return []RelationshipType{
+		RelationshipTypeFather,
+		RelationshipTypeMother,
+		RelationshipTypeSon,
+		RelationshipTypeDaughter,
+		RelationshipTypeBrother,
+		RelationshipTypeSister,
+		RelationshipTypeMaternalHalfBrother,
+		RelationshipTypeMaternalHalfSister,
+		RelationshipTypeMaternalGrandfather,
+		RelationshipTypeMaternalGrandmother,
+		RelationshipTypeMaternalUncle,
+		RelationshipTypeMaternalAunt,
+		RelationshipTypeMaternalCousin,
+		RelationshipTypeNephew,
+		RelationshipTypeNiece,
+		RelationshipTypeOther,
+	}
+}
+
+// CarrierStatus is whether a female relative carries the hemophilia gene, it's empty when it wasn't assessed.
+type CarrierStatus string
+
+const (
+	CarrierStatusCarrier         CarrierStatus = "carrier"
+	CarrierStatusObligateCarrier CarrierStatus = "obligate_carrier"
+	CarrierStatusPossibleCarrier CarrierStatus = "possible_carrier"
+	CarrierStatusNonCarrier      CarrierStatus = "non_carrier"
+)
+
+func CarrierStatuses() []CarrierStatus {
+	return []CarrierStatus{
+		CarrierStatusCarrier,
+		CarrierStatusObligateCarrier,
+		CarrierStatusPossibleCarrier,
+		CarrierStatusNonCarrier,
+	}
+}
+
+// Relative is a patient's family member who isn't a patient, like a carrier mother,
+// a relative can be shared between the patients of the same family.
+type Relative struct {
+	Id            uint          `gorm:"primaryKey;autoIncrement"`
+	Name          string        `gorm:"not null"`
+	Gender        bool          `gorm:"not null"`
+	CarrierStatus CarrierStatus `gorm:"index"`
+	Notes         string
+	AccountId     uint
+
+	CreatedAt time.Time `gorm:"index;not null"`
+	UpdatedAt time.Time
+}
+
+func (Relative) TableName() string {
+	return "relatives"
+}
+
+// PatientRelationship is a family link where the relative is the patient's Type,
+// the relative is either another patient, set in RelativePatientId, or a non patient relative, set in RelativeId.
+type PatientRelationship struct {
+	Id                uint             `gorm:"primaryKey;autoIncrement"`
+	PatientId         uint             `gorm:"not null;uniqueIndex:idx_patient_relative"`
+	RelativePatientId uint             `gorm:"index;uniqueIndex:idx_patient_relative"`
+	RelativeId        uint             `gorm:"index;uniqueIndex:idx_patient_relative"`
+	Type              RelationshipType `gorm:"not null"`
+	AccountId         uint
+
+	CreatedAt time.Time `gorm:"index;not null"`
+	UpdatedAt time.Time
+}
+
+func (PatientRelationship) TableName() string {
+	return "patient_relationships"
+}
+
+// Links checks whether the relationship links the two patients, in either direction.
+func (r PatientRelationship) Links(patientId, otherPatientId uint) bool {
+	return (r.PatientId == patientId && r.RelativePatientId == otherPatientId) ||
+		(r.PatientId == otherPatientId && r.RelativePatientId == patientId)
+}
+
+// Pedigree is the family graph of a patient, it has every patient and relative reachable through the relationships.
+type Pedigree struct {
+	Patients      []Patient
+	Relatives     []Relative
+	Relationships []PatientRelationship
+}
+
+// RelationshipProposal is a likely family link between two patients, proposed from their names,
+// that isn't recorded until staff confirm it.
+type RelationshipProposal struct {
+	Patient Patient
+	Type    RelationshipType
+	// MatchedOn has the names that matched, like last_name, father_name and mother_name.
+	MatchedOn []string
+}
+
+// minParentAgeGap is the least age difference between a proposed parent and child.
+const minParentAgeGap = 12
+
+// ProposeRelationships proposes the candidates who share the patient's last name, father's name and mother's name as siblings,
+// and the candidates whose first name and last name are the patient's father's, or the other way around, as fathers and children,
+// where a father must be older than his child by minParentAgeGap years.
+// Candidates that are already linked to the patient aren't proposed.
+func ProposeRelationships(patient Patient, candidates []Patient, existing []PatientRelationship) []RelationshipProposal {
+	sameName := func(a, b string) bool {
+		a, b = strings.TrimSpace(a), strings.TrimSpace(b)
+		return a != "" && strings.EqualFold(a, b)
+	}
+	isParentOf := func(parent, child Patient) bool {
+		return !parent.DateOfBirth.AddDate(minParentAgeGap, 0, 0).After(child.DateOfBirth)
+	}
+
+	proposals := make([]RelationshipProposal, 0)
+	for _, candidate := range candidates {
+		if candidate.Id == patient.Id || !sameName(candidate.LastName, patient.LastName) {
+			continue
+		}
+
+		linked := false
+		for _, relationship := range existing {
+			if relationship.Links(patient.Id, candidate.Id) {
+				linked = true
+				break
+			}
+		}
+		if linked {
+			continue
+		}
+
+		// Gender is true for males.
+		switch {
+		case sameName(candidate.FatherName, patient.FatherName) && sameName(candidate.MotherName, patient.MotherName):
+			relationshipType := RelationshipTypeSister
+			if candidate.Gender {
+				relationshipType = RelationshipTypeBrother
+			}
+			proposals = append(proposals, RelationshipProposal{
+				Patient:   candidate,
+				Type:      relationshipType,
+				MatchedOn: []string{"last_name", "father_name", "mother_name"},
+			})
+		case candidate.Gender && sameName(candidate.FirstName, patient.FatherName) && isParentOf(candidate, patient):
+			proposals = append(proposals, RelationshipProposal{
+				Patient:   candidate,
+				Type:      RelationshipTypeFather,
+				MatchedOn: []string{"last_name", "father_name"},
+			})
+		case patient.Gender && sameName(candidate.FatherName, patient.FirstName) && isParentOf(patient, candidate):
+			relationshipType := RelationshipTypeDaughter
+			if candidate.Gender {
+				relationshipType = RelationshipTypeSon
+			}
+			proposals = append(proposals, RelationshipProposal{
+				Patient:   candidate,
+				Type:      relationshipType,
+				MatchedOn: []string{"last_name", "father_name"},
+			})
+		}
+	}
+
+	return proposals
+}
@@ -0,0 +1,166 @@
+package models
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type HemophiliaType string
+
+const (
+	HemophiliaTypeA HemophiliaType = "A"
+	HemophiliaTypeB HemophiliaType = "B"
+)
+
+func HemophiliaTypes() []HemophiliaType {
+	return []HemophiliaType{
+		HemophiliaTypeA,
+		HemophiliaTypeB,
+	}
+}
+
+type HemophiliaSeverity string
+
+const (
+	HemophiliaSeveritySevere   HemophiliaSeverity = "severe"
+	HemophiliaSeverityModerate HemophiliaSeverity = "moderate"
+	HemophiliaSeverityMild     HemophiliaSeverity = "mild"
+)
+
+func HemophiliaSeverities() []HemophiliaSeverity {
+	return []HemophiliaSeverity{
+		HemophiliaSeveritySevere,
+		HemophiliaSeverityModerate,
+		HemophiliaSeverityMild,
+	}
+}
+
+const (
+	FactorVIIIBloodTestName = "Factor - VIII"
+	FactorIXBloodTestName   = "Factor - IX"
+)
+
+// ClassifyHemophiliaSeverity classifies a factor's activity percentage,
+// where levels of 40% and above aren't hemophilia and are classified as an empty severity.
+func ClassifyHemophiliaSeverity(factorLevel float64) HemophiliaSeverity {
+	switch {
+	case factorLevel < 1:
+		return HemophiliaSeveritySevere
+	case factorLevel <= 5:
+		return HemophiliaSeverityModerate
+	case factorLevel < 40:
+		return HemophiliaSeverityMild
+	default:
+		return ""
+	}
+}
+
+// HemophiliaClassification is a patient's hemophilia type and severity,
+// with the factor level and the blood test result they were classified from.
+type HemophiliaClassification struct {
+	Type              HemophiliaType
+	Severity          HemophiliaSeverity
+	FactorLevel       float64
+	MeasuredAt        time.Time
+	BloodTestResultId uint
+}
+
+// factorFieldId is the id of the blood test's factor level field, with the fields as they were defined at t,
+// which is the numeric field named like the blood test, or the blood test's first numeric field.
+func factorFieldId(bt BloodTest, t time.Time) uint {
+	fieldId := uint(0)
+	for _, field := range bt.Fields {
+		field = field.At(t)
+		if !field.IsNumeric() {
+			continue
+		}
+		if strings.EqualFold(strings.TrimSpace(field.Name), strings.TrimSpace(bt.Name)) {
+			return field.Id
+		}
+		if fieldId == 0 {
+			fieldId = field.Id
+		}
+	}
+
+	return fieldId
+}
+
+// factorLevel parses the filled factor level, which isn't valid for the empty or invalid values that older imports stored as 0.
+func factorLevel(field BloodTestFilledField) (float64, bool) {
+	value, err := strconv.ParseFloat(strings.TrimSpace(field.ValueString), 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, false
+	}
+
+	return value, true
+}
+
+// ClassifyHemophilia classifies a patient's hemophilia using the latest non pending factor VIII and factor IX results,
+// where the deficient factor with the lower level decides the type,
+// the classification is empty when neither factor was measured below 40%.
+// Every result's factor level field is found with the fields' definitions when the result was taken,
+// so that editing a field doesn't reclassify the results taken before, and results without a valid factor level are skipped.
+func ClassifyHemophilia(results []BloodTestResult, bloodTests []BloodTest) HemophiliaClassification {
+	factorTypes := make(map[uint]HemophiliaType)
+	factorTests := make(map[uint]BloodTest)
+	for _, bt := range bloodTests {
+		switch {
+		case strings.EqualFold(strings.TrimSpace(bt.Name), FactorVIIIBloodTestName):
+			factorTypes[bt.Id] = HemophiliaTypeA
+		case strings.EqualFold(strings.TrimSpace(bt.Name), FactorIXBloodTestName):
+			factorTypes[bt.Id] = HemophiliaTypeB
+		default:
+			continue
+		}
+		factorTests[bt.Id] = bt
+	}
+
+	latest := make(map[HemophiliaType]HemophiliaClassification)
+	for _, btr := range results {
+		hemophiliaType, ok := factorTypes[btr.BloodTestId]
+		if !ok || btr.Pending {
+			continue
+		}
+		if current, ok := latest[hemophiliaType]; ok && !btr.CreatedAt.After(current.MeasuredAt) {
+			continue
+		}
+
+		fieldId := factorFieldId(factorTests[btr.BloodTestId], btr.CreatedAt)
+		if fieldId == 0 {
+			continue
+		}
+
+		for _, field := range btr.FilledFields {
+			if field.BloodTestFieldId != fieldId {
+				continue
+			}
+			level, ok := factorLevel(field)
+			if !ok {
+				break
+			}
+			latest[hemophiliaType] = HemophiliaClassification{
+				Type:              hemophiliaType,
+				Severity:          ClassifyHemophiliaSeverity(level),
+				FactorLevel:       level,
+				MeasuredAt:        btr.CreatedAt,
+				BloodTestResultId: btr.Id,
+			}
+			break
+		}
+	}
+
+	var classification HemophiliaClassification
+	for _, hemophiliaType := range HemophiliaTypes() {
+		current, ok := latest[hemophiliaType]
+		if !ok || current.Severity == "" {
+			continue
+		}
+		if classification.Type == "" || current.FactorLevel < classification.FactorLevel {
+			classification = current
+		}
+	}
+
+	return classification
+}
@@ -0,0 +1,170 @@
+package models
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestClassifyHemophiliaSeverity(t *testing.T) {
+	tests := []struct {
+		factorLevel float64
+		want        HemophiliaSeverity
+	}{
+		{factorLevel: 0, want: HemophiliaSeveritySevere},
+		{factorLevel: 0.9, want: HemophiliaSeveritySevere},
+		{factorLevel: 1, want: HemophiliaSeverityModerate},
+		{factorLevel: 5, want: HemophiliaSeverityModerate},
+		{factorLevel: 5.1, want: HemophiliaSeverityMild},
+		{factorLevel: 39.9, want: HemophiliaSeverityMild},
+		{factorLevel: 40, want: ""},
+		{factorLevel: 120, want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := ClassifyHemophiliaSeverity(tt.factorLevel); got != tt.want {
+			t.Errorf("ClassifyHemophiliaSeverity(%v) = %q, want %q", tt.factorLevel, got, tt.want)
+		}
+	}
+}
+
+func TestClassifyHemophilia(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2024, time.January, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	factorVIII := BloodTest{
+		Id:   1,
+		Name: FactorVIIIBloodTestName,
+		Fields: []BloodTestField{
+			{Id: 10, Name: "Notes", Unit: BlootTestUnitNoUnit},
+			{Id: 11, Name: FactorVIIIBloodTestName, Unit: BlootTestUnitPercentage},
+		},
+	}
+	factorIX := BloodTest{
+		Id:   2,
+		Name: FactorIXBloodTestName,
+		Fields: []BloodTestField{
+			{Id: 20, Name: FactorIXBloodTestName, Unit: BlootTestUnitPercentage},
+		},
+	}
+	// the factor field was numeric until day 10, when it was changed to have no unit.
+	editedFactorVIII := BloodTest{
+		Id:   1,
+		Name: FactorVIIIBloodTestName,
+		Fields: []BloodTestField{
+			{
+				Id: 11, Name: FactorVIIIBloodTestName, Unit: BlootTestUnitNoUnit,
+				Versions: []BloodTestFieldVersion{
+					{BloodTestFieldId: 11, Version: 1, Name: FactorVIIIBloodTestName, Unit: BlootTestUnitPercentage, EffectiveFrom: day(1)},
+					{BloodTestFieldId: 11, Version: 2, Name: FactorVIIIBloodTestName, Unit: BlootTestUnitNoUnit, EffectiveFrom: day(10)},
+				},
+			},
+		},
+	}
+
+	result := func(id, bloodTestId, fieldId uint, value float64, at time.Time, pending bool) BloodTestResult {
+		return BloodTestResult{
+			Id:          id,
+			BloodTestId: bloodTestId,
+			Pending:     pending,
+			CreatedAt:   at,
+			FilledFields: []BloodTestFilledField{
+				{BloodTestFieldId: fieldId, ValueNumber: value, ValueString: strconv.FormatFloat(value, 'f', -1, 64)},
+			},
+		}
+	}
+	// imported results stored the factor levels that didn't parse as 0.
+	importedResult := func(id uint, valueString string, at time.Time) BloodTestResult {
+		return BloodTestResult{
+			Id:          id,
+			BloodTestId: 1,
+			CreatedAt:   at,
+			FilledFields: []BloodTestFilledField{
+				{BloodTestFieldId: 11, ValueString: valueString},
+			},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		results    []BloodTestResult
+		bloodTests []BloodTest
+		want       HemophiliaClassification
+	}{
+		{
+			name:       "no results",
+			bloodTests: []BloodTest{factorVIII, factorIX},
+			want:       HemophiliaClassification{},
+		},
+		{
+			name:       "the latest result is used",
+			results:    []BloodTestResult{result(1, 1, 11, 0.5, day(1), false), result(2, 1, 11, 3, day(2), false)},
+			bloodTests: []BloodTest{factorVIII, factorIX},
+			want: HemophiliaClassification{
+				Type: HemophiliaTypeA, Severity: HemophiliaSeverityModerate, FactorLevel: 3, MeasuredAt: day(2), BloodTestResultId: 2,
+			},
+		},
+		{
+			name:       "pending results are skipped",
+			results:    []BloodTestResult{result(1, 1, 11, 0.5, day(1), false), result(2, 1, 11, 3, day(2), true)},
+			bloodTests: []BloodTest{factorVIII, factorIX},
+			want: HemophiliaClassification{
+				Type: HemophiliaTypeA, Severity: HemophiliaSeveritySevere, FactorLevel: 0.5, MeasuredAt: day(1), BloodTestResultId: 1,
+			},
+		},
+		{
+			name:       "the lower deficient factor decides the type",
+			results:    []BloodTestResult{result(1, 1, 11, 20, day(1), false), result(2, 2, 20, 2, day(1), false)},
+			bloodTests: []BloodTest{factorVIII, factorIX},
+			want: HemophiliaClassification{
+				Type: HemophiliaTypeB, Severity: HemophiliaSeverityModerate, FactorLevel: 2, MeasuredAt: day(1), BloodTestResultId: 2,
+			},
+		},
+		{
+			name: "results without a valid factor level are skipped",
+			results: []BloodTestResult{
+				result(1, 1, 11, 3, day(1), false), importedResult(2, "", day(2)), importedResult(3, "2%", day(3)),
+			},
+			bloodTests: []BloodTest{factorVIII, factorIX},
+			want: HemophiliaClassification{
+				Type: HemophiliaTypeA, Severity: HemophiliaSeverityModerate, FactorLevel: 3, MeasuredAt: day(1), BloodTestResultId: 1,
+			},
+		},
+		{
+			name:       "a blank imported factor level isn't severe",
+			results:    []BloodTestResult{importedResult(1, " ", day(1))},
+			bloodTests: []BloodTest{factorVIII, factorIX},
+			want:       HemophiliaClassification{},
+		},
+		{
+			name:       "normal levels aren't classified",
+			results:    []BloodTestResult{result(1, 1, 11, 80, day(1), false)},
+			bloodTests: []BloodTest{factorVIII, factorIX},
+			want:       HemophiliaClassification{},
+		},
+		{
+			name:       "results are interpreted with the field's definition when they were taken",
+			results:    []BloodTestResult{result(1, 1, 11, 0.5, day(5), false)},
+			bloodTests: []BloodTest{editedFactorVIII},
+			want: HemophiliaClassification{
+				Type: HemophiliaTypeA, Severity: HemophiliaSeveritySevere, FactorLevel: 0.5, MeasuredAt: day(5), BloodTestResultId: 1,
+			},
+		},
+		{
+			name:       "results taken after the field stopped being numeric are skipped",
+			results:    []BloodTestResult{result(1, 1, 11, 0.5, day(12), false)},
+			bloodTests: []BloodTest{editedFactorVIII},
+			want:       HemophiliaClassification{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ClassifyHemophilia(tt.results, tt.bloodTests)
+			if got != tt.want {
+				t.Errorf("ClassifyHemophilia() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
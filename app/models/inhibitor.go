@@ -0,0 +1,103 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+const (
+	// InhibitorPositiveTiterBU is the titer from which an inhibitor is positive.
+	InhibitorPositiveTiterBU = 0.6
+	// InhibitorHighResponderTiterBU is the peak titer from which a patient is a high responder.
+	InhibitorHighResponderTiterBU = 5
+)
+
+type InhibitorResponder string
+
+const (
+	InhibitorResponderLow  InhibitorResponder = "low"
+	InhibitorResponderHigh InhibitorResponder = "high"
+)
+
+// InhibitorTiter is a Bethesda titer measured in a blood test result.
+type InhibitorTiter struct {
+	BloodTestResultId uint
+	BloodTestFieldId  uint
+	Titer             float64
+	MeasuredAt        time.Time
+}
+
+func (t InhibitorTiter) IsPositive() bool {
+	return t.Titer >= InhibitorPositiveTiterBU
+}
+
+// ListInhibitorTiters lists the titers of the non pending results' fields that were measured in BU when the results were taken, oldest first.
+func ListInhibitorTiters(results []BloodTestResult, bloodTests []BloodTest) []InhibitorTiter {
+	fields := make(map[uint]BloodTestField)
+	for _, bt := range bloodTests {
+		for _, field := range bt.Fields {
+			fields[field.Id] = field
+		}
+	}
+
+	titers := make([]InhibitorTiter, 0)
+	for _, btr := range results {
+		if btr.Pending {
+			continue
+		}
+		for _, field := range btr.FilledFields {
+			btField, ok := fields[field.BloodTestFieldId]
+			if !ok || btField.At(btr.CreatedAt).Unit != BlootTestUnitBU {
+				continue
+			}
+			titers = append(titers, InhibitorTiter{
+				BloodTestResultId: btr.Id,
+				BloodTestFieldId:  field.BloodTestFieldId,
+				Titer:             field.ValueNumber,
+				MeasuredAt:        btr.CreatedAt,
+			})
+		}
+	}
+
+	slices.SortStableFunc(titers, func(a, b InhibitorTiter) int {
+		return a.MeasuredAt.Compare(b.MeasuredAt)
+	})
+
+	return titers
+}
+
+// InhibitorClassification is a patient's inhibitor status, where Positive is decided by the latest titer,
+// and Responder by the peak titer, so a high responder stays one after the inhibitor is gone.
+type InhibitorClassification struct {
+	Positive    bool
+	Responder   InhibitorResponder
+	LatestTiter float64
+	PeakTiter   float64
+	MeasuredAt  time.Time
+}
+
+// ClassifyInhibitor classifies the titers, which are expected to be oldest first.
+func ClassifyInhibitor(titers []InhibitorTiter) InhibitorClassification {
+	if len(titers) == 0 {
+		return InhibitorClassification{}
+	}
+
+	latest := titers[len(titers)-1]
+	classification := InhibitorClassification{
+		Positive:    latest.IsPositive(),
+		LatestTiter: latest.Titer,
+		MeasuredAt:  latest.MeasuredAt,
+	}
+	for _, titer := range titers {
+		classification.PeakTiter = max(classification.PeakTiter, titer.Titer)
+	}
+
+	switch {
+	case classification.PeakTiter >= InhibitorHighResponderTiterBU:
+		classification.Responder = InhibitorResponderHigh
+	case classification.PeakTiter >= InhibitorPositiveTiterBU:
+		classification.Responder = InhibitorResponderLow
+	}
+
+	return classification
+}
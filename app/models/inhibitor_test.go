@@ -0,0 +1,121 @@
+package models
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestListInhibitorTiters(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2024, time.January, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	bloodTests := []BloodTest{
+		{
+			Id: 1,
+			Fields: []BloodTestField{
+				{Id: 10, Unit: BlootTestUnitBU},
+				{Id: 11, Unit: BlootTestUnitPercentage},
+				// the field was measured in BU until day 10.
+				{
+					Id: 12, Unit: BlootTestUnitPercentage,
+					Versions: []BloodTestFieldVersion{
+						{BloodTestFieldId: 12, Version: 1, Unit: BlootTestUnitBU, EffectiveFrom: day(1)},
+						{BloodTestFieldId: 12, Version: 2, Unit: BlootTestUnitPercentage, EffectiveFrom: day(10)},
+					},
+				},
+			},
+		},
+	}
+
+	results := []BloodTestResult{
+		{
+			Id: 1, BloodTestId: 1, CreatedAt: day(12),
+			FilledFields: []BloodTestFilledField{
+				{BloodTestFieldId: 10, ValueNumber: 1},
+				{BloodTestFieldId: 11, ValueNumber: 50},
+				{BloodTestFieldId: 12, ValueNumber: 30},
+			},
+		},
+		{
+			Id: 2, BloodTestId: 1, CreatedAt: day(5),
+			FilledFields: []BloodTestFilledField{
+				{BloodTestFieldId: 12, ValueNumber: 6},
+			},
+		},
+		{
+			Id: 3, BloodTestId: 1, CreatedAt: day(7), Pending: true,
+			FilledFields: []BloodTestFilledField{
+				{BloodTestFieldId: 10, ValueNumber: 9},
+			},
+		},
+	}
+
+	want := []InhibitorTiter{
+		{BloodTestResultId: 2, BloodTestFieldId: 12, Titer: 6, MeasuredAt: day(5)},
+		{BloodTestResultId: 1, BloodTestFieldId: 10, Titer: 1, MeasuredAt: day(12)},
+	}
+	if got := ListInhibitorTiters(results, bloodTests); !slices.Equal(got, want) {
+		t.Errorf("ListInhibitorTiters() = %+v, want %+v", got, want)
+	}
+}
+
+func TestClassifyInhibitor(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2024, time.January, d, 0, 0, 0, 0, time.UTC)
+	}
+	titers := func(values ...float64) []InhibitorTiter {
+		ts := make([]InhibitorTiter, 0, len(values))
+		for i, value := range values {
+			ts = append(ts, InhibitorTiter{Titer: value, MeasuredAt: day(i + 1)})
+		}
+		return ts
+	}
+
+	tests := []struct {
+		name   string
+		titers []InhibitorTiter
+		want   InhibitorClassification
+	}{
+		{
+			name: "no titers",
+			want: InhibitorClassification{},
+		},
+		{
+			name:   "negative",
+			titers: titers(0.3),
+			want:   InhibitorClassification{LatestTiter: 0.3, PeakTiter: 0.3, MeasuredAt: day(1)},
+		},
+		{
+			name:   "positive at the threshold",
+			titers: titers(InhibitorPositiveTiterBU),
+			want: InhibitorClassification{
+				Positive: true, Responder: InhibitorResponderLow,
+				LatestTiter: InhibitorPositiveTiterBU, PeakTiter: InhibitorPositiveTiterBU, MeasuredAt: day(1),
+			},
+		},
+		{
+			name:   "high responder",
+			titers: titers(2, 12),
+			want: InhibitorClassification{
+				Positive: true, Responder: InhibitorResponderHigh, LatestTiter: 12, PeakTiter: 12, MeasuredAt: day(2),
+			},
+		},
+		{
+			name:   "a high responder stays one after the inhibitor is gone",
+			titers: titers(12, 0.2),
+			want: InhibitorClassification{
+				Responder: InhibitorResponderHigh, LatestTiter: 0.2, PeakTiter: 12, MeasuredAt: day(2),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyInhibitor(tt.titers); got != tt.want {
+				t.Errorf("ClassifyInhibitor() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
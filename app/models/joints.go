@@ -2,15 +2,50 @@ package models
 
 import "time"
 
+type JointsEvaluationKind string
+
+const (
+	// JointsEvaluationKindSimple is an evaluation with only a score per joint.
+	JointsEvaluationKindSimple JointsEvaluationKind = "simple"
+	// JointsEvaluationKindHJHS is a Hemophilia Joint Health Score 2.1 evaluation.
+	JointsEvaluationKindHJHS JointsEvaluationKind = "hjhs"
+)
+
+func JointsEvaluationKinds() []JointsEvaluationKind {
+	return []JointsEvaluationKind{
+		JointsEvaluationKindSimple,
+		JointsEvaluationKindHJHS,
+	}
+}
+
+// EvaluatedJoints returns the joints that are scored in a joints evaluation.
+func EvaluatedJoints() []BleedSite {
+	return []BleedSite{
+		BleedSiteRightAnkle,
+		BleedSiteLeftAnkle,
+		BleedSiteRightKnee,
+		BleedSiteLeftKnee,
+		BleedSiteRightElbow,
+		BleedSiteLeftElbow,
+	}
+}
+
+const HJHSMaxGlobalGait = 4
+
+// JointsEvaluation has a score per joint, where HJHS evaluations also have every joint's items,
+// and the joint scores are the items' totals.
 type JointsEvaluation struct {
-	Id         uint `gorm:"primaryKey;autoIncrement"`
-	PatientId  uint `gorm:"index"`
-	RightAnkle int  `gorm:"not null"`
-	LeftAnkle  int  `gorm:"not null"`
-	RightKnee  int  `gorm:"not null"`
-	LeftKnee   int  `gorm:"not null"`
-	RightElbow int  `gorm:"not null"`
-	LeftElbow  int  `gorm:"not null"`
+	Id         uint                 `gorm:"primaryKey;autoIncrement"`
+	PatientId  uint                 `gorm:"index"`
+	Kind       JointsEvaluationKind `gorm:"not null;default:simple"`
+	RightAnkle int                  `gorm:"not null"`
+	LeftAnkle  int                  `gorm:"not null"`
+	RightKnee  int                  `gorm:"not null"`
+	LeftKnee   int                  `gorm:"not null"`
+	RightElbow int                  `gorm:"not null"`
+	LeftElbow  int                  `gorm:"not null"`
+	GlobalGait int
+	Joints     []JointHealthScore `gorm:"foreignKey:JointsEvaluationId"`
 
 	CreatedAt time.Time `gorm:"index;not null"`
 	UpdatedAt time.Time
@@ -19,3 +54,124 @@ type JointsEvaluation struct {
 func (JointsEvaluation) TableName() string {
 	return "joints_evaluations"
 }
+
+// JointScore returns the evaluation's score of the given joint site, or 0 for sites that aren't joints.
+func (je JointsEvaluation) JointScore(site BleedSite) int {
+	switch site {
+	case BleedSiteRightAnkle:
+		return je.RightAnkle
+	case BleedSiteLeftAnkle:
+		return je.LeftAnkle
+	case BleedSiteRightKnee:
+		return je.RightKnee
+	case BleedSiteLeftKnee:
+		return je.LeftKnee
+	case BleedSiteRightElbow:
+		return je.RightElbow
+	case BleedSiteLeftElbow:
+		return je.LeftElbow
+	default:
+		return 0
+	}
+}
+
+func (je *JointsEvaluation) setJointScore(site BleedSite, score int) {
+	switch site {
+	case BleedSiteRightAnkle:
+		je.RightAnkle = score
+	case BleedSiteLeftAnkle:
+		je.LeftAnkle = score
+	case BleedSiteRightKnee:
+		je.RightKnee = score
+	case BleedSiteLeftKnee:
+		je.LeftKnee = score
+	case BleedSiteRightElbow:
+		je.RightElbow = score
+	case BleedSiteLeftElbow:
+		je.LeftElbow = score
+	}
+}
+
+// SetJointScoresFromItems sets every joint's score to its HJHS items' total.
+func (je *JointsEvaluation) SetJointScoresFromItems() {
+	for _, joint := range je.Joints {
+		je.setJointScore(joint.Joint, joint.Total())
+	}
+}
+
+// Total sums the joint scores, and the global gait score for HJHS evaluations.
+func (je JointsEvaluation) Total() int {
+	total := je.RightAnkle + je.LeftAnkle +
+		je.RightKnee + je.LeftKnee +
+		je.RightElbow + je.LeftElbow
+	if je.Kind == JointsEvaluationKindHJHS {
+		total += je.GlobalGait
+	}
+
+	return total
+}
+
+// JointHealthScore is a joint's HJHS 2.1 items.
+type JointHealthScore struct {
+	Id                 uint      `gorm:"primaryKey;autoIncrement"`
+	JointsEvaluationId uint      `gorm:"index;not null"`
+	Joint              BleedSite `gorm:"not null"`
+	Swelling           int       `gorm:"not null"`
+	Duration           int       `gorm:"not null"`
+	MuscleAtrophy      int       `gorm:"not null"`
+	Crepitus           int       `gorm:"not null"`
+	FlexionLoss        int       `gorm:"not null"`
+	ExtensionLoss      int       `gorm:"not null"`
+	JointPain          int       `gorm:"not null"`
+	Strength           int       `gorm:"not null"`
+}
+
+func (JointHealthScore) TableName() string {
+	return "joint_health_scores"
+}
+
+// HJHSItem is a joint's item with its allowed [0, Max] range.
+type HJHSItem struct {
+	Name  string
+	Score int
+	Max   int
+}
+
+func (j JointHealthScore) Items() []HJHSItem {
+	return []HJHSItem{
+		{Name: "swelling", Score: j.Swelling, Max: 3},
+		{Name: "duration", Score: j.Duration, Max: 1},
+		{Name: "muscle_atrophy", Score: j.MuscleAtrophy, Max: 2},
+		{Name: "crepitus", Score: j.Crepitus, Max: 2},
+		{Name: "flexion_loss", Score: j.FlexionLoss, Max: 3},
+		{Name: "extension_loss", Score: j.ExtensionLoss, Max: 3},
+		{Name: "joint_pain", Score: j.JointPain, Max: 2},
+		{Name: "strength", Score: j.Strength, Max: 4},
+	}
+}
+
+func (j JointHealthScore) Total() int {
+	total := 0
+	for _, item := range j.Items() {
+		total += item.Score
+	}
+
+	return total
+}
+
+type JointTrendPoint struct {
+	EvaluationId uint
+	EvaluatedAt  time.Time
+	Kind         JointsEvaluationKind
+	Score        int
+	// Items is nil for simple evaluations.
+	Items *JointHealthScore
+}
+
+// JointTrend is a joint's scores over the patient's evaluations, oldest first.
+type JointTrend struct {
+	Joint  BleedSite
+	Points []JointTrendPoint
+	// Change is the latest score minus the first one.
+	Change int
+}
@@ -1,18 +1,78 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
+// MedicineProduct holds the attributes that are shared by all of a medicine's batches,
+// where batches with a different dose or unit are different products.
+type MedicineProduct struct {
+	Id           uint   `gorm:"primaryKey;autoIncrement"`
+	Name         string `gorm:"not null;uniqueIndex:idx_medicine_product_strength"`
+	Dose         int    `gorm:"not null;uniqueIndex:idx_medicine_product_strength"`
+	Unit         string `gorm:"not null;uniqueIndex:idx_medicine_product_strength"`
+	Manufacturer string `gorm:"not null;uniqueIndex:idx_medicine_product_strength"`
+	FactorType   string `gorm:"not null;uniqueIndex:idx_medicine_product_strength"`
+	// MinStock is the number of packages under which a low stock alert is raised, zero disables it.
+	MinStock int
+	Batches  []Medicine `gorm:"foreignKey:ProductId"`
+
+	CreatedAt time.Time `gorm:"index;not null"`
+	UpdatedAt time.Time
+}
+
+func (MedicineProduct) TableName() string {
+	return "medicine_products"
+}
+
+// IsDosedInIU reports whether the product's dose is in international units, i.e. it's a factor concentrate.
+func (p MedicineProduct) IsDosedInIU() bool {
+	return strings.EqualFold(strings.TrimSpace(p.Unit), "IU")
+}
+
+// IsPlainFactorConcentrate reports whether the product is a factor VIII or IX concentrate,
+// i.e. not a bypassing agent, which isn't effective for patients with inhibitors.
+func (p MedicineProduct) IsPlainFactorConcentrate() bool {
+	factorType := strings.ToUpper(strings.TrimSpace(p.FactorType))
+	return p.IsDosedInIU() && (factorType == "VIII" || factorType == "IX")
+}
+
+// Amount returns the number of packages left of all of the product's batches.
+func (p MedicineProduct) Amount() int {
+	amount := 0
+	for _, batch := range p.Batches {
+		amount += batch.Amount
+	}
+
+	return amount
+}
+
+// UsableAmount returns the number of packages left of the product's batches that can still be prescribed at t,
+// i.e. the non archived batches that didn't expire.
+func (p MedicineProduct) UsableAmount(t time.Time) int {
+	amount := 0
+	for _, batch := range p.Batches {
+		if batch.IsArchived() || batch.IsExpired(t) {
+			continue
+		}
+		amount += batch.Amount
+	}
+
+	return amount
+}
+
+// Medicine is a single received batch of a medicine product.
 type Medicine struct {
-	Id           uint      `gorm:"primaryKey;autoIncrement"`
-	Name         string    `gorm:"not null"`
-	Dose         int       `gorm:"not null"`
-	Unit         string    `gorm:"not null"`
-	Amount       int       `gorm:"not null"`
-	ExpiresAt    time.Time `gorm:"not null"`
-	ReceivedAt   time.Time `gorm:"not null"`
-	Manufacturer string    `gorm:"not null"`
-	BatchNumber  string    `gorm:"not null"`
-	FactorType   string    `gorm:"not null"`
+	Id          uint            `gorm:"primaryKey;autoIncrement"`
+	ProductId   uint            `gorm:"index;not null"`
+	Product     MedicineProduct `gorm:"foreignKey:ProductId"`
+	Amount      int             `gorm:"not null"`
+	ExpiresAt   time.Time       `gorm:"not null"`
+	ReceivedAt  time.Time       `gorm:"not null"`
+	BatchNumber string          `gorm:"not null"`
+	// ArchivedAt is set for batches that can't be prescribed anymore.
+	ArchivedAt *time.Time `gorm:"index"`
 
 	CreatedAt time.Time `gorm:"index;not null"`
 	UpdatedAt time.Time
@@ -21,3 +81,73 @@ type Medicine struct {
 func (Medicine) TableName() string {
 	return "medicines"
 }
+
+func (m Medicine) IsArchived() bool {
+	return m.ArchivedAt != nil
+}
+
+func (m Medicine) IsExpired(at time.Time) bool {
+	return !m.ExpiresAt.After(at)
+}
+
+type StockMovementType string
+
+const (
+	StockMovementTypeReceipt        StockMovementType = "receipt"
+	StockMovementTypeDispensation   StockMovementType = "dispensation"
+	StockMovementTypeAdjustment     StockMovementType = "adjustment"
+	StockMovementTypeExpiryWriteOff StockMovementType = "expiry_write_off"
+	StockMovementTypeTransfer       StockMovementType = "transfer"
+)
+
+func StockMovementTypes() []StockMovementType {
+	return []StockMovementType{
+		StockMovementTypeReceipt,
+		StockMovementTypeDispensation,
+		StockMovementTypeAdjustment,
+		StockMovementTypeExpiryWriteOff,
+		StockMovementTypeTransfer,
+	}
+}
+
+// StockMovement is an append-only ledger entry of a medicine's amount change,
+// where Quantity is positive for incoming packages and negative for outgoing ones,
+// so a medicine's amount is the sum of its movements' quantities.
+type StockMovement struct {
+	Id         uint              `gorm:"primaryKey;autoIncrement"`
+	MedicineId uint              `gorm:"index;not null"`
+	AccountId  uint              `gorm:"index"`
+	Type       StockMovementType `gorm:"index;not null"`
+	Quantity   int               `gorm:"not null"`
+	Reason     string
+	// PrescribedMedicineId is set for dispensations only.
+	PrescribedMedicineId uint `gorm:"index"`
+	// Destination is set for transfers only.
+	Destination string
+
+	CreatedAt time.Time `gorm:"index;not null"`
+}
+
+func (StockMovement) TableName() string {
+	return "stock_movements"
+}
+
+// StockMovementFilter filters a medicine's stock movements, zero valued fields are ignored.
+type StockMovementFilter struct {
+	MedicineId uint
+	Type       StockMovementType
+	From       time.Time
+	To         time.Time
+	Offset     int
+	Limit      int
+}
+
+// MedicineProductProjection projects when a product runs out, based on its average daily dispensation.
+type MedicineProductProjection struct {
+	Product MedicineProduct
+	// Amount is the product's usable amount, see MedicineProduct.UsableAmount.
+	Amount            int
+	DailyDispensation float64
+	// RunOutAt is zero when the product isn't being dispensed.
+	RunOutAt time.Time
+}
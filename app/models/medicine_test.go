@@ -0,0 +1,115 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMedicineProduct(t *testing.T) {
+	tests := []struct {
+		name                       string
+		product                    MedicineProduct
+		wantAmount                 int
+		wantDosedInIU              bool
+		wantPlainFactorConcentrate bool
+	}{
+		{
+			name:    "product without batches",
+			product: MedicineProduct{Unit: "mg"},
+		},
+		{
+			name: "factor VIII concentrate",
+			product: MedicineProduct{
+				Unit: " iu ", FactorType: "viii",
+				Batches: []Medicine{{Amount: 3}, {Amount: 0}, {Amount: 5}},
+			},
+			wantAmount:                 8,
+			wantDosedInIU:              true,
+			wantPlainFactorConcentrate: true,
+		},
+		{
+			name:                       "factor IX concentrate",
+			product:                    MedicineProduct{Unit: "IU", FactorType: "IX", Batches: []Medicine{{Amount: 2}}},
+			wantAmount:                 2,
+			wantDosedInIU:              true,
+			wantPlainFactorConcentrate: true,
+		},
+		{
+			name:          "bypassing agent",
+			product:       MedicineProduct{Unit: "IU", FactorType: "FEIBA"},
+			wantDosedInIU: true,
+		},
+		{
+			name:    "factor type of a product that isn't dosed in IU",
+			product: MedicineProduct{Unit: "mg", FactorType: "VIII"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.product.Amount(); got != tt.wantAmount {
+				t.Errorf("Amount() = %d, want %d", got, tt.wantAmount)
+			}
+			if got := tt.product.IsDosedInIU(); got != tt.wantDosedInIU {
+				t.Errorf("IsDosedInIU() = %t, want %t", got, tt.wantDosedInIU)
+			}
+			if got := tt.product.IsPlainFactorConcentrate(); got != tt.wantPlainFactorConcentrate {
+				t.Errorf("IsPlainFactorConcentrate() = %t, want %t", got, tt.wantPlainFactorConcentrate)
+			}
+		})
+	}
+}
+
+func TestMedicineIsExpired(t *testing.T) {
+	expiresAt := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+	medicine := Medicine{ExpiresAt: expiresAt}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{name: "before the expiry", at: expiresAt.Add(-time.Second), want: false},
+		{name: "at the expiry", at: expiresAt, want: true},
+		{name: "after the expiry", at: expiresAt.AddDate(0, 0, 1), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := medicine.IsExpired(tt.at); got != tt.want {
+				t.Errorf("IsExpired() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMedicineProductUsableAmount(t *testing.T) {
+	now := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+	archivedAt := now.AddDate(0, -1, 0)
+	product := MedicineProduct{
+		Batches: []Medicine{
+			{Amount: 3, ExpiresAt: now.AddDate(1, 0, 0)},
+			{Amount: 4, ExpiresAt: now.AddDate(0, 0, 1)},
+			{Amount: 5, ExpiresAt: now},
+			{Amount: 6, ExpiresAt: now.AddDate(1, 0, 0), ArchivedAt: &archivedAt},
+		},
+	}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want int
+	}{
+		{name: "archived and expired batches are left out", at: now, want: 7},
+		{name: "batches expire over time", at: now.AddDate(0, 0, 2), want: 3},
+		{name: "before any batch expired", at: now.AddDate(0, 0, -1), want: 12},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := product.UsableAmount(tt.at); got != tt.want {
+				t.Errorf("UsableAmount() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
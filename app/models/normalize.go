@@ -0,0 +1,154 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+)
+
+var arabicLetterReplacer = strings.NewReplacer(
+	"أ", "ا", "إ", "ا", "آ", "ا", "ٱ", "ا",
+	"ى", "ي", "ئ", "ي", "ؤ", "و", "ة", "ه",
+)
+
+// NormalizeArabic normalizes a name for matching, it removes the diacritics and the tatweel,
+// unifies the alef, yaa, waw and taa marbuta forms, lower cases latin letters and collapses the spaces.
+func NormalizeArabic(s string) string {
+	s = arabicLetterReplacer.Replace(s)
+
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		switch {
+		case r >= 0x064B && r <= 0x0652, r == 0x0670, r == 0x0640:
+			continue
+		case unicode.IsSpace(r):
+			space = b.Len() > 0
+			continue
+		}
+		if space {
+			b.WriteRune(' ')
+			space = false
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+
+	return b.String()
+}
+
+var arabicToLatin = map[rune]string{
+	'ا': "a", 'ب': "b", 'ت': "t", 'ث': "T", 'ج': "j", 'ح': "h", 'خ': "K",
+	'د': "d", 'ذ': "D", 'ر': "r", 'ز': "z", 'س': "s", 'ش': "S", 'ص': "s",
+	'ض': "d", 'ط': "t", 'ظ': "z", 'ع': "", 'غ': "G", 'ف': "f", 'ق': "k",
+	'ك': "k", 'ل': "l", 'م': "m", 'ن': "n", 'ه': "h", 'و': "w", 'ي': "y",
+	'ء': "",
+}
+
+var latinDigraphReplacer = strings.NewReplacer(
+	"sh", "S", "ch", "S", "kh", "K", "gh", "G", "th", "T", "dh", "D", "ph", "f",
+)
+
+var latinLetterReplacer = strings.NewReplacer(
+	"q", "k", "c", "k", "g", "j", "p", "b", "v", "f", "x", "ks",
+)
+
+// nameSkeleton is the consonants of a normalized name after transliterating it to latin letters,
+// so that the arabic and latin spellings of the same name, like "محمد" and "Mohammad", have the same skeleton.
+func nameSkeleton(normalized string) string {
+	var latin strings.Builder
+	for _, r := range normalized {
+		if letter, ok := arabicToLatin[r]; ok {
+			latin.WriteString(letter)
+			continue
+		}
+		if r >= 'a' && r <= 'z' || r == ' ' {
+			latin.WriteRune(r)
+		}
+	}
+
+	words := strings.Fields(latinDigraphReplacer.Replace(latin.String()))
+	var skeleton strings.Builder
+	var last rune
+	for _, word := range words {
+		// a trailing h is usually a taa marbuta, that's often left out of latin spellings, like in "Fatima".
+		if len(word) > 2 && strings.HasSuffix(word, "h") {
+			word = word[:len(word)-1]
+		}
+		for _, r := range latinLetterReplacer.Replace(word) {
+			if strings.ContainsRune("aeiouwy", r) {
+				continue
+			}
+			if r == last {
+				continue
+			}
+			skeleton.WriteRune(r)
+			last = r
+		}
+	}
+
+	return skeleton.String()
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
+
+// similarity is 1 minus the edit distance between a and b over the longer's length, it's 0 when either is empty.
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 || len(rb) == 0 {
+		return 0
+	}
+
+	return 1 - float64(levenshtein(ra, rb))/float64(max(len(ra), len(rb)))
+}
+
+// NameSimilarity scores how similar two names are between 0 and 1, using their arabic normalized forms,
+// and their transliterated skeletons which match across arabic and latin spellings.
+func NameSimilarity(a, b string) float64 {
+	a, b = NormalizeArabic(a), NormalizeArabic(b)
+	score := similarity(a, b)
+
+	skeletonA, skeletonB := nameSkeleton(a), nameSkeleton(b)
+	// short skeletons match too many names.
+	if len([]rune(skeletonA)) >= 2 && len([]rune(skeletonB)) >= 2 {
+		score = max(score, 0.9*similarity(skeletonA, skeletonB))
+	}
+
+	return score
+}
+
+// OnlyDigits keeps the value's digits, with arabic digits converted to latin ones.
+func OnlyDigits(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		if r >= '٠' && r <= '٩' {
+			return '0' + (r - '٠')
+		}
+		return -1
+	}, value)
+}
+
+// NameSkeleton is the transliterated skeleton of a name, see nameSkeleton.
+func NameSkeleton(name string) string {
+	return nameSkeleton(NormalizeArabic(name))
+}
@@ -2,6 +2,8 @@ package models
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -15,6 +17,9 @@ type PatientIndexFields struct {
 	PlaceOfBirth Address
 	Residency    Address
 	PhoneNumber  string
+	// HemophiliaType and HemophiliaSeverity match exactly when they're not empty.
+	HemophiliaType     HemophiliaType
+	HemophiliaSeverity HemophiliaSeverity
 }
 
 type PatientFirstVisitReason string
@@ -25,6 +30,14 @@ const (
 	PatientFirstVisitReasonReferral      PatientFirstVisitReason = "referral"
 )
 
+func PatientFirstVisitReasons() []PatientFirstVisitReason {
+	return []PatientFirstVisitReason{
+		PatientFirstVisitReasonFamilyHistory,
+		PatientFirstVisitReasonBleeding,
+		PatientFirstVisitReasonReferral,
+	}
+}
+
 type Patient struct {
 	Id                  uint                    `gorm:"primaryKey;autoIncrement"`
 	PublicId            string                  `gorm:"index;not null;unique"`
@@ -42,8 +55,29 @@ type Patient struct {
 	Gender              bool                    `gorm:"not null;index"`
 	PhoneNumber         string                  `gorm:"index;not null"`
 	FamilyHistoryExists bool                    `gorm:"not null"`
+	CarrierStatus       CarrierStatus           `gorm:"index"`
 	FirstVisitReason    PatientFirstVisitReason `gorm:"not null"`
 	BATScore            uint                    `gorm:"not null"`
+	// HemophiliaType, HemophiliaSeverity and the factor level fields are classified from the patient's factor results,
+	// and they're empty when the patient doesn't have a deficient factor result.
+	HemophiliaType               HemophiliaType     `gorm:"index"`
+	HemophiliaSeverity           HemophiliaSeverity `gorm:"index"`
+	FactorLevel                  float64
+	FactorLevelMeasuredAt        time.Time
+	FactorLevelBloodTestResultId uint
+	// InhibitorPositive and the other inhibitor fields are classified from the patient's titers, see ClassifyInhibitor.
+	InhibitorPositive   bool               `gorm:"not null;default:false;index"`
+	InhibitorResponder  InhibitorResponder `gorm:"index"`
+	InhibitorTiter      float64
+	InhibitorPeakTiter  float64
+	InhibitorMeasuredAt time.Time
+	// the normalized fields are only used for searching, and they're set by SetNormalizedFields on every write.
+	FirstNameNormalized   string `gorm:"index;not null;default:''"`
+	LastNameNormalized    string `gorm:"index;not null;default:''"`
+	FatherNameNormalized  string `gorm:"index;not null;default:''"`
+	MotherNameNormalized  string `gorm:"index;not null;default:''"`
+	NameSkeletons         string `gorm:"not null;default:''"`
+	PhoneNumberNormalized string `gorm:"index;not null;default:''"`
 	// TODO: keep only in the action's model
 	Viruses           []Virus            `gorm:"many2many:has_viruses;"`
 	BloodTestResults  []BloodTestResult  `gorm:"many2many:did_blood_tests;"`
@@ -57,6 +91,40 @@ func (Patient) TableName() string {
 	return "patients"
 }
 
+func (p *Patient) SetHemophiliaClassification(classification HemophiliaClassification) {
+	p.HemophiliaType = classification.Type
+	p.HemophiliaSeverity = classification.Severity
+	p.FactorLevel = classification.FactorLevel
+	p.FactorLevelMeasuredAt = classification.MeasuredAt
+	p.FactorLevelBloodTestResultId = classification.BloodTestResultId
+}
+
+func (p *Patient) SetInhibitorClassification(classification InhibitorClassification) {
+	p.InhibitorPositive = classification.Positive
+	p.InhibitorResponder = classification.Responder
+	p.InhibitorTiter = classification.LatestTiter
+	p.InhibitorPeakTiter = classification.PeakTiter
+	p.InhibitorMeasuredAt = classification.MeasuredAt
+}
+
+// SetNormalizedFields sets the names' NormalizeArabic forms, the names' skeletons separated by spaces,
+// and the phone number's digits.
+func (p *Patient) SetNormalizedFields() {
+	p.FirstNameNormalized = NormalizeArabic(p.FirstName)
+	p.LastNameNormalized = NormalizeArabic(p.LastName)
+	p.FatherNameNormalized = NormalizeArabic(p.FatherName)
+	p.MotherNameNormalized = NormalizeArabic(p.MotherName)
+
+	skeletons := make([]string, 0, 4)
+	for _, name := range []string{p.FirstName, p.LastName, p.FatherName, p.MotherName} {
+		if skeleton := NameSkeleton(name); skeleton != "" {
+			skeletons = append(skeletons, skeleton)
+		}
+	}
+	p.NameSkeletons = strings.Join(skeletons, " ")
+	p.PhoneNumberNormalized = OnlyDigits(identifyingValue(p.PhoneNumber))
+}
+
 func (p Patient) IndexId() string {
 	return fmt.Sprintf("%s#%s#%s#%s", p.FirstName, p.LastName, p.FatherName, p.MotherName)
 }
@@ -85,6 +153,65 @@ func (p *Patient) FillEmptyFieldsUsingPublicId() {
 	}
 }
 
+// Diff returns the changed editable fields between p and newPatient,
+// where the address fields are compared using their values and not their ids.
+func (p Patient) Diff(newPatient Patient) []PatientFieldChange {
+	changes := make([]PatientFieldChange, 0)
+	addChange := func(fieldName, oldValue, newValue string) {
+		if oldValue == newValue {
+			return
+		}
+		changes = append(changes, PatientFieldChange{
+			FieldName: fieldName,
+			OldValue:  oldValue,
+			NewValue:  newValue,
+		})
+	}
+
+	addChange("national_id", p.NationalId, newPatient.NationalId)
+	addChange("nationality", p.Nationality, newPatient.Nationality)
+	addChange("first_name", p.FirstName, newPatient.FirstName)
+	addChange("last_name", p.LastName, newPatient.LastName)
+	addChange("father_name", p.FatherName, newPatient.FatherName)
+	addChange("mother_name", p.MotherName, newPatient.MotherName)
+	addChange("place_of_birth", p.PlaceOfBirth.String(), newPatient.PlaceOfBirth.String())
+	addChange("date_of_birth", p.DateOfBirth.Format(time.DateOnly), newPatient.DateOfBirth.Format(time.DateOnly))
+	addChange("residency", p.Residency.String(), newPatient.Residency.String())
+	addChange("gender", strconv.FormatBool(p.Gender), strconv.FormatBool(newPatient.Gender))
+	addChange("phone_number", p.PhoneNumber, newPatient.PhoneNumber)
+	addChange("family_history_exists", strconv.FormatBool(p.FamilyHistoryExists), strconv.FormatBool(newPatient.FamilyHistoryExists))
+	addChange("carrier_status", string(p.CarrierStatus), string(newPatient.CarrierStatus))
+	addChange("first_visit_reason", string(p.FirstVisitReason), string(newPatient.FirstVisitReason))
+	addChange("bat_score", strconv.FormatUint(uint64(p.BATScore), 10), strconv.FormatUint(uint64(newPatient.BATScore), 10))
+
+	return changes
+}
+
+// PatientFieldChange is a single edited field of a patient's record,
+// values are stored as their string representation.
+type PatientFieldChange struct {
+	Id        uint   `gorm:"primaryKey;autoIncrement"`
+	PatientId uint   `gorm:"index;not null"`
+	AccountId uint   `gorm:"index;not null"`
+	FieldName string `gorm:"not null"`
+	OldValue  string
+	NewValue  string
+
+	CreatedAt time.Time `gorm:"index;not null"`
+	UpdatedAt time.Time
+}
+
+func (PatientFieldChange) TableName() string {
+	return "patient_field_changes"
+}
+
+// PatientVisitsSummary is a patient with their visits aggregated over some date range.
+type PatientVisitsSummary struct {
+	Patient     Patient
+	VisitsCount int
+	LastVisitAt time.Time
+}
+
 type PatientUseMedicine struct {
 	Id         uint `gorm:"primaryKey;autoIncrement"`
 	PatientId  uint `gorm:"not null"`
@@ -0,0 +1,264 @@
+package models
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PatientSearchTerm is a word of a free text patient search, in the forms that it's matched with.
+type PatientSearchTerm struct {
+	// Normalized is matched with the normalized names, and with the prefixes of the public id and the national id.
+	Normalized string
+	// Skeleton is matched with the names' skeletons, it's empty for terms that are too short to have a meaningful skeleton.
+	Skeleton string
+	// Digits is matched with the normalized phone number, it's empty for terms that are too short to be part of a phone number.
+	Digits string
+}
+
+// minSearchDigits is the least digits a term needs to be matched with the patients' phone numbers.
+const minSearchDigits = 4
+
+// ParsePatientSearchTerms splits a free text search into its normalized words.
+func ParsePatientSearchTerms(query string) []PatientSearchTerm {
+	words := strings.Fields(NormalizeArabic(query))
+	terms := make([]PatientSearchTerm, 0, len(words))
+	for _, word := range words {
+		term := PatientSearchTerm{
+			Normalized: word,
+		}
+		if skeleton := nameSkeleton(word); len(skeleton) >= 2 {
+			term.Skeleton = skeleton
+		}
+		if digits := OnlyDigits(word); len(digits) >= minSearchDigits {
+			term.Digits = digits
+		}
+		terms = append(terms, term)
+	}
+
+	return terms
+}
+
+// PatientSearchResult is a patient that matched a search, with how relevant the patient is to it.
+type PatientSearchResult struct {
+	Patient Patient
+	Score   int
+}
+
+func scoreNameMatch(name, term string, weight int) int {
+	switch {
+	case name == "" || term == "":
+		return 0
+	case slices.Contains(strings.Fields(name), term):
+		return 3 * weight
+	case strings.HasPrefix(name, term) || strings.Contains(name, " "+term):
+		return 2 * weight
+	case strings.Contains(name, term):
+		return weight
+	default:
+		return 0
+	}
+}
+
+// scoreSearchTerm scores the term's best match with the patient's fields,
+// where identifiers outrank names, and names outrank their transliterated skeletons.
+func scoreSearchTerm(p Patient, term PatientSearchTerm) int {
+	scores := []int{
+		scoreNameMatch(p.FirstNameNormalized, term.Normalized, 10),
+		scoreNameMatch(p.LastNameNormalized, term.Normalized, 9),
+		scoreNameMatch(p.FatherNameNormalized, term.Normalized, 6),
+		scoreNameMatch(p.MotherNameNormalized, term.Normalized, 5),
+	}
+
+	switch {
+	case strings.EqualFold(p.PublicId, term.Normalized):
+		scores = append(scores, 100)
+	case strings.HasPrefix(strings.ToLower(p.PublicId), term.Normalized):
+		scores = append(scores, 40)
+	}
+
+	if nationalId := identifyingValue(p.NationalId); nationalId != "" {
+		switch {
+		case nationalId == term.Normalized:
+			scores = append(scores, 90)
+		case strings.HasPrefix(nationalId, term.Normalized):
+			scores = append(scores, 30)
+		}
+	}
+
+	if term.Digits != "" && p.PhoneNumberNormalized != "" {
+		switch {
+		case strings.HasSuffix(p.PhoneNumberNormalized, PhoneKey(term.Digits)) && len(term.Digits) >= PhoneSuffixLength:
+			scores = append(scores, 80)
+		case strings.Contains(p.PhoneNumberNormalized, term.Digits):
+			scores = append(scores, 30)
+		}
+	}
+
+	if term.Skeleton != "" {
+		switch skeletons := strings.Fields(p.NameSkeletons); {
+		case slices.Contains(skeletons, term.Skeleton):
+			scores = append(scores, 8)
+		case slices.ContainsFunc(skeletons, func(skeleton string) bool { return strings.HasPrefix(skeleton, term.Skeleton) }):
+			scores = append(scores, 5)
+		}
+	}
+
+	return slices.Max(scores)
+}
+
+// RankPatientSearch scores the patients by the sum of their terms' best matches, and sorts them most relevant first,
+// see SortPatientSearchResults.
+func RankPatientSearch(terms []PatientSearchTerm, patients []Patient) []PatientSearchResult {
+	results := make([]PatientSearchResult, 0, len(patients))
+	for _, p := range patients {
+		score := 0
+		for _, term := range terms {
+			score += scoreSearchTerm(p, term)
+		}
+		results = append(results, PatientSearchResult{
+			Patient: p,
+			Score:   score,
+		})
+	}
+
+	SortPatientSearchResults(results)
+
+	return results
+}
+
+// SortPatientSearchResults sorts the results most relevant first, where results with the same score are sorted newest first by their ids,
+// so that every result has a unique position that a PatientCursor can point to.
+func SortPatientSearchResults(results []PatientSearchResult) {
+	slices.SortFunc(results, func(a, b PatientSearchResult) int {
+		if a.Score != b.Score {
+			return b.Score - a.Score
+		}
+		return int(b.Patient.Id) - int(a.Patient.Id)
+	})
+}
+
+type PatientSort string
+
+const (
+	PatientSortCreatedAt   PatientSort = "created_at"
+	PatientSortDateOfBirth PatientSort = "date_of_birth"
+	PatientSortName        PatientSort = "name"
+	// PatientSortRelevance ranks the patients that match the filter's terms, see RankPatientSearch.
+	PatientSortRelevance PatientSort = "relevance"
+)
+
+func PatientSorts() []PatientSort {
+	return []PatientSort{
+		PatientSortCreatedAt,
+		PatientSortDateOfBirth,
+		PatientSortName,
+		PatientSortRelevance,
+	}
+}
+
+// Column is the patients' column that the sort orders by, it's empty for PatientSortRelevance.
+func (s PatientSort) Column() string {
+	switch s {
+	case PatientSortCreatedAt:
+		return "created_at"
+	case PatientSortDateOfBirth:
+		return "date_of_birth"
+	case PatientSortName:
+		return "first_name_normalized"
+	default:
+		return ""
+	}
+}
+
+// PatientCursor is the position of a listed page's last patient, where the next page starts after it.
+type PatientCursor struct {
+	Sort       PatientSort
+	Descending bool
+	// Value is the last patient's sorted column, or the last patient's score for PatientSortRelevance.
+	Value string
+	Id    uint
+}
+
+// NewPatientCursor is the cursor after the listed result.
+func NewPatientCursor(sort PatientSort, descending bool, last PatientSearchResult) PatientCursor {
+	cursor := PatientCursor{
+		Sort:       sort,
+		Descending: descending,
+		Id:         last.Patient.Id,
+	}
+
+	switch sort {
+	case PatientSortCreatedAt:
+		cursor.Value = last.Patient.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case PatientSortDateOfBirth:
+		cursor.Value = last.Patient.DateOfBirth.UTC().Format(time.RFC3339Nano)
+	case PatientSortName:
+		cursor.Value = last.Patient.FirstNameNormalized
+	case PatientSortRelevance:
+		cursor.Value = strconv.Itoa(last.Score)
+	}
+
+	return cursor
+}
+
+// IsAfter reports whether the result comes after the cursor in the order of SortPatientSearchResults,
+// it's only meaningful for PatientSortRelevance cursors.
+func (c PatientCursor) IsAfter(result PatientSearchResult) bool {
+	score, err := strconv.Atoi(c.Value)
+	if err != nil {
+		return false
+	}
+
+	return result.Score < score || (result.Score == score && result.Patient.Id < c.Id)
+}
+
+// SortValue parses the cursor's value to the type of the sorted column.
+func (c PatientCursor) SortValue() (any, error) {
+	switch c.Sort {
+	case PatientSortCreatedAt, PatientSortDateOfBirth:
+		return time.Parse(time.RFC3339Nano, c.Value)
+	case PatientSortRelevance:
+		return strconv.Atoi(c.Value)
+	default:
+		return c.Value, nil
+	}
+}
+
+// PatientListFilter narrows down the listed patients to the ones that match all of its set fields.
+type PatientListFilter struct {
+	// Terms match the patients that match every term, see ParsePatientSearchTerms.
+	Terms []PatientSearchTerm
+	// Ids match only the patients with the ids when they're set.
+	Ids                []uint
+	Gender             *bool
+	Governorate        string
+	DiagnosisId        uint
+	HemophiliaType     HemophiliaType
+	HemophiliaSeverity HemophiliaSeverity
+	// BornAfter is exclusive and BornBefore is inclusive, so that they can be set from ages in whole years.
+	BornAfter   time.Time
+	BornBefore  time.Time
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	// VisitedFrom and VisitedTo match the patients with at least one visit in the range.
+	VisitedFrom time.Time
+	VisitedTo   time.Time
+
+	Sort       PatientSort
+	Descending bool
+	// Cursor is the previous page's cursor, it's nil for the first page.
+	Cursor *PatientCursor
+	Limit  int
+}
+
+// PatientList is a page of the patients that matched a PatientListFilter.
+type PatientList struct {
+	// Results' scores are only set for PatientSortRelevance.
+	Results []PatientSearchResult
+	// Total is the count of the ranked patients for PatientSortRelevance, which is capped to the newest matches.
+	Total int64
+	// NextCursor is nil for the last page.
+	NextCursor *PatientCursor
+}
@@ -0,0 +1,229 @@
+package models
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestNormalizeArabic(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "alef forms", in: "أحمد إسماعيل آمنة", want: "احمد اسماعيل امنه"},
+		{name: "diacritics", in: "مُحَمَّد", want: "محمد"},
+		{name: "tatweel", in: "مـحـمـد", want: "محمد"},
+		{name: "yaa and hamza forms", in: "مصطفى رؤى هانئ", want: "مصطفي روي هاني"},
+		{name: "latin letters and spaces", in: "  Ahmad \t KHALIL ", want: "ahmad khalil"},
+		{name: "empty", in: "   ", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeArabic(tt.in); got != tt.want {
+				t.Errorf("NormalizeArabic(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePatientSearchTerms(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []PatientSearchTerm
+	}{
+		{
+			query: "",
+			want:  []PatientSearchTerm{},
+		},
+		{
+			query: "Mohammad  مُحمّد",
+			want: []PatientSearchTerm{
+				{Normalized: "mohammad", Skeleton: "mhmd"},
+				{Normalized: "محمد", Skeleton: "mhmd"},
+			},
+		},
+		{
+			query: "Al 0933",
+			want: []PatientSearchTerm{
+				{Normalized: "al"},
+				{Normalized: "0933", Digits: "0933"},
+			},
+		},
+		{
+			query: "٠٩٣٣١٢٣ 093",
+			want: []PatientSearchTerm{
+				{Normalized: "٠٩٣٣١٢٣", Digits: "0933123"},
+				{Normalized: "093"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := ParsePatientSearchTerms(tt.query); !slices.Equal(got, tt.want) {
+				t.Errorf("ParsePatientSearchTerms(%q) = %+v, want %+v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRankPatientSearch(t *testing.T) {
+	patient := func(id uint, publicId, firstName, lastName, nationalId, phoneNumber string) Patient {
+		p := Patient{
+			Id:          id,
+			PublicId:    publicId,
+			FirstName:   firstName,
+			LastName:    lastName,
+			NationalId:  nationalId,
+			PhoneNumber: phoneNumber,
+			CreatedAt:   time.Date(2024, time.January, int(id), 0, 0, 0, 0, time.UTC),
+		}
+		p.SetNormalizedFields()
+		return p
+	}
+
+	patients := []Patient{
+		patient(1, "AB12CD", "Omar", "Mohammad", "01020304050", "0933123456"),
+		patient(2, "EF34GH", "محمد", "Khalil", "05040302010", ""),
+		patient(3, "IJ56KL", "Mohammad", "Saleh", "", "+963 944 000 000"),
+		patient(4, "MOH789", "Sara", "Haddad", "", ""),
+	}
+
+	ranked := func(results []PatientSearchResult) []uint {
+		ids := make([]uint, 0, len(results))
+		for _, result := range results {
+			if result.Score > 0 {
+				ids = append(ids, result.Patient.Id)
+			}
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []uint
+	}{
+		{
+			name:  "first names outrank last names, and names outrank their skeletons",
+			query: "mohammad",
+			want:  []uint{3, 1, 2},
+		},
+		{
+			name:  "exact public id",
+			query: "ab12cd",
+			want:  []uint{1},
+		},
+		{
+			name:  "public id prefix outranks names",
+			query: "moh",
+			want:  []uint{4, 3, 1},
+		},
+		{
+			name:  "exact national id",
+			query: "05040302010",
+			want:  []uint{2},
+		},
+		{
+			name:  "national id prefix",
+			query: "0102",
+			want:  []uint{1},
+		},
+		{
+			name:  "phone number in another format",
+			query: "00963933123456",
+			want:  []uint{1},
+		},
+		{
+			name:  "every term adds to the score",
+			query: "mohammad saleh",
+			want:  []uint{3, 1, 2},
+		},
+		{
+			name:  "equal scores are newest first",
+			query: "Muhammed",
+			want:  []uint{3, 2, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ranked(RankPatientSearch(ParsePatientSearchTerms(tt.query), patients))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("RankPatientSearch(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatientCursor(t *testing.T) {
+	createdAt := time.Date(2024, time.January, 2, 3, 4, 5, 6, time.UTC)
+	dateOfBirth := time.Date(2010, time.March, 4, 0, 0, 0, 0, time.UTC)
+	last := PatientSearchResult{
+		Patient: Patient{Id: 7, CreatedAt: createdAt, DateOfBirth: dateOfBirth, FirstNameNormalized: "احمد"},
+		Score:   30,
+	}
+
+	tests := []struct {
+		sort      PatientSort
+		wantValue string
+		wantSort  any
+	}{
+		{sort: PatientSortCreatedAt, wantValue: "2024-01-02T03:04:05.000000006Z", wantSort: createdAt},
+		{sort: PatientSortDateOfBirth, wantValue: "2010-03-04T00:00:00Z", wantSort: dateOfBirth},
+		{sort: PatientSortName, wantValue: "احمد", wantSort: "احمد"},
+		{sort: PatientSortRelevance, wantValue: "30", wantSort: 30},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.sort), func(t *testing.T) {
+			cursor := NewPatientCursor(tt.sort, true, last)
+			if cursor.Value != tt.wantValue || cursor.Id != 7 || !cursor.Descending {
+				t.Fatalf("NewPatientCursor() = %+v, want value %q after id 7", cursor, tt.wantValue)
+			}
+
+			value, err := cursor.SortValue()
+			if err != nil {
+				t.Fatalf("SortValue() error = %v", err)
+			}
+			if value != tt.wantSort {
+				t.Errorf("SortValue() = %v, want %v", value, tt.wantSort)
+			}
+		})
+	}
+
+	invalid := PatientCursor{Sort: PatientSortCreatedAt, Value: "yesterday"}
+	if _, err := invalid.SortValue(); err == nil {
+		t.Errorf("SortValue() of %+v didn't fail", invalid)
+	}
+}
+
+func TestPatientCursorIsAfter(t *testing.T) {
+	cursor := NewPatientCursor(PatientSortRelevance, true, PatientSearchResult{Patient: Patient{Id: 5}, Score: 30})
+	result := func(id uint, score int) PatientSearchResult {
+		return PatientSearchResult{Patient: Patient{Id: id}, Score: score}
+	}
+
+	tests := []struct {
+		name   string
+		result PatientSearchResult
+		want   bool
+	}{
+		{name: "higher score", result: result(2, 40), want: false},
+		{name: "same score and newer", result: result(6, 30), want: false},
+		{name: "the cursor's result", result: result(5, 30), want: false},
+		{name: "same score and older", result: result(4, 30), want: true},
+		{name: "lower score", result: result(9, 10), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cursor.IsAfter(tt.result); got != tt.want {
+				t.Errorf("IsAfter(%+v) = %v, want %v", tt.result, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,15 @@
+package models
+
+// Usage counts the records that reference a catalog entry, by the kind of the referencing records.
+type Usage map[string]int64
+
+// InUse reports whether any record references the entry.
+func (u Usage) InUse() bool {
+	for _, count := range u {
+		if count > 0 {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,11 +1,16 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 type Virus struct {
 	Id                    uint        `gorm:"primaryKey;autoIncrement"`
 	Name                  string      `gorm:"not null"`
 	IdentifyingBloodTests []BloodTest `gorm:"not null;many2many:identifying_blood_tests;"`
+	// ArchivedAt is set for viruses that can't be assigned to patients anymore.
+	ArchivedAt *time.Time `gorm:"index"`
 
 	CreatedAt time.Time `gorm:"index;not null"`
 	UpdatedAt time.Time
@@ -14,3 +19,171 @@ type Virus struct {
 func (Virus) TableName() string {
 	return "viruses"
 }
+
+func (v Virus) IsArchived() bool {
+	return v.ArchivedAt != nil
+}
+
+type InfectionStatus string
+
+const (
+	InfectionStatusActive  InfectionStatus = "active"
+	InfectionStatusCleared InfectionStatus = "cleared"
+	InfectionStatusTreated InfectionStatus = "treated"
+)
+
+func InfectionStatuses() []InfectionStatus {
+	return []InfectionStatus{
+		InfectionStatusActive,
+		InfectionStatusCleared,
+		InfectionStatusTreated,
+	}
+}
+
+// PatientVirus is a patient's infection with a virus, it's the join row of the patient's viruses,
+// where BloodTestResultId is the result that confirmed the infection when it was confirmed by one.
+type PatientVirus struct {
+	PatientId         uint            `gorm:"primaryKey"`
+	VirusId           uint            `gorm:"primaryKey"`
+	Virus             Virus           `gorm:"foreignKey:VirusId"`
+	Status            InfectionStatus `gorm:"index;not null;default:active"`
+	DetectedAt        time.Time
+	StatusChangedAt   time.Time
+	BloodTestResultId uint
+	AccountId         uint
+	Notes             string
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (PatientVirus) TableName() string {
+	return "has_viruses"
+}
+
+// MergeInfections merges the infections of two patients with the same virus when the patients are merged into the surviving one,
+// where the merged infection has the status that was changed last, the earliest detection, and both infections' notes.
+func MergeInfections(survivor, merged PatientVirus) PatientVirus {
+	infection := survivor
+	if merged.StatusChangedAt.After(survivor.StatusChangedAt) {
+		infection.Status = merged.Status
+		infection.StatusChangedAt = merged.StatusChangedAt
+		infection.BloodTestResultId = merged.BloodTestResultId
+		infection.AccountId = merged.AccountId
+	}
+	if !merged.DetectedAt.IsZero() && (infection.DetectedAt.IsZero() || merged.DetectedAt.Before(infection.DetectedAt)) {
+		infection.DetectedAt = merged.DetectedAt
+	}
+
+	notes := strings.TrimSpace(merged.Notes)
+	switch {
+	case notes == "" || strings.Contains(infection.Notes, notes):
+	case strings.TrimSpace(infection.Notes) == "":
+		infection.Notes = notes
+	default:
+		infection.Notes = strings.TrimSpace(infection.Notes) + "\n" + notes
+	}
+
+	return infection
+}
+
+// InfectionSuggestion is an infection status that a patient's result of one of the virus's identifying blood tests suggests.
+type InfectionSuggestion struct {
+	Virus             Virus
+	Status            InfectionStatus
+	BloodTestResultId uint
+	DetectedAt        time.Time
+	// Current is the patient's recorded infection with the virus, it's nil when none was recorded.
+	Current *PatientVirus
+}
+
+// SuggestInfections suggests an active infection for every non archived virus with a latest identifying result that's positive,
+// unless the patient's infection was recorded as active after it, and suggests clearing active infections
+// with a latest identifying result that's negative and newer than the infection's status.
+func SuggestInfections(viruses []Virus, infections []PatientVirus, results []BloodTestResult, bloodTests []BloodTest) []InfectionSuggestion {
+	fields := make(map[uint]BloodTestField)
+	for _, bt := range bloodTests {
+		for _, field := range bt.Fields {
+			fields[field.Id] = field
+		}
+	}
+
+	patientInfections := make(map[uint]PatientVirus, len(infections))
+	for _, infection := range infections {
+		patientInfections[infection.VirusId] = infection
+	}
+
+	suggestions := make([]InfectionSuggestion, 0)
+	for _, virus := range viruses {
+		if virus.IsArchived() {
+			continue
+		}
+
+		identifying := make(map[uint]bool, len(virus.IdentifyingBloodTests))
+		for _, bt := range virus.IdentifyingBloodTests {
+			identifying[bt.Id] = true
+		}
+
+		var latest *BloodTestResult
+		latestPositive := false
+		for i, btr := range results {
+			if btr.Pending || !identifying[btr.BloodTestId] {
+				continue
+			}
+			if latest != nil && !btr.CreatedAt.After(latest.CreatedAt) {
+				continue
+			}
+
+			outcome := 0
+			for _, filled := range btr.FilledFields {
+				field, ok := fields[filled.BloodTestFieldId]
+				if !ok {
+					continue
+				}
+				field = field.At(btr.CreatedAt)
+				if field.IsPositive(filled) {
+					outcome = 1
+					break
+				}
+				if field.IsNegative(filled) {
+					outcome = -1
+				}
+			}
+			if outcome == 0 {
+				continue
+			}
+
+			latest = &results[i]
+			latestPositive = outcome > 0
+		}
+		if latest == nil {
+			continue
+		}
+
+		infection, recorded := patientInfections[virus.Id]
+		suggestion := InfectionSuggestion{
+			Virus:             virus,
+			BloodTestResultId: latest.Id,
+			DetectedAt:        latest.CreatedAt,
+		}
+		if recorded {
+			suggestion.Current = &infection
+			if latest.CreatedAt.Before(infection.StatusChangedAt) {
+				continue
+			}
+		}
+
+		switch {
+		case latestPositive && (!recorded || infection.Status != InfectionStatusActive):
+			suggestion.Status = InfectionStatusActive
+		case !latestPositive && recorded && infection.Status == InfectionStatusActive:
+			suggestion.Status = InfectionStatusCleared
+		default:
+			continue
+		}
+
+		suggestions = append(suggestions, suggestion)
+	}
+
+	return suggestions
+}
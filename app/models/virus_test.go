@@ -0,0 +1,77 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMergeInfections(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2024, time.January, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		survivor PatientVirus
+		merged   PatientVirus
+		want     PatientVirus
+	}{
+		{
+			name: "the survivor's newer status is kept",
+			survivor: PatientVirus{
+				PatientId: 1, VirusId: 5, Status: InfectionStatusCleared, DetectedAt: day(3), StatusChangedAt: day(9), BloodTestResultId: 7, AccountId: 2,
+			},
+			merged: PatientVirus{
+				PatientId: 2, VirusId: 5, Status: InfectionStatusActive, DetectedAt: day(1), StatusChangedAt: day(1), BloodTestResultId: 4, AccountId: 3,
+			},
+			want: PatientVirus{
+				PatientId: 1, VirusId: 5, Status: InfectionStatusCleared, DetectedAt: day(1), StatusChangedAt: day(9), BloodTestResultId: 7, AccountId: 2,
+			},
+		},
+		{
+			name: "the merged patient's newer status is taken",
+			survivor: PatientVirus{
+				PatientId: 1, VirusId: 5, Status: InfectionStatusActive, DetectedAt: day(2), StatusChangedAt: day(2), BloodTestResultId: 4, AccountId: 2,
+			},
+			merged: PatientVirus{
+				PatientId: 2, VirusId: 5, Status: InfectionStatusTreated, DetectedAt: day(4), StatusChangedAt: day(8), AccountId: 3,
+			},
+			want: PatientVirus{
+				PatientId: 1, VirusId: 5, Status: InfectionStatusTreated, DetectedAt: day(2), StatusChangedAt: day(8), AccountId: 3,
+			},
+		},
+		{
+			name:     "a missing detection is filled",
+			survivor: PatientVirus{PatientId: 1, VirusId: 5, Status: InfectionStatusActive},
+			merged:   PatientVirus{PatientId: 2, VirusId: 5, Status: InfectionStatusActive, DetectedAt: day(6)},
+			want:     PatientVirus{PatientId: 1, VirusId: 5, Status: InfectionStatusActive, DetectedAt: day(6)},
+		},
+		{
+			name:     "notes are combined",
+			survivor: PatientVirus{PatientId: 1, VirusId: 5, Notes: "on treatment"},
+			merged:   PatientVirus{PatientId: 2, VirusId: 5, Notes: " genotype 1b "},
+			want:     PatientVirus{PatientId: 1, VirusId: 5, Notes: "on treatment\ngenotype 1b"},
+		},
+		{
+			name:     "repeated notes aren't duplicated",
+			survivor: PatientVirus{PatientId: 1, VirusId: 5, Notes: "genotype 1b"},
+			merged:   PatientVirus{PatientId: 2, VirusId: 5, Notes: "genotype 1b"},
+			want:     PatientVirus{PatientId: 1, VirusId: 5, Notes: "genotype 1b"},
+		},
+		{
+			name:     "the merged patient's notes are kept",
+			survivor: PatientVirus{PatientId: 1, VirusId: 5},
+			merged:   PatientVirus{PatientId: 2, VirusId: 5, Notes: "genotype 1b"},
+			want:     PatientVirus{PatientId: 1, VirusId: 5, Notes: "genotype 1b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MergeInfections(tt.survivor, tt.merged); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MergeInfections() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
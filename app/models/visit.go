@@ -15,6 +15,29 @@ const (
 	VisitReasonActiveBleeding       VisitReason = "active_bleeding"
 )
 
+func VisitReasons() []VisitReason {
+	return []VisitReason{
+		VisitReasonPrimaryProphylaxis,
+		VisitReasonSecondaryProphylaxis,
+		VisitReasonSurgery,
+		VisitReasonJointEvaluation,
+		VisitReasonJointInjection,
+		VisitReasonHemelibra,
+		VisitReasonTreatmentAtHome,
+		VisitReasonActiveBleeding,
+	}
+}
+
+// VisitDateRangeFilter selects visits created in [From, To),
+// an empty Reason matches visits of any reason.
+type VisitDateRangeFilter struct {
+	From   time.Time
+	To     time.Time
+	Reason VisitReason
+	Offset int
+	Limit  int
+}
+
 type Visit struct {
 	Id            uint        `gorm:"primaryKey;autoIncrement"`
 	PatientId     uint        `gorm:"index;not null"`
@@ -31,12 +54,35 @@ func (Visit) TableName() string {
 	return "visits"
 }
 
+// Prescription is a visit's prescription of a medicine product,
+// where every prescribed package is a PrescribedMedicine of one of the product's batches.
+type Prescription struct {
+	Id        uint  `gorm:"primaryKey;autoIncrement"`
+	VisitId   uint  `gorm:"not null;index"`
+	Visit     Visit `gorm:"foreignKey:VisitId"`
+	PatientId uint  `gorm:"not null;index"`
+	ProductId uint  `gorm:"not null;index"`
+	Quantity  int   `gorm:"not null"`
+	DosePerKg float64
+	// TotalIU is zero for products that aren't dosed in IU.
+	TotalIU      float64
+	Instructions string
+
+	CreatedAt time.Time `gorm:"index;not null"`
+	UpdatedAt time.Time
+}
+
+func (Prescription) TableName() string {
+	return "prescriptions"
+}
+
 type PrescribedMedicine struct {
-	Id         uint `gorm:"primaryKey;autoIncrement"`
-	VisitId    uint `gorm:"not null;index"`
-	PatientId  uint `gorm:"not null"`
-	MedicineId uint `gorm:"not null"`
-	UsedAt     time.Time
+	Id             uint `gorm:"primaryKey;autoIncrement"`
+	VisitId        uint `gorm:"not null;index"`
+	PrescriptionId uint `gorm:"index"`
+	PatientId      uint `gorm:"not null"`
+	MedicineId     uint `gorm:"not null"`
+	UsedAt         time.Time
 
 	CreatedAt time.Time `gorm:"index;not null"`
 	UpdatedAt time.Time
@@ -45,3 +91,16 @@ type PrescribedMedicine struct {
 func (PrescribedMedicine) TableName() string {
 	return "prescribed_medicines"
 }
+
+// FactorConsumption is a patient's factor concentrate consumption over [From, To).
+type FactorConsumption struct {
+	From               time.Time
+	To                 time.Time
+	PrescriptionsCount int
+	TotalIU            float64
+	// IUPerKg sums every prescription's IU over the patient's weight at the time.
+	IUPerKg        float64
+	IUPerKgPerYear float64
+	// UnweightedIU is the IU of prescriptions where the patient's weight wasn't known, it's not included in IUPerKg.
+	UnweightedIU float64
+}
@@ -0,0 +1,8 @@
+package app
+
+import "shs/app/models"
+
+// Notifier sends alerts to whoever needs to act on them, e.g. the pharmacy's staff.
+type Notifier interface {
+	Notify(alerts []models.Alert) error
+}
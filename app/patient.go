@@ -2,7 +2,7 @@ package app
 
 import (
 	"shs/app/models"
-	"time"
+	"slices"
 )
 
 func (a *App) CreatePatient(patient models.Patient) (models.Patient, error) {
@@ -32,8 +32,23 @@ func (a *App) GetPatientById(id uint) (models.Patient, error) {
 	return patient, nil
 }
 
-func (a *App) GetMinimalPatientByPublicId(publicId string) (models.Patient, error) {
+// getPatientByPublicId gets the patient with the public id, or the patient that the public id's patient was merged into.
+func (a *App) getPatientByPublicId(publicId string) (models.Patient, error) {
 	patient, err := a.repo.GetPatientByPublicId(publicId)
+	if _, ok := err.(*ErrNotFound); !ok {
+		return patient, err
+	}
+
+	alias, aliasErr := a.repo.GetPatientAliasByPublicId(publicId)
+	if aliasErr != nil {
+		return models.Patient{}, err
+	}
+
+	return a.repo.GetPatientById(alias.PatientId)
+}
+
+func (a *App) GetMinimalPatientByPublicId(publicId string) (models.Patient, error) {
+	patient, err := a.getPatientByPublicId(publicId)
 	if err != nil {
 		return models.Patient{}, err
 	}
@@ -42,7 +57,7 @@ func (a *App) GetMinimalPatientByPublicId(publicId string) (models.Patient, erro
 }
 
 func (a *App) GetFullPatientByPublicId(publicId string) (models.Patient, error) {
-	patient, err := a.repo.GetPatientByPublicId(publicId)
+	patient, err := a.getPatientByPublicId(publicId)
 	if err != nil {
 		return models.Patient{}, err
 	}
@@ -71,16 +86,139 @@ func (a *App) GetFullPatientByPublicId(publicId string) (models.Patient, error)
 	return patient, nil
 }
 
-func (a *App) FindPatientsByVisitRange(from, to time.Time) ([]models.Patient, error) {
-	return a.repo.FindPatientsByVisitDateRange(from, to)
+func (a *App) FindPatientsByVisitRange(filter models.VisitDateRangeFilter) ([]models.PatientVisitsSummary, int64, error) {
+	return a.repo.FindPatientsByVisitDateRange(filter)
 }
 
 func (a *App) FindPatientsByIndexFields(fields models.PatientIndexFields) ([]models.Patient, error) {
 	return a.repo.FindPatientsByFields(fields)
 }
 
-func (a *App) ListLastPatients(limit int) ([]models.Patient, error) {
-	return a.repo.ListLastPatients(limit)
+// maxSearchCandidates caps the newest patients that are ranked for PatientSortRelevance, so that short and common terms stay cheap,
+// the patients that match a term's identifier exactly are ranked on top of them.
+const maxSearchCandidates = 500
+
+// aliasSearchScore is the score of the patient that a searched merged patient's public id was merged into,
+// which is the same as an exact public id match.
+const aliasSearchScore = 100
+
+// ListPatients lists a page of the patients that match the filter, where PatientSortRelevance ranks the newest matches,
+// see models.RankPatientSearch, and a single term that's the public id of a merged patient ranks the patient it was merged into
+// like an exact public id match, when the patient matches the rest of the filter.
+// Relevance pages are continued after their last result's score and id, so that new patients don't shift the next pages.
+func (a *App) ListPatients(filter models.PatientListFilter) (models.PatientList, error) {
+	if filter.Sort != models.PatientSortRelevance {
+		// the extra patient tells whether there's a next page.
+		pageFilter := filter
+		pageFilter.Limit++
+		patients, total, err := a.repo.ListPatients(pageFilter)
+		if err != nil {
+			return models.PatientList{}, err
+		}
+
+		list := models.PatientList{
+			Total: total,
+		}
+		hasNext := len(patients) > filter.Limit
+		if hasNext {
+			patients = patients[:filter.Limit]
+		}
+
+		list.Results = make([]models.PatientSearchResult, 0, len(patients))
+		for _, patient := range patients {
+			list.Results = append(list.Results, models.PatientSearchResult{
+				Patient: patient,
+			})
+		}
+		if hasNext {
+			cursor := models.NewPatientCursor(filter.Sort, filter.Descending, list.Results[len(list.Results)-1])
+			list.NextCursor = &cursor
+		}
+
+		return list, nil
+	}
+
+	candidatesFilter := filter
+	candidatesFilter.Sort = models.PatientSortCreatedAt
+	candidatesFilter.Descending = true
+	candidatesFilter.Cursor = nil
+	candidatesFilter.Limit = maxSearchCandidates
+	candidates, _, err := a.repo.ListPatients(candidatesFilter)
+	if err != nil {
+		return models.PatientList{}, err
+	}
+
+	// exact identifier matches are ranked even when they're older than the newest candidates.
+	exactIds, err := a.repo.ListPatientIdsByIdentifiers(filter.Terms)
+	if err != nil {
+		return models.PatientList{}, err
+	}
+	exactIds = slices.DeleteFunc(exactIds, func(id uint) bool {
+		return slices.ContainsFunc(candidates, func(candidate models.Patient) bool { return candidate.Id == id })
+	})
+	if len(exactIds) > 0 {
+		exactFilter := candidatesFilter
+		exactFilter.Ids = exactIds
+		exactFilter.Limit = len(exactIds)
+		exact, _, err := a.repo.ListPatients(exactFilter)
+		if err != nil {
+			return models.PatientList{}, err
+		}
+		candidates = append(candidates, exact...)
+	}
+
+	results := models.RankPatientSearch(filter.Terms, candidates)
+
+	if len(filter.Terms) == 1 {
+		alias, err := a.repo.GetPatientAliasByPublicId(filter.Terms[0].Normalized)
+		switch err.(type) {
+		case nil:
+			// the patient it was merged into doesn't match the term, but it still has to match the rest of the filter.
+			aliasFilter := candidatesFilter
+			aliasFilter.Terms = nil
+			aliasFilter.Ids = []uint{alias.PatientId}
+			aliasFilter.Limit = 1
+			patients, _, err := a.repo.ListPatients(aliasFilter)
+			if err != nil {
+				return models.PatientList{}, err
+			}
+			if len(patients) > 0 {
+				results = slices.DeleteFunc(results, func(result models.PatientSearchResult) bool {
+					return result.Patient.Id == alias.PatientId
+				})
+				results = append(results, models.PatientSearchResult{
+					Patient: patients[0],
+					Score:   aliasSearchScore,
+				})
+				models.SortPatientSearchResults(results)
+			}
+		case *ErrNotFound:
+			// the term isn't a merged patient's public id
+		default:
+			return models.PatientList{}, err
+		}
+	}
+
+	list := models.PatientList{
+		Total: int64(len(results)),
+	}
+	start := 0
+	if filter.Cursor != nil {
+		start = slices.IndexFunc(results, filter.Cursor.IsAfter)
+		if start < 0 {
+			list.Results = []models.PatientSearchResult{}
+			return list, nil
+		}
+	}
+
+	end := min(start+filter.Limit, len(results))
+	list.Results = results[start:end]
+	if end < len(results) {
+		cursor := models.NewPatientCursor(filter.Sort, true, results[end-1])
+		list.NextCursor = &cursor
+	}
+
+	return list, nil
 }
 
 func (a *App) ListPatientVisitPrescribedMedicine(visitId uint) ([]models.PrescribedMedicine, error) {
@@ -106,3 +244,59 @@ func (a *App) GetPatientVisit(visitId uint) (models.Visit, error) {
 func (a *App) DeletePatient(id uint) error {
 	return a.repo.DeletePatient(id)
 }
+
+// UpdatePatient replaces the patient's editable fields with newPatient's,
+// and records every changed field as made by the account with the id editorAccountId.
+func (a *App) UpdatePatient(id uint, newPatient models.Patient, editorAccountId uint) ([]models.PatientFieldChange, error) {
+	oldPatient, err := a.repo.GetPatientById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if newPatient.NationalId == "" {
+		newPatient.NationalId = "please_change_" + oldPatient.PublicId
+	}
+
+	changes := oldPatient.Diff(newPatient)
+	if len(changes) == 0 {
+		return changes, nil
+	}
+
+	for i := range changes {
+		changes[i].PatientId = id
+		changes[i].AccountId = editorAccountId
+	}
+
+	err = a.repo.WithTx(func(tx Repository) error {
+		err := tx.UpdatePatient(id, newPatient)
+		if err != nil {
+			return err
+		}
+
+		err = tx.CreatePatientFieldChanges(changes)
+		if err != nil {
+			return err
+		}
+
+		if oldPatient.FirstName == newPatient.FirstName && oldPatient.LastName == newPatient.LastName {
+			return nil
+		}
+
+		patientAccount, err := tx.GetAccountByUsername(oldPatient.PublicId)
+		if err != nil {
+			// imported patients don't have accounts
+			return nil
+		}
+
+		return tx.UpdateAccountDisplayName(patientAccount.Id, newPatient.FirstName+" "+newPatient.LastName)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+func (a *App) ListPatientFieldChanges(patientId uint) ([]models.PatientFieldChange, error) {
+	return a.repo.ListPatientFieldChanges(patientId)
+}
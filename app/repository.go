@@ -6,6 +6,10 @@ import (
 )
 
 type Repository interface {
+	// WithTx runs fn using a Repository that's bound to a single transaction,
+	// where the transaction is committed when fn returns nil, and rolled back otherwise.
+	WithTx(fn func(tx Repository) error) error
+
 	GetAccount(id uint) (models.Account, error)
 	GetAccountByUsername(username string) (models.Account, error)
 	CreateAccount(account models.Account) (models.Account, error)
@@ -15,47 +19,134 @@ type Repository interface {
 	UpdateAccountDisplayName(id uint, name string) error
 	UpdateAccountPassword(id uint, password string) error
 	UpdateAccountUsername(id uint, username string) error
+	SetAccountDisabledAt(id uint, disabledAt *time.Time) error
 
 	CreateBloodTest(bt models.BloodTest) (models.BloodTest, error)
+	// DeleteBloodTest deletes the blood test with its fields and their versions.
 	DeleteBloodTest(id uint) error
 	GetBloodTest(id uint) (models.BloodTest, error)
 	UpdateBloodTest(id uint, bt models.BloodTest) (models.BloodTest, error)
 	ListAllBloodTests() ([]models.BloodTest, error)
+	GetBloodTestField(id uint) (models.BloodTestField, error)
+	CreateBloodTestField(field models.BloodTestField) (models.BloodTestField, error)
+	UpdateBloodTestField(field models.BloodTestField) error
+	CreateBloodTestFieldVersion(version models.BloodTestFieldVersion) error
+	// SetBloodTestArchivedAt archives the blood test at the given time, or unarchives it when it's nil.
+	SetBloodTestArchivedAt(id uint, archivedAt *time.Time) error
+	GetBloodTestUsage(id uint) (models.Usage, error)
 
 	CreateBloodTestResult(btResult models.BloodTestResult) (models.BloodTestResult, error)
 	ListPatientBloodTestResults(patientId uint) ([]models.BloodTestResult, error)
 	SetBloodTestResultPending(id uint, pending bool) error
 	CreateBloodTestResultFilledFields(filledFields []models.BloodTestFilledField) error
 	UpdateBloodTestResultCreatedAt(id uint, ts time.Time) error
+	// ListPatientBloodTestFieldPoints lists the field's values in the patient's non pending results that were created in [from, to),
+	// oldest first, zero times are ignored.
+	ListPatientBloodTestFieldPoints(patientId, fieldId uint, from, to time.Time) ([]models.BloodTestFieldPoint, error)
 
 	CreateVirus(virus models.Virus) (models.Virus, error)
 	DeleteVirus(id uint) error
 	ListAllViruses() ([]models.Virus, error)
 	ListVirusesForPatient(patientId uint) ([]models.Virus, error)
+	GetVirus(id uint) (models.Virus, error)
+	CreatePatientVirus(patientVirus models.PatientVirus) (models.PatientVirus, error)
+	// UpdatePatientVirus updates the patient's infection with the virus, which are identified by their ids.
+	UpdatePatientVirus(patientVirus models.PatientVirus) error
+	GetPatientVirus(patientId, virusId uint) (models.PatientVirus, error)
+	// ListPatientViruses lists the patient's infections with their viruses, latest detected first.
+	ListPatientViruses(patientId uint) ([]models.PatientVirus, error)
+	SetVirusArchivedAt(id uint, archivedAt *time.Time) error
+	GetVirusUsage(id uint) (models.Usage, error)
+
+	CreateRelative(relative models.Relative) (models.Relative, error)
+	UpdateRelative(relative models.Relative) error
+	GetRelative(id uint) (models.Relative, error)
+	DeleteRelative(id uint) error
+	ListRelativesByIds(ids []uint) ([]models.Relative, error)
+	CreatePatientRelationship(relationship models.PatientRelationship) (models.PatientRelationship, error)
+	GetPatientRelationship(id uint) (models.PatientRelationship, error)
+	DeletePatientRelationship(id uint) error
+	// ListRelationshipsOf lists the relationships of the patients, either as the patient or as the relative, and of the non patient relatives.
+	ListRelationshipsOf(patientIds, relativeIds []uint) ([]models.PatientRelationship, error)
+	ListPatientsByIds(ids []uint) ([]models.Patient, error)
+	// ListPatientsWithLastName lists the patients that have the last name, which are the candidates of the patient's family.
+	ListPatientsWithLastName(lastName string) ([]models.Patient, error)
 
 	CreateMedicine(medicine models.Medicine) (models.Medicine, error)
 	DeleteMedicine(id uint) error
 	ListAllMedicines() ([]models.Medicine, error)
 	ListMedicinesByIds(ids []uint) ([]models.Medicine, error)
-	UpdateMedicineAmount(id uint, newAmount int) error
-	DecrementMedicineAmount(id uint, amount int) error
+	SetMedicineArchivedAt(id uint, archivedAt *time.Time) error
+	GetMedicineUsage(id uint) (models.Usage, error)
+	// ListMedicineBatchesInStock lists the product's non archived batches with packages left, soonest to expire first.
+	ListMedicineBatchesInStock(productId uint) ([]models.Medicine, error)
+	// ListMedicinesInStockExpiringBefore lists the non archived medicines with packages left that expire before the given time, soonest to expire first.
+	ListMedicinesInStockExpiringBefore(before time.Time) ([]models.Medicine, error)
 	GetMedicine(id uint) (models.Medicine, error)
+	// GetMedicineForUpdate gets the medicine and locks it until the transaction ends, it must be called in a transaction.
+	GetMedicineForUpdate(id uint) (models.Medicine, error)
+	// FindOrCreateMedicineProduct finds a product by its name, dose, unit, manufacturer and factor type, or creates it.
+	FindOrCreateMedicineProduct(product models.MedicineProduct) (models.MedicineProduct, error)
+	GetMedicineProduct(id uint) (models.MedicineProduct, error)
+	ListAllMedicineProducts() ([]models.MedicineProduct, error)
+	UpdateMedicineProductMinStock(id uint, minStock int) error
+	// ListMedicineProductsDispensations sums the dispensed packages of each product since the given time.
+	ListMedicineProductsDispensations(since time.Time) ([]models.MedicineProductDispensation, error)
+
+	CreateAlert(alert models.Alert) (models.Alert, error)
+	ListAlerts(filter models.AlertFilter) ([]models.Alert, int64, error)
+	// CreateStockMovement applies the movement's quantity to its medicine's amount and records it,
+	// it fails with ErrInsufficientMedicine when the amount would go below zero.
+	CreateStockMovement(movement models.StockMovement) (models.StockMovement, error)
+	ListMedicineStockMovements(filter models.StockMovementFilter) ([]models.StockMovement, int64, error)
+	GetMedicineLedgerAmount(medicineId uint) (int, error)
 
 	CreatePatient(patient models.Patient) (models.Patient, error)
 	GetPatientById(id uint) (models.Patient, error)
 	GetPatientByPublicId(publicId string) (models.Patient, error)
-	FindPatientsByVisitDateRange(from, to time.Time) ([]models.Patient, error)
+	FindPatientsByVisitDateRange(filter models.VisitDateRangeFilter) ([]models.PatientVisitsSummary, int64, error)
 	FindPatientsByFields(patientIndexFields models.PatientIndexFields) ([]models.Patient, error)
-	ListLastPatients(limit int) ([]models.Patient, error)
+	// ListPatients lists a page of the patients that match the filter, with the count of all of the matched patients.
+	ListPatients(filter models.PatientListFilter) ([]models.Patient, int64, error)
+	// ListPatientIdsByIdentifiers lists the ids of the patients whose public id or national id is one of the terms,
+	// or whose phone number ends with the phone key of a term, see models.PhoneKey.
+	ListPatientIdsByIdentifiers(terms []models.PatientSearchTerm) ([]uint, error)
 	DeletePatient(id uint) error
+	// ListAllPatients lists every patient's identifying fields, without their addresses.
+	ListAllPatients() ([]models.Patient, error)
+	// ListDuplicateCandidatePatients lists the identifying fields of the patients that share their phone number's suffix,
+	// or their first and last names' skeletons with another patient, without their addresses.
+	ListDuplicateCandidatePatients() ([]models.Patient, error)
+	// MovePatientRecords moves every record of the patient with fromId to the patient with toId,
+	// where infections with viruses that both patients have are merged with models.MergeInfections.
+	MovePatientRecords(fromId, toId uint) error
+	CreatePatientAlias(alias models.PatientAlias) (models.PatientAlias, error)
+	GetPatientAliasByPublicId(publicId string) (models.PatientAlias, error)
+	UpdatePatient(id uint, patient models.Patient) error
+	UpdatePatientHemophilia(id uint, classification models.HemophiliaClassification) error
+	UpdatePatientInhibitor(id uint, classification models.InhibitorClassification) error
+	ListPatientsWithInhibitors() ([]models.Patient, error)
+	CreatePatientFieldChanges(changes []models.PatientFieldChange) error
+	ListPatientFieldChanges(patientId uint) ([]models.PatientFieldChange, error)
 
 	CreatePatientVisit(visit models.Visit) (models.Visit, error)
 	ListPatientVisits(patientId uint) ([]models.Visit, error)
 	GetPatientVisit(visitId uint) (models.Visit, error)
 	CreatePrescribedMedicine(pm models.PrescribedMedicine) (models.PrescribedMedicine, error)
+	CreatePrescription(prescription models.Prescription) (models.Prescription, error)
+	ListVisitPrescriptions(visitId uint) ([]models.Prescription, error)
+	// ListPatientPrescriptions lists the patient's prescriptions created in [from, to) with their visits, oldest first,
+	// zero times are ignored.
+	ListPatientPrescriptions(patientId uint, from, to time.Time) ([]models.Prescription, error)
 	GetPatientLastVisit(patientId uint) (models.Visit, error)
 	ListPatientVisitPrescribedMedicine(visitId uint) ([]models.PrescribedMedicine, error)
 	UseMedicineForVisit(prescribedMedicineId, visitId uint) error
+	ListPrescribedMedicinesByIds(ids []uint) ([]models.PrescribedMedicine, error)
+
+	CreateBleedEpisode(episode models.BleedEpisode) (models.BleedEpisode, error)
+	// ListPatientBleedEpisodes lists the patient's bleed episodes that occurred in [from, to) with their infusions, latest first,
+	// zero times are ignored.
+	ListPatientBleedEpisodes(patientId uint, from, to time.Time) ([]models.BleedEpisode, error)
 
 	CreateAddress(address models.Address) (models.Address, error)
 	GetAllAddresses() ([]models.Address, error)
@@ -63,12 +154,26 @@ type Repository interface {
 	DeleteAddress(id uint) error
 
 	CreateJointEvaluation(je models.JointsEvaluation) (models.JointsEvaluation, error)
+	// ListJointEvaluationsForPatient lists the patient's evaluations with their HJHS joints, newest first.
 	ListJointEvaluationsForPatient(patientId uint) ([]models.JointsEvaluation, error)
 
 	CreateDiagnosis(d models.Diagnosis) (models.Diagnosis, error)
 	DeleteDiagnisis(id uint) error
+	GetDiagnosis(id uint) (models.Diagnosis, error)
 	ListAllDiagnoses() ([]models.Diagnosis, error)
+	SetDiagnosisArchivedAt(id uint, archivedAt *time.Time) error
+	GetDiagnosisUsage(id uint) (models.Usage, error)
 
 	CreateDiagnosisResult(dr models.DiagnosisResult) (models.DiagnosisResult, error)
+	// ListPatientDiagnosisResults lists all of the patient's results with their filled fields, including the revoked and replaced ones,
+	// oldest diagnosed first.
 	ListPatientDiagnosisResults(patientId uint) ([]models.DiagnosisResult, error)
+	GetDiagnosisResult(id uint) (models.DiagnosisResult, error)
+	// SetDiagnosisResultReplacedBy marks the result as replaced when it's still active, otherwise it fails with ErrInactiveDiagnosisResult.
+	SetDiagnosisResultReplacedBy(id, replacedById uint) error
+	// RevokeDiagnosisResult revokes the result when it's still active, otherwise it fails with ErrInactiveDiagnosisResult.
+	RevokeDiagnosisResult(id, revokedById uint, reason string) error
+
+	CreateAuditLog(auditLog models.AuditLog) (models.AuditLog, error)
+	ListAuditLogs(filter models.AuditLogFilter) ([]models.AuditLog, int64, error)
 }
@@ -1,15 +1,144 @@
 package app
 
-import "shs/app/models"
+import (
+	"fmt"
+	"shs/app/models"
+	"time"
+)
 
 func (a *App) CreateVirus(virus models.Virus) (models.Virus, error) {
 	return a.repo.CreateVirus(virus)
 }
 
+// DeleteVirus deletes the virus unless patients have it, since their records would lose it.
 func (a *App) DeleteVirus(id uint) error {
-	return a.repo.DeleteVirus(id)
+	return a.WithTx(func(txApp *App) error {
+		usage, err := txApp.repo.GetVirusUsage(id)
+		if err != nil {
+			return err
+		}
+		if usage.InUse() {
+			return &ErrInUse{
+				ResourceName: "virus",
+				Usage:        usage,
+			}
+		}
+
+		return txApp.repo.DeleteVirus(id)
+	})
+}
+
+// SetVirusArchived archives or unarchives the virus.
+func (a *App) SetVirusArchived(id uint, archived bool) error {
+	var archivedAt *time.Time
+	if archived {
+		now := time.Now().UTC()
+		archivedAt = &now
+	}
+
+	return a.repo.SetVirusArchivedAt(id, archivedAt)
 }
 
 func (a *App) ListAllViruses() ([]models.Virus, error) {
 	return a.repo.ListAllViruses()
 }
+
+func (a *App) GetVirus(id uint) (models.Virus, error) {
+	return a.repo.GetVirus(id)
+}
+
+// CreatePatientVirus records the patient's infection with the virus, with its status in effect from now.
+func (a *App) CreatePatientVirus(patientVirus models.PatientVirus) (models.PatientVirus, error) {
+	patientVirus.StatusChangedAt = time.Now().UTC()
+	return a.repo.CreatePatientVirus(patientVirus)
+}
+
+// UpdatePatientVirus updates the patient's infection with the virus,
+// where a changed status is in effect from now.
+func (a *App) UpdatePatientVirus(patientVirus models.PatientVirus) (models.PatientVirus, error) {
+	err := a.WithTx(func(txApp *App) error {
+		current, err := txApp.repo.GetPatientVirus(patientVirus.PatientId, patientVirus.VirusId)
+		if err != nil {
+			return err
+		}
+
+		patientVirus.StatusChangedAt = current.StatusChangedAt
+		if patientVirus.Status != current.Status {
+			patientVirus.StatusChangedAt = time.Now().UTC()
+		}
+
+		err = txApp.repo.UpdatePatientVirus(patientVirus)
+		if err != nil {
+			return err
+		}
+
+		patientVirus, err = txApp.repo.GetPatientVirus(patientVirus.PatientId, patientVirus.VirusId)
+		return err
+	})
+	if err != nil {
+		return models.PatientVirus{}, err
+	}
+
+	return patientVirus, nil
+}
+
+func (a *App) ListPatientViruses(patientId uint) ([]models.PatientVirus, error) {
+	return a.repo.ListPatientViruses(patientId)
+}
+
+// ListPatientInfectionSuggestions suggests changes to the patient's infections from their results of the viruses' identifying blood tests.
+func (a *App) ListPatientInfectionSuggestions(patientId uint) ([]models.InfectionSuggestion, error) {
+	viruses, err := a.repo.ListAllViruses()
+	if err != nil {
+		return nil, err
+	}
+
+	infections, err := a.repo.ListPatientViruses(patientId)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := a.repo.ListPatientBloodTestResults(patientId)
+	if err != nil {
+		return nil, err
+	}
+
+	bloodTests, err := a.repo.ListAllBloodTests()
+	if err != nil {
+		return nil, err
+	}
+
+	return models.SuggestInfections(viruses, infections, results, bloodTests), nil
+}
+
+// alertInfectionSuggestions raises an alert for every infection change that the given result suggests for the patient.
+func (a *App) alertInfectionSuggestions(patientId, btrId uint) error {
+	suggestions, err := a.ListPatientInfectionSuggestions(patientId)
+	if err != nil {
+		return err
+	}
+
+	var patient models.Patient
+	alerts := make([]models.Alert, 0)
+	for _, suggestion := range suggestions {
+		if suggestion.BloodTestResultId != btrId {
+			continue
+		}
+		if patient.Id == 0 {
+			patient, err = a.repo.GetPatientById(patientId)
+			if err != nil {
+				return err
+			}
+		}
+
+		alerts = append(alerts, models.Alert{
+			Type:      models.AlertTypeInfectionSuggested,
+			PatientId: patientId,
+			Message: fmt.Sprintf("Patient %s (%s %s) has a blood test result that suggests a %s %s infection",
+				patient.PublicId, patient.FirstName, patient.LastName, suggestion.Status, suggestion.Virus.Name),
+		})
+	}
+
+	_, err = a.CreateAlerts(alerts)
+	return err
+}
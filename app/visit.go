@@ -1,6 +1,9 @@
 package app
 
-import "shs/app/models"
+import (
+	"shs/app/models"
+	"time"
+)
 
 func (a *App) CreatePatientVisit(visit models.Visit) (models.Visit, error) {
 	return a.repo.CreatePatientVisit(visit)
@@ -9,3 +12,15 @@ func (a *App) CreatePatientVisit(visit models.Visit) (models.Visit, error) {
 func (a *App) CreatePrescribedMedicine(pm models.PrescribedMedicine) (models.PrescribedMedicine, error) {
 	return a.repo.CreatePrescribedMedicine(pm)
 }
+
+func (a *App) CreatePrescription(prescription models.Prescription) (models.Prescription, error) {
+	return a.repo.CreatePrescription(prescription)
+}
+
+func (a *App) ListVisitPrescriptions(visitId uint) ([]models.Prescription, error) {
+	return a.repo.ListVisitPrescriptions(visitId)
+}
+
+func (a *App) ListPatientPrescriptions(patientId uint, from, to time.Time) ([]models.Prescription, error) {
+	return a.repo.ListPatientPrescriptions(patientId, from, to)
+}
@@ -7,13 +7,16 @@ import (
 	"shs/app"
 	"shs/config"
 	"shs/handlers/apis"
+	"shs/handlers/middlewares/audit"
 	"shs/handlers/middlewares/auth"
 	"shs/handlers/middlewares/contenttype"
 	"shs/handlers/middlewares/logger"
 	"shs/jwt"
 	"shs/log"
+	"shs/lognotifier"
 	"shs/mariadb"
 	"shs/redis"
+	"time"
 
 	"github.com/tdewolff/minify/v2"
 	"github.com/tdewolff/minify/v2/json"
@@ -25,7 +28,7 @@ func main() {
 		log.Fatalln(err)
 	}
 	cache := redis.New()
-	app := app.New(repo, cache)
+	app := app.New(repo, cache, lognotifier.New())
 	jwtUtil := jwt.New[actions.TokenPayload]()
 	usecases := actions.New(
 		app,
@@ -33,6 +36,7 @@ func main() {
 		jwtUtil,
 	)
 	authMiddleware := auth.New(usecases)
+	auditMiddleware := audit.New(usecases)
 	minifyer := minify.New()
 	minifyer.AddFuncRegexp(regexp.MustCompile("[/+]json$"), json.Minify)
 
@@ -45,6 +49,8 @@ func main() {
 	addressApi := apis.NewAddressApi(usecases)
 	patientApi := apis.NewPatientApi(usecases)
 	diagnosisApi := apis.NewDiagnosisApi(usecases)
+	auditApi := apis.NewAuditApi(usecases)
+	alertApi := apis.NewAlertApi(usecases)
 
 	v1ApisHandler := http.NewServeMux()
 	v1ApisHandler.HandleFunc("POST /login/username", emailLoginApi.HandleUsernameLogin)
@@ -53,58 +59,111 @@ func main() {
 	v1ApisHandler.HandleFunc("GET /me/logout", authMiddleware.AuthApi(meApi.HandleLogout))
 
 	v1ApisHandler.HandleFunc("GET /accounts/{id}", authMiddleware.AuthApi(accountApi.HandleGetAccount))
-	v1ApisHandler.HandleFunc("DELETE /accounts/{id}", authMiddleware.AuthApi(accountApi.HandleDeleteAccount))
-	v1ApisHandler.HandleFunc("PUT /accounts/{id}", authMiddleware.AuthApi(accountApi.HandleUpdateAccount))
-	v1ApisHandler.HandleFunc("POST /accounts/admin", authMiddleware.AuthApi(accountApi.HandleCreateAdminAccount))
-	v1ApisHandler.HandleFunc("POST /accounts/secritary", authMiddleware.AuthApi(accountApi.HandleCreateSecritaryAccount))
-	v1ApisHandler.HandleFunc("POST /accounts/jointlogist", authMiddleware.AuthApi(accountApi.HandleCreateJointlogistAccount))
+	v1ApisHandler.HandleFunc("DELETE /accounts/{id}", authMiddleware.AuthApi(auditMiddleware.AuditApi(accountApi.HandleDeleteAccount)))
+	v1ApisHandler.HandleFunc("PUT /accounts/{id}", authMiddleware.AuthApi(auditMiddleware.AuditApi(accountApi.HandleUpdateAccount)))
+	v1ApisHandler.HandleFunc("POST /accounts/admin", authMiddleware.AuthApi(auditMiddleware.AuditApi(accountApi.HandleCreateAdminAccount)))
+	v1ApisHandler.HandleFunc("POST /accounts/secritary", authMiddleware.AuthApi(auditMiddleware.AuditApi(accountApi.HandleCreateSecritaryAccount)))
+	v1ApisHandler.HandleFunc("POST /accounts/jointlogist", authMiddleware.AuthApi(auditMiddleware.AuditApi(accountApi.HandleCreateJointlogistAccount)))
 	v1ApisHandler.HandleFunc("GET /accounts", authMiddleware.AuthApi(accountApi.HandleListAllAccounts))
 
-	v1ApisHandler.HandleFunc("POST /bloodtests", authMiddleware.AuthApi(bloodTestApi.HandleCreateBloodTest))
+	v1ApisHandler.HandleFunc("POST /bloodtests", authMiddleware.AuthApi(auditMiddleware.AuditApi(bloodTestApi.HandleCreateBloodTest)))
 	v1ApisHandler.HandleFunc("GET /bloodtests/{id}", authMiddleware.AuthApi(bloodTestApi.HandleGetBloodTest))
 	v1ApisHandler.HandleFunc("GET /bloodtests", authMiddleware.AuthApi(bloodTestApi.HandleListBloodTests))
-	v1ApisHandler.HandleFunc("DELETE /bloodtests/{id}", authMiddleware.AuthApi(bloodTestApi.HandleDeleteBloodTest))
+	v1ApisHandler.HandleFunc("PUT /bloodtests/{id}", authMiddleware.AuthApi(auditMiddleware.AuditApi(bloodTestApi.HandleUpdateBloodTest)))
+	v1ApisHandler.HandleFunc("DELETE /bloodtests/{id}", authMiddleware.AuthApi(auditMiddleware.AuditApi(bloodTestApi.HandleDeleteBloodTest)))
+	v1ApisHandler.HandleFunc("POST /bloodtests/{id}/archive", authMiddleware.AuthApi(auditMiddleware.AuditApi(bloodTestApi.HandleSetBloodTestArchived)))
+	v1ApisHandler.HandleFunc("DELETE /bloodtests/{id}/archive", authMiddleware.AuthApi(auditMiddleware.AuditApi(bloodTestApi.HandleSetBloodTestArchived)))
 
-	v1ApisHandler.HandleFunc("POST /diagnoses", authMiddleware.AuthApi(diagnosisApi.HandleCreateDiagnosis))
+	v1ApisHandler.HandleFunc("POST /diagnoses", authMiddleware.AuthApi(auditMiddleware.AuditApi(diagnosisApi.HandleCreateDiagnosis)))
 	v1ApisHandler.HandleFunc("GET /diagnoses", authMiddleware.AuthApi(diagnosisApi.HandleListDiagnosiss))
-	v1ApisHandler.HandleFunc("DELETE /diagnoses/{id}", authMiddleware.AuthApi(diagnosisApi.HandleDeleteDiagnosis))
+	v1ApisHandler.HandleFunc("DELETE /diagnoses/{id}", authMiddleware.AuthApi(auditMiddleware.AuditApi(diagnosisApi.HandleDeleteDiagnosis)))
+	v1ApisHandler.HandleFunc("POST /diagnoses/{id}/archive", authMiddleware.AuthApi(auditMiddleware.AuditApi(diagnosisApi.HandleSetDiagnosisArchived)))
+	v1ApisHandler.HandleFunc("DELETE /diagnoses/{id}/archive", authMiddleware.AuthApi(auditMiddleware.AuditApi(diagnosisApi.HandleSetDiagnosisArchived)))
 
-	v1ApisHandler.HandleFunc("POST /viruses", authMiddleware.AuthApi(virusApi.HandleCreateVirus))
+	v1ApisHandler.HandleFunc("POST /viruses", authMiddleware.AuthApi(auditMiddleware.AuditApi(virusApi.HandleCreateVirus)))
 	v1ApisHandler.HandleFunc("GET /viruses", authMiddleware.AuthApi(virusApi.HandleListViruses))
-	v1ApisHandler.HandleFunc("DELETE /viruses/{id}", authMiddleware.AuthApi(virusApi.HandleDeleteVirus))
+	v1ApisHandler.HandleFunc("DELETE /viruses/{id}", authMiddleware.AuthApi(auditMiddleware.AuditApi(virusApi.HandleDeleteVirus)))
+	v1ApisHandler.HandleFunc("POST /viruses/{id}/archive", authMiddleware.AuthApi(auditMiddleware.AuditApi(virusApi.HandleSetVirusArchived)))
+	v1ApisHandler.HandleFunc("DELETE /viruses/{id}/archive", authMiddleware.AuthApi(auditMiddleware.AuditApi(virusApi.HandleSetVirusArchived)))
 
-	v1ApisHandler.HandleFunc("POST /medicines", authMiddleware.AuthApi(medicineApi.HandleCreateMedicine))
+	v1ApisHandler.HandleFunc("POST /medicines", authMiddleware.AuthApi(auditMiddleware.AuditApi(medicineApi.HandleCreateMedicine)))
 	v1ApisHandler.HandleFunc("GET /medicines", authMiddleware.AuthApi(medicineApi.HandleListMedicines))
+	v1ApisHandler.HandleFunc("GET /medicines/expiring", authMiddleware.AuthApi(medicineApi.HandleListExpiringMedicines))
 	v1ApisHandler.HandleFunc("GET /medicines/{id}", authMiddleware.AuthApi(medicineApi.HandleGetMedicine))
-	v1ApisHandler.HandleFunc("PUT /medicines/{id}/amount", authMiddleware.AuthApi(medicineApi.HandleUpdateMedicineAmount))
-	v1ApisHandler.HandleFunc("DELETE /medicines/{id}", authMiddleware.AuthApi(medicineApi.HandleDeleteMedicine))
+	v1ApisHandler.HandleFunc("PUT /medicines/{id}/amount", authMiddleware.AuthApi(auditMiddleware.AuditApi(medicineApi.HandleUpdateMedicineAmount)))
+	v1ApisHandler.HandleFunc("POST /medicines/{id}/movements", authMiddleware.AuthApi(auditMiddleware.AuditApi(medicineApi.HandleCreateStockMovement)))
+	v1ApisHandler.HandleFunc("GET /medicines/{id}/movements", authMiddleware.AuthApi(medicineApi.HandleListMedicineStockMovements))
+	v1ApisHandler.HandleFunc("DELETE /medicines/{id}", authMiddleware.AuthApi(auditMiddleware.AuditApi(medicineApi.HandleDeleteMedicine)))
+	v1ApisHandler.HandleFunc("POST /medicines/{id}/archive", authMiddleware.AuthApi(auditMiddleware.AuditApi(medicineApi.HandleSetMedicineArchived)))
+	v1ApisHandler.HandleFunc("DELETE /medicines/{id}/archive", authMiddleware.AuthApi(auditMiddleware.AuditApi(medicineApi.HandleSetMedicineArchived)))
+	v1ApisHandler.HandleFunc("GET /medicine-products", authMiddleware.AuthApi(medicineApi.HandleListMedicineProducts))
+	v1ApisHandler.HandleFunc("GET /medicine-products/projections", authMiddleware.AuthApi(medicineApi.HandleListMedicineProductProjections))
+	v1ApisHandler.HandleFunc("GET /medicine-products/{id}", authMiddleware.AuthApi(medicineApi.HandleGetMedicineProduct))
+	v1ApisHandler.HandleFunc("PUT /medicine-products/{id}/min-stock", authMiddleware.AuthApi(auditMiddleware.AuditApi(medicineApi.HandleUpdateMedicineProductMinStock)))
 
 	v1ApisHandler.HandleFunc(
 		"GET /addresses/goveronate/{goveronate}/suburb/{suburb}/street/{street}",
 		authMiddleware.AuthApi(addressApi.HandleFindAddress))
 
-	v1ApisHandler.HandleFunc("POST /patients", authMiddleware.AuthApi(patientApi.HandleCreatePatient))
+	v1ApisHandler.HandleFunc("POST /patients", authMiddleware.AuthApi(auditMiddleware.AuditApi(patientApi.HandleCreatePatient)))
 	v1ApisHandler.HandleFunc("GET /patients/{id}/card", authMiddleware.AuthApi(patientApi.HandleGenerateCard))
-	v1ApisHandler.HandleFunc("DELETE /patients/{id}", authMiddleware.AuthApi(patientApi.HandleDeletePatient))
+	v1ApisHandler.HandleFunc("DELETE /patients/{id}", authMiddleware.AuthApi(auditMiddleware.AuditApi(patientApi.HandleDeletePatient)))
 	v1ApisHandler.HandleFunc("GET /patients/{id}", authMiddleware.AuthApi(patientApi.HandleGetPatient))
-	v1ApisHandler.HandleFunc("GET /patients/last", authMiddleware.AuthApi(patientApi.HandleListLastPatients))
-	v1ApisHandler.HandleFunc(
-		"GET /patients/public-id/{public_id}/first-name/{first_name}/last-name/{last_name}/father-name/{father_name}/mother-name/{mother_name}/national-id/{national_id}/phone-number/{phone_number}",
-		authMiddleware.AuthApi(patientApi.HandleFindPatients))
-	v1ApisHandler.HandleFunc("POST /patients/import/csv", authMiddleware.AuthApi(patientApi.HandleImportPatientsFromCsv))
-
-	v1ApisHandler.HandleFunc("POST /patients/bloodtest", authMiddleware.AuthApi(patientApi.HandleCreatePatientBloodTestResult))
-	v1ApisHandler.HandleFunc("PUT /patients/{id}/bloodtest/{btr_id}/pending", authMiddleware.AuthApi(patientApi.HandleUpdatePendingBloodTestResult))
-	v1ApisHandler.HandleFunc("POST /patients/{id}/checkup", authMiddleware.AuthApi(patientApi.HandleCheckUp))
-	v1ApisHandler.HandleFunc("POST /patients/diagnosis", authMiddleware.AuthApi(patientApi.HandleCreatePatientDiagnosisResult))
-	v1ApisHandler.HandleFunc("POST /patients/{id}/joints-evaluation", authMiddleware.AuthApi(patientApi.HandleCreatePatientJointsEvaluation))
+	// PUT and PATCH are the same partial update, where the fields that aren't sent keep their current values.
+	v1ApisHandler.HandleFunc("PUT /patients/{id}", authMiddleware.AuthApi(auditMiddleware.AuditApi(patientApi.HandleUpdatePatient)))
+	v1ApisHandler.HandleFunc("PATCH /patients/{id}", authMiddleware.AuthApi(auditMiddleware.AuditApi(patientApi.HandleUpdatePatient)))
+	v1ApisHandler.HandleFunc("GET /patients/{id}/history", authMiddleware.AuthApi(patientApi.HandleListPatientFieldChanges))
+	v1ApisHandler.HandleFunc("GET /patients", authMiddleware.AuthApi(patientApi.HandleListPatients))
+	v1ApisHandler.HandleFunc("GET /patients/visited", authMiddleware.AuthApi(patientApi.HandleListVisitedPatients))
+	v1ApisHandler.HandleFunc("GET /patients/inhibitors", authMiddleware.AuthApi(patientApi.HandleListPatientsWithInhibitors))
+	v1ApisHandler.HandleFunc("GET /patients/duplicates", authMiddleware.AuthApi(patientApi.HandleListDuplicatePatients))
+	v1ApisHandler.HandleFunc("GET /patients/{id}/duplicates", authMiddleware.AuthApi(patientApi.HandleListPatientDuplicates))
+	v1ApisHandler.HandleFunc("POST /patients/{id}/merge", authMiddleware.AuthApi(auditMiddleware.AuditApi(patientApi.HandleMergePatients)))
+	v1ApisHandler.HandleFunc("POST /patients/import/csv", authMiddleware.AuthApi(auditMiddleware.AuditApi(patientApi.HandleImportPatientsFromCsv)))
+
+	v1ApisHandler.HandleFunc("POST /patients/bloodtest", authMiddleware.AuthApi(auditMiddleware.AuditApi(patientApi.HandleCreatePatientBloodTestResult)))
+	v1ApisHandler.HandleFunc("PUT /patients/{id}/bloodtest/{btr_id}/pending", authMiddleware.AuthApi(auditMiddleware.AuditApi(patientApi.HandleUpdatePendingBloodTestResult)))
+	v1ApisHandler.HandleFunc("GET /patients/{id}/bloodtests/fields/{field_id}/series", authMiddleware.AuthApi(patientApi.HandleGetPatientBloodTestFieldSeries))
+	v1ApisHandler.HandleFunc("POST /patients/{id}/checkup", authMiddleware.AuthApi(auditMiddleware.AuditApi(patientApi.HandleCheckUp)))
+	v1ApisHandler.HandleFunc("POST /patients/diagnosis", authMiddleware.AuthApi(auditMiddleware.AuditApi(patientApi.HandleCreatePatientDiagnosisResult)))
+	v1ApisHandler.HandleFunc("GET /patients/{id}/diagnoses", authMiddleware.AuthApi(patientApi.HandleListPatientDiagnosisResults))
+	v1ApisHandler.HandleFunc("PUT /patients/{id}/diagnoses/{result_id}", authMiddleware.AuthApi(auditMiddleware.AuditApi(patientApi.HandleUpdatePatientDiagnosisResult)))
+	v1ApisHandler.HandleFunc("DELETE /patients/{id}/diagnoses/{result_id}", authMiddleware.AuthApi(auditMiddleware.AuditApi(patientApi.HandleRevokePatientDiagnosisResult)))
+	v1ApisHandler.HandleFunc("POST /patients/{id}/joints-evaluation", authMiddleware.AuthApi(auditMiddleware.AuditApi(patientApi.HandleCreatePatientJointsEvaluation)))
 	v1ApisHandler.HandleFunc("GET /patients/{id}/joints-evaluations", authMiddleware.AuthApi(patientApi.HandleListPatientJointsEvaluations))
+	v1ApisHandler.HandleFunc("GET /patients/{id}/joints-evaluations/trend", authMiddleware.AuthApi(patientApi.HandleGetPatientJointsTrend))
 	v1ApisHandler.HandleFunc("GET /patients/{id}/visits", authMiddleware.AuthApi(patientApi.HandleListPatientVisits))
+	v1ApisHandler.HandleFunc("GET /patients/{id}/consumption", authMiddleware.AuthApi(patientApi.HandleGetPatientFactorConsumption))
+	v1ApisHandler.HandleFunc("GET /patients/{id}/bleeds", authMiddleware.AuthApi(patientApi.HandleListPatientBleedEpisodes))
+	v1ApisHandler.HandleFunc("GET /patients/{id}/analytics", authMiddleware.AuthApi(patientApi.HandleGetPatientAnalytics))
+	v1ApisHandler.HandleFunc("GET /patients/{id}/inhibitors", authMiddleware.AuthApi(patientApi.HandleListPatientInhibitorTiters))
+	v1ApisHandler.HandleFunc("GET /patients/{id}/viruses", authMiddleware.AuthApi(patientApi.HandleListPatientViruses))
+	v1ApisHandler.HandleFunc("POST /patients/{id}/viruses", authMiddleware.AuthApi(auditMiddleware.AuditApi(patientApi.HandleCreatePatientVirus)))
+	v1ApisHandler.HandleFunc("PUT /patients/{id}/viruses/{virus_id}", authMiddleware.AuthApi(auditMiddleware.AuditApi(patientApi.HandleUpdatePatientVirus)))
+	v1ApisHandler.HandleFunc("GET /patients/{id}/viruses/suggestions", authMiddleware.AuthApi(patientApi.HandleListPatientInfectionSuggestions))
+	v1ApisHandler.HandleFunc("GET /patients/{id}/pedigree", authMiddleware.AuthApi(patientApi.HandleGetPatientPedigree))
+	v1ApisHandler.HandleFunc("POST /patients/{id}/relationships", authMiddleware.AuthApi(auditMiddleware.AuditApi(patientApi.HandleCreatePatientRelationship)))
+	v1ApisHandler.HandleFunc("DELETE /patients/{id}/relationships/{relationship_id}", authMiddleware.AuthApi(auditMiddleware.AuditApi(patientApi.HandleDeletePatientRelationship)))
+	v1ApisHandler.HandleFunc("GET /patients/{id}/relationships/proposals", authMiddleware.AuthApi(patientApi.HandleListPatientRelationshipProposals))
+	v1ApisHandler.HandleFunc("PUT /relatives/{id}", authMiddleware.AuthApi(auditMiddleware.AuditApi(patientApi.HandleUpdateRelative)))
 
 	// TODO: separate this from admin patient endpoints
-	v1ApisHandler.HandleFunc("POST /patients/visit/{visit_id}/medicine/{med_id}", authMiddleware.AuthApi(patientApi.HandleUsePrescribedMedicineForVisit))
+	v1ApisHandler.HandleFunc("POST /patients/visit/{visit_id}/medicine/{med_id}", authMiddleware.AuthApi(auditMiddleware.AuditApi(patientApi.HandleUsePrescribedMedicineForVisit)))
 
 	v1ApisHandler.HandleFunc("GET /me/patient/last-visit", authMiddleware.AuthApi(patientApi.HandleGetPatientLastVisit))
+	v1ApisHandler.HandleFunc("POST /me/bleeds", authMiddleware.AuthApi(auditMiddleware.AuditApi(patientApi.HandleCreateMyBleedEpisode)))
+	v1ApisHandler.HandleFunc("GET /me/bleeds", authMiddleware.AuthApi(patientApi.HandleListMyBleedEpisodes))
+
+	v1ApisHandler.HandleFunc("GET /audit", authMiddleware.AuthApi(auditApi.HandleListAuditLogs))
+
+	v1ApisHandler.HandleFunc("GET /alerts", authMiddleware.AuthApi(alertApi.HandleListAlerts))
+
+	go runDaily(func() {
+		_, err := usecases.SendDailyStockSummary(actions.SendDailyStockSummaryParams{})
+		if err != nil {
+			log.Errorf("Failed to send daily stock summary, error: %s\n", err.Error())
+		}
+	})
 
 	if config.Env().GoEnv == config.GoEnvTest || config.Env().GoEnv == config.GoEnvDev {
 		v1ApisHandler.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
@@ -150,3 +209,13 @@ func main() {
 		log.Fatalln(http.ListenAndServe(":"+config.Env().Port, minifyer.Middleware(applicationHandler)))
 	}
 }
+
+// runDaily runs job every day at midnight UTC.
+func runDaily(job func()) {
+	for {
+		now := time.Now().UTC()
+		nextMidnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+		time.Sleep(nextMidnight.Sub(now))
+		job()
+	}
+}
@@ -0,0 +1,80 @@
+package apis
+
+import (
+	"encoding/json"
+	"net/http"
+	"shs/actions"
+	"shs/log"
+)
+
+type alertApi struct {
+	usecases *actions.Actions
+}
+
+func NewAlertApi(usecases *actions.Actions) *alertApi {
+	return &alertApi{
+		usecases: usecases,
+	}
+}
+
+func (e *alertApi) HandleListAlerts(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+
+	productId, err := queryInt(query, "product_id")
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	from, _, err := queryTime(query, "from")
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	to, toDateOnly, err := queryTime(query, "to")
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+	if toDateOnly {
+		to = to.AddDate(0, 0, 1)
+	}
+
+	page, err := queryInt(query, "page")
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	pageSize, err := queryInt(query, "page_size")
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	params := actions.ListAlertsParams{
+		ActionContext: ctx,
+		Type:          query.Get("type"),
+		ProductId:     uint(productId),
+		From:          from,
+		To:            to,
+		Page:          page,
+		PageSize:      pageSize,
+	}
+
+	payload, err := e.usecases.ListAlerts(params)
+	if err != nil {
+		log.Errorf("[ALERT API]: Failed to list alerts: %+v, error: %s\n", params, err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
@@ -0,0 +1,73 @@
+package apis
+
+import (
+	"encoding/json"
+	"net/http"
+	"shs/actions"
+	"shs/log"
+)
+
+type auditApi struct {
+	usecases *actions.Actions
+}
+
+func NewAuditApi(usecases *actions.Actions) *auditApi {
+	return &auditApi{
+		usecases: usecases,
+	}
+}
+
+func (e *auditApi) HandleListAuditLogs(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+
+	accountId, err := queryInt(query, "account_id")
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	from, to, err := queryTimeRange(query)
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	page, err := queryInt(query, "page")
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	pageSize, err := queryInt(query, "page_size")
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	params := actions.ListAuditLogsParams{
+		ActionContext: ctx,
+		AccountId:     uint(accountId),
+		Action:        query.Get("action"),
+		ResourceType:  query.Get("resource_type"),
+		ResourceId:    query.Get("resource_id"),
+		From:          from,
+		To:            to,
+		Page:          page,
+		PageSize:      pageSize,
+	}
+
+	payload, err := e.usecases.ListAuditLogs(params)
+	if err != nil {
+		log.Errorf("[AUDIT API]: Failed to list audit logs: %+v, error: %s\n", params, err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
@@ -44,6 +44,40 @@ func (e *bloodTestApi) HandleCreateBloodTest(w http.ResponseWriter, r *http.Requ
 	_ = json.NewEncoder(w).Encode(payload)
 }
 
+func (e *bloodTestApi) HandleUpdateBloodTest(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	var params actions.UpdateBloodTestParams
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	payload, err := e.usecases.UpdateBloodTest(actions.UpdateBloodTestParams{
+		ActionContext: ctx,
+		BloodTestId:   uint(id),
+		NewBloodTest:  params.NewBloodTest,
+	})
+	if err != nil {
+		log.Errorf("[BLOODTEST API]: Failed to update blood test, error: %s\n", err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
 func (e *bloodTestApi) HandleGetBloodTest(w http.ResponseWriter, r *http.Request) {
 	ctx, err := parseContext(r.Context())
 	if err != nil {
@@ -77,8 +111,15 @@ func (e *bloodTestApi) HandleListBloodTests(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	includeArchived, err := queryBool(r.URL.Query(), "include_archived")
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
 	payload, err := e.usecases.ListAllBloodTests(actions.ListAllBloodTestsParams{
-		ActionContext: ctx,
+		ActionContext:   ctx,
+		IncludeArchived: includeArchived,
 	})
 	if err != nil {
 		log.Errorf("[BLOODTEST API]: Failed to get blood tests, error: %s\n", err.Error())
@@ -114,3 +155,31 @@ func (e *bloodTestApi) HandleDeleteBloodTest(w http.ResponseWriter, r *http.Requ
 
 	_ = json.NewEncoder(w).Encode(payload)
 }
+
+// HandleSetBloodTestArchived archives the blood test on POST, and unarchives it on DELETE.
+func (e *bloodTestApi) HandleSetBloodTestArchived(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	payload, err := e.usecases.SetBloodTestArchived(actions.SetBloodTestArchivedParams{
+		ActionContext: ctx,
+		BloodTestId:   uint(id),
+		Archived:      r.Method != http.MethodDelete,
+	})
+	if err != nil {
+		log.Errorf("[BLOODTEST API]: Failed to set blood test archived, error: %s\n", err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
@@ -51,8 +51,15 @@ func (e *diagnosisApi) HandleListDiagnosiss(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	includeArchived, err := queryBool(r.URL.Query(), "include_archived")
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
 	payload, err := e.usecases.ListAllDiagnoses(actions.ListAllDiagnosesParams{
-		ActionContext: ctx,
+		ActionContext:   ctx,
+		IncludeArchived: includeArchived,
 	})
 	if err != nil {
 		log.Errorf("[DIAGNOSIS API]: Failed to get diagnoses, error: %s\n", err.Error())
@@ -88,3 +95,31 @@ func (e *diagnosisApi) HandleDeleteDiagnosis(w http.ResponseWriter, r *http.Requ
 
 	_ = json.NewEncoder(w).Encode(payload)
 }
+
+// HandleSetDiagnosisArchived archives the diagnosis on POST, and unarchives it on DELETE.
+func (e *diagnosisApi) HandleSetDiagnosisArchived(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	payload, err := e.usecases.SetDiagnosisArchived(actions.SetDiagnosisArchivedParams{
+		ActionContext: ctx,
+		DiagnosisId:   uint(id),
+		Archived:      r.Method != http.MethodDelete,
+	})
+	if err != nil {
+		log.Errorf("[DIAGNOSIS API]: Failed to set diagnosis archived, error: %s\n", err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
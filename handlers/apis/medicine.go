@@ -51,8 +51,15 @@ func (e *medicineApi) HandleListMedicines(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	includeArchived, err := queryBool(r.URL.Query(), "include_archived")
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
 	payload, err := e.usecases.ListAllMedicine(actions.ListAllMedicineParams{
-		ActionContext: ctx,
+		ActionContext:   ctx,
+		IncludeArchived: includeArchived,
 	})
 	if err != nil {
 		log.Errorf("[MEDICINE API]: Failed to get medicines, error: %s\n", err.Error())
@@ -147,3 +154,251 @@ func (e *medicineApi) HandleGetMedicine(w http.ResponseWriter, r *http.Request)
 
 	_ = json.NewEncoder(w).Encode(payload)
 }
+
+func (e *medicineApi) HandleCreateStockMovement(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	var params actions.CreateStockMovementParams
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		handleErrorResponse(w, err)
+		return
+	}
+	params.ActionContext = ctx
+	params.MedicineId = uint(id)
+
+	payload, err := e.usecases.CreateStockMovement(params)
+	if err != nil {
+		log.Errorf("[MEDICINE API]: Failed to create stock movement: %+v, error: %s\n", params, err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func (e *medicineApi) HandleListMedicineStockMovements(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+
+	from, _, err := queryTime(query, "from")
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	to, toDateOnly, err := queryTime(query, "to")
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+	if toDateOnly {
+		to = to.AddDate(0, 0, 1)
+	}
+
+	page, err := queryInt(query, "page")
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	pageSize, err := queryInt(query, "page_size")
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	params := actions.ListMedicineStockMovementsParams{
+		ActionContext: ctx,
+		MedicineId:    uint(id),
+		Type:          query.Get("type"),
+		From:          from,
+		To:            to,
+		Page:          page,
+		PageSize:      pageSize,
+	}
+
+	payload, err := e.usecases.ListMedicineStockMovements(params)
+	if err != nil {
+		log.Errorf("[MEDICINE API]: Failed to list medicine stock movements: %+v, error: %s\n", params, err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func (e *medicineApi) HandleListExpiringMedicines(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	days, err := queryInt(r.URL.Query(), "days")
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	params := actions.ListExpiringMedicinesParams{
+		ActionContext: ctx,
+		WithinDays:    days,
+	}
+
+	payload, err := e.usecases.ListExpiringMedicines(params)
+	if err != nil {
+		log.Errorf("[MEDICINE API]: Failed to list expiring medicines: %+v, error: %s\n", params, err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func (e *medicineApi) HandleListMedicineProducts(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	payload, err := e.usecases.ListAllMedicineProducts(actions.ListAllMedicineProductsParams{
+		ActionContext: ctx,
+	})
+	if err != nil {
+		log.Errorf("[MEDICINE API]: Failed to list medicine products, error: %s\n", err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func (e *medicineApi) HandleGetMedicineProduct(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	payload, err := e.usecases.GetMedicineProduct(actions.GetMedicineProductParams{
+		ActionContext: ctx,
+		ProductId:     uint(id),
+	})
+	if err != nil {
+		log.Errorf("[MEDICINE API]: Failed to get medicine product, error: %s\n", err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func (e *medicineApi) HandleUpdateMedicineProductMinStock(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	var params actions.UpdateMedicineProductMinStockParams
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		handleErrorResponse(w, err)
+		return
+	}
+	params.ActionContext = ctx
+	params.ProductId = uint(id)
+
+	payload, err := e.usecases.UpdateMedicineProductMinStock(params)
+	if err != nil {
+		log.Errorf("[MEDICINE API]: Failed to update medicine product min stock: %+v, error: %s\n", params, err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func (e *medicineApi) HandleListMedicineProductProjections(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	payload, err := e.usecases.ListMedicineProductProjections(actions.ListMedicineProductProjectionsParams{
+		ActionContext: ctx,
+	})
+	if err != nil {
+		log.Errorf("[MEDICINE API]: Failed to list medicine product projections, error: %s\n", err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+// HandleSetMedicineArchived archives the medicine on POST, and unarchives it on DELETE.
+func (e *medicineApi) HandleSetMedicineArchived(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	payload, err := e.usecases.SetMedicineArchived(actions.SetMedicineArchivedParams{
+		ActionContext: ctx,
+		MedicineId:    uint(id),
+		Archived:      r.Method != http.MethodDelete,
+	})
+	if err != nil {
+		log.Errorf("[MEDICINE API]: Failed to set medicine archived, error: %s\n", err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
@@ -96,45 +96,204 @@ func (e *patientApi) HandleCreatePatientDiagnosisResult(w http.ResponseWriter, r
 	_ = json.NewEncoder(w).Encode(payload)
 }
 
-func (e *patientApi) HandleListLastPatients(w http.ResponseWriter, r *http.Request) {
+func (e *patientApi) HandleListPatientDiagnosisResults(w http.ResponseWriter, r *http.Request) {
 	ctx, err := parseContext(r.Context())
 	if err != nil {
 		handleErrorResponse(w, err)
 		return
 	}
 
-	payload, err := e.usecases.ListLastPatients(actions.ListLastPatientsParams{
-		ActionContext: ctx,
-	})
+	params := actions.ListPatientDiagnosisResultsParams{
+		ActionContext:   ctx,
+		PatientPublicId: r.PathValue("id"),
+	}
+
+	payload, err := e.usecases.ListPatientDiagnosisResults(params)
+	if err != nil {
+		log.Errorf("[PATIENT API]: Failed to list patient's diagnosis results: %+v, error: %s\n", params, err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func (e *patientApi) HandleUpdatePatientDiagnosisResult(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	resultId, err := strconv.Atoi(r.PathValue("result_id"))
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	var reqBody actions.UpdatePatientDiagnosisResultParams
+	err = json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	reqBody.ActionContext = ctx
+	reqBody.PatientPublicId = r.PathValue("id")
+	reqBody.DiagnosisResultId = uint(resultId)
+	payload, err := e.usecases.UpdatePatientDiagnosisResult(reqBody)
+	if err != nil {
+		log.Errorf("[PATIENT API]: Failed to update patient's diagnosis result: %+v, error: %s\n", reqBody, err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func (e *patientApi) HandleRevokePatientDiagnosisResult(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	resultId, err := strconv.Atoi(r.PathValue("result_id"))
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	params := actions.RevokePatientDiagnosisResultParams{
+		ActionContext:     ctx,
+		PatientPublicId:   r.PathValue("id"),
+		DiagnosisResultId: uint(resultId),
+		Reason:            r.URL.Query().Get("reason"),
+	}
+	payload, err := e.usecases.RevokePatientDiagnosisResult(params)
+	if err != nil {
+		log.Errorf("[PATIENT API]: Failed to revoke patient's diagnosis result: %+v, error: %s\n", params, err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func (e *patientApi) HandleListPatients(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+
+	diagnosisId, err := queryInt(query, "diagnosis_id")
+	if err != nil || diagnosisId < 0 {
+		handleErrorResponse(w, ErrBadRequest{FieldName: "diagnosis_id"})
+		return
+	}
+
+	minAge, err := queryOptionalInt(query, "min_age")
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	maxAge, err := queryOptionalInt(query, "max_age")
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	createdFrom, createdTo, err := queryNamedTimeRange(query, "created_from", "created_to")
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	visitedFrom, visitedTo, err := queryNamedTimeRange(query, "visited_from", "visited_to")
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	limit, err := queryInt(query, "limit")
 	if err != nil {
 		handleErrorResponse(w, err)
 		return
 	}
 
+	params := actions.ListPatientsParams{
+		ActionContext:      ctx,
+		Query:              query.Get("q"),
+		Gender:             query.Get("gender"),
+		Governorate:        query.Get("governorate"),
+		DiagnosisId:        uint(diagnosisId),
+		HemophiliaType:     query.Get("hemophilia_type"),
+		HemophiliaSeverity: query.Get("hemophilia_severity"),
+		MinAge:             minAge,
+		MaxAge:             maxAge,
+		CreatedFrom:        createdFrom,
+		CreatedTo:          createdTo,
+		VisitedFrom:        visitedFrom,
+		VisitedTo:          visitedTo,
+		Sort:               query.Get("sort"),
+		Order:              query.Get("order"),
+		Cursor:             query.Get("cursor"),
+		Limit:              limit,
+	}
+
+	payload, err := e.usecases.ListPatients(params)
+	if err != nil {
+		log.Errorf("[PATIENT API]: Failed to list patients: %+v, error: %s\n", params, err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
 	_ = json.NewEncoder(w).Encode(payload)
 }
 
-func (e *patientApi) HandleFindPatients(w http.ResponseWriter, r *http.Request) {
+func (e *patientApi) HandleListVisitedPatients(w http.ResponseWriter, r *http.Request) {
 	ctx, err := parseContext(r.Context())
 	if err != nil {
 		handleErrorResponse(w, err)
 		return
 	}
 
-	findParams := actions.FindPatientsParams{
+	query := r.URL.Query()
+
+	from, to, err := queryTimeRange(query)
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	page, err := queryInt(query, "page")
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	pageSize, err := queryInt(query, "page_size")
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	params := actions.ListVisitedPatientsParams{
 		ActionContext: ctx,
-		PublicId:      r.PathValue("public_id"),
-		FirstName:     r.PathValue("first_name"),
-		LastName:      r.PathValue("last_name"),
-		FatherName:    r.PathValue("father_name"),
-		MotherName:    r.PathValue("mother_name"),
-		NationalId:    r.PathValue("national_id"),
-		PhoneNumber:   r.PathValue("phone_number"),
+		From:          from,
+		To:            to,
+		VisitReason:   query.Get("reason"),
+		Page:          page,
+		PageSize:      pageSize,
 	}
 
-	payload, err := e.usecases.FindPatients(findParams)
+	payload, err := e.usecases.ListVisitedPatients(params)
 	if err != nil {
-		log.Errorf("[PATIENT API]: Failed to find patientes: %+v, error: %s\n", findParams, err.Error())
+		log.Errorf("[PATIENT API]: Failed to list visited patients: %+v, error: %s\n", params, err.Error())
 		handleErrorResponse(w, err)
 		return
 	}
@@ -186,6 +345,51 @@ func (e *patientApi) HandleDeletePatient(w http.ResponseWriter, r *http.Request)
 	_ = json.NewEncoder(w).Encode(payload)
 }
 
+func (e *patientApi) HandleUpdatePatient(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	var reqBody actions.UpdatePatientParams
+	err = json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+	reqBody.ActionContext = ctx
+	reqBody.PublicId = r.PathValue("id")
+
+	payload, err := e.usecases.UpdatePatient(reqBody)
+	if err != nil {
+		log.Errorf("[PATIENT API]: Failed to update patient: %+v, error: %s\n", reqBody, err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func (e *patientApi) HandleListPatientFieldChanges(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	payload, err := e.usecases.ListPatientFieldChanges(actions.ListPatientFieldChangesParams{
+		ActionContext: ctx,
+		PublicId:      r.PathValue("id"),
+	})
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
 func (e *patientApi) HandleCheckUp(w http.ResponseWriter, r *http.Request) {
 	ctx, err := parseContext(r.Context())
 	if err != nil {
@@ -345,6 +549,25 @@ func (e *patientApi) HandleListPatientJointsEvaluations(w http.ResponseWriter, r
 	_ = json.NewEncoder(w).Encode(payload)
 }
 
+func (e *patientApi) HandleGetPatientJointsTrend(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	payload, err := e.usecases.GetPatientJointsTrend(actions.GetPatientJointsTrendParams{
+		ActionContext: ctx,
+		PatientId:     r.PathValue("id"),
+	})
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
 func validateFileType(r io.ReadSeeker, wantedTypes ...string) error {
 	reader := bufio.NewReader(r)
 
@@ -437,3 +660,573 @@ func (e *patientApi) HandleUsePrescribedMedicineForVisit(w http.ResponseWriter,
 
 	_ = json.NewEncoder(w).Encode(payload)
 }
+
+func (e *patientApi) HandleGetPatientFactorConsumption(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+
+	from, _, err := queryTime(query, "from")
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	to, toDateOnly, err := queryTime(query, "to")
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+	if toDateOnly {
+		to = to.AddDate(0, 0, 1)
+	}
+
+	params := actions.GetPatientFactorConsumptionParams{
+		ActionContext: ctx,
+		PatientId:     r.PathValue("id"),
+		From:          from,
+		To:            to,
+	}
+
+	payload, err := e.usecases.GetPatientFactorConsumption(params)
+	if err != nil {
+		log.Errorf("[PATIENT API]: Failed to get patient factor consumption: %+v, error: %s\n", params, err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func (e *patientApi) HandleCreateMyBleedEpisode(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	var reqBody actions.CreateMyBleedEpisodeParams
+	err = json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	reqBody.ActionContext = ctx
+	payload, err := e.usecases.CreateMyBleedEpisode(reqBody)
+	if err != nil {
+		log.Errorf("[PATIENT API]: Failed to create bleed episode: %+v, error: %s\n", reqBody, err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func (e *patientApi) HandleListMyBleedEpisodes(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	from, to, err := queryTimeRange(r.URL.Query())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	params := actions.ListMyBleedEpisodesParams{
+		ActionContext: ctx,
+		From:          from,
+		To:            to,
+	}
+
+	payload, err := e.usecases.ListMyBleedEpisodes(params)
+	if err != nil {
+		log.Errorf("[PATIENT API]: Failed to list bleed episodes: %+v, error: %s\n", params, err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func (e *patientApi) HandleListPatientBleedEpisodes(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	from, to, err := queryTimeRange(r.URL.Query())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	params := actions.ListPatientBleedEpisodesParams{
+		ActionContext: ctx,
+		PatientId:     r.PathValue("id"),
+		From:          from,
+		To:            to,
+	}
+
+	payload, err := e.usecases.ListPatientBleedEpisodes(params)
+	if err != nil {
+		log.Errorf("[PATIENT API]: Failed to list patient bleed episodes: %+v, error: %s\n", params, err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func (e *patientApi) HandleGetPatientAnalytics(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+
+	to, toDateOnly, err := queryTime(query, "to")
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+	if toDateOnly {
+		to = to.AddDate(0, 0, 1)
+	}
+
+	windowsMonths, err := queryInts(query, "windows")
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	params := actions.GetPatientAnalyticsParams{
+		ActionContext: ctx,
+		PatientId:     r.PathValue("id"),
+		To:            to,
+		WindowsMonths: windowsMonths,
+	}
+
+	payload, err := e.usecases.GetPatientAnalytics(params)
+	if err != nil {
+		log.Errorf("[PATIENT API]: Failed to get patient analytics: %+v, error: %s\n", params, err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func (e *patientApi) HandleListPatientInhibitorTiters(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	params := actions.ListPatientInhibitorTitersParams{
+		ActionContext: ctx,
+		PatientId:     r.PathValue("id"),
+	}
+
+	payload, err := e.usecases.ListPatientInhibitorTiters(params)
+	if err != nil {
+		log.Errorf("[PATIENT API]: Failed to list patient inhibitor titers: %+v, error: %s\n", params, err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func (e *patientApi) HandleListPatientsWithInhibitors(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	payload, err := e.usecases.ListPatientsWithInhibitors(actions.ListPatientsWithInhibitorsParams{
+		ActionContext: ctx,
+	})
+	if err != nil {
+		log.Errorf("[PATIENT API]: Failed to list patients with inhibitors, error: %s\n", err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func (e *patientApi) HandleGetPatientBloodTestFieldSeries(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	fieldId, err := strconv.Atoi(r.PathValue("field_id"))
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+
+	from, to, err := queryTimeRange(query)
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	maxPoints, err := queryInt(query, "max_points")
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	params := actions.GetPatientBloodTestFieldSeriesParams{
+		ActionContext: ctx,
+		PatientId:     r.PathValue("id"),
+		FieldId:       uint(fieldId),
+		From:          from,
+		To:            to,
+		MaxPoints:     maxPoints,
+	}
+
+	payload, err := e.usecases.GetPatientBloodTestFieldSeries(params)
+	if err != nil {
+		log.Errorf("[PATIENT API]: Failed to get patient blood test field series: %+v, error: %s\n", params, err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func (e *patientApi) HandleListPatientViruses(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	params := actions.ListPatientVirusesParams{
+		ActionContext: ctx,
+		PatientId:     r.PathValue("id"),
+	}
+
+	payload, err := e.usecases.ListPatientViruses(params)
+	if err != nil {
+		log.Errorf("[PATIENT API]: Failed to list patient viruses: %+v, error: %s\n", params, err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func (e *patientApi) HandleCreatePatientVirus(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	var reqBody actions.CreatePatientVirusParams
+	err = json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	reqBody.ActionContext = ctx
+	reqBody.PatientId = r.PathValue("id")
+	payload, err := e.usecases.CreatePatientVirus(reqBody)
+	if err != nil {
+		log.Errorf("[PATIENT API]: Failed to create patient virus: %+v, error: %s\n", reqBody, err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func (e *patientApi) HandleUpdatePatientVirus(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	virusId, err := strconv.Atoi(r.PathValue("virus_id"))
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	var reqBody actions.UpdatePatientVirusParams
+	err = json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	reqBody.ActionContext = ctx
+	reqBody.PatientId = r.PathValue("id")
+	reqBody.VirusId = uint(virusId)
+	payload, err := e.usecases.UpdatePatientVirus(reqBody)
+	if err != nil {
+		log.Errorf("[PATIENT API]: Failed to update patient virus: %+v, error: %s\n", reqBody, err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func (e *patientApi) HandleListPatientInfectionSuggestions(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	params := actions.ListPatientInfectionSuggestionsParams{
+		ActionContext: ctx,
+		PatientId:     r.PathValue("id"),
+	}
+
+	payload, err := e.usecases.ListPatientInfectionSuggestions(params)
+	if err != nil {
+		log.Errorf("[PATIENT API]: Failed to list patient infection suggestions: %+v, error: %s\n", params, err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func (e *patientApi) HandleCreatePatientRelationship(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	var reqBody actions.CreatePatientRelationshipParams
+	err = json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	reqBody.ActionContext = ctx
+	reqBody.PatientId = r.PathValue("id")
+	payload, err := e.usecases.CreatePatientRelationship(reqBody)
+	if err != nil {
+		log.Errorf("[PATIENT API]: Failed to create patient relationship: %+v, error: %s\n", reqBody, err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func (e *patientApi) HandleDeletePatientRelationship(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	relationshipId, err := strconv.Atoi(r.PathValue("relationship_id"))
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	params := actions.DeletePatientRelationshipParams{
+		ActionContext:  ctx,
+		PatientId:      r.PathValue("id"),
+		RelationshipId: uint(relationshipId),
+	}
+
+	payload, err := e.usecases.DeletePatientRelationship(params)
+	if err != nil {
+		log.Errorf("[PATIENT API]: Failed to delete patient relationship: %+v, error: %s\n", params, err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func (e *patientApi) HandleUpdateRelative(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	relativeId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	var reqBody actions.UpdateRelativeParams
+	err = json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	reqBody.ActionContext = ctx
+	reqBody.RelativeId = uint(relativeId)
+	payload, err := e.usecases.UpdateRelative(reqBody)
+	if err != nil {
+		log.Errorf("[PATIENT API]: Failed to update relative: %+v, error: %s\n", reqBody, err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func (e *patientApi) HandleGetPatientPedigree(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	params := actions.GetPatientPedigreeParams{
+		ActionContext: ctx,
+		PatientId:     r.PathValue("id"),
+	}
+
+	payload, err := e.usecases.GetPatientPedigree(params)
+	if err != nil {
+		log.Errorf("[PATIENT API]: Failed to get patient pedigree: %+v, error: %s\n", params, err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func (e *patientApi) HandleListPatientRelationshipProposals(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	params := actions.ListPatientRelationshipProposalsParams{
+		ActionContext: ctx,
+		PatientId:     r.PathValue("id"),
+	}
+
+	payload, err := e.usecases.ListPatientRelationshipProposals(params)
+	if err != nil {
+		log.Errorf("[PATIENT API]: Failed to list patient relationship proposals: %+v, error: %s\n", params, err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func (e *patientApi) HandleListDuplicatePatients(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	minScore, err := queryFloat(r.URL.Query(), "min_score")
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	params := actions.ListDuplicatePatientsParams{
+		ActionContext: ctx,
+		MinScore:      minScore,
+	}
+
+	payload, err := e.usecases.ListDuplicatePatients(params)
+	if err != nil {
+		log.Errorf("[PATIENT API]: Failed to list duplicate patients: %+v, error: %s\n", params, err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func (e *patientApi) HandleListPatientDuplicates(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	minScore, err := queryFloat(r.URL.Query(), "min_score")
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	params := actions.ListPatientDuplicatesParams{
+		ActionContext: ctx,
+		PatientId:     r.PathValue("id"),
+		MinScore:      minScore,
+	}
+
+	payload, err := e.usecases.ListPatientDuplicates(params)
+	if err != nil {
+		log.Errorf("[PATIENT API]: Failed to list patient duplicates: %+v, error: %s\n", params, err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func (e *patientApi) HandleMergePatients(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	var reqBody actions.MergePatientsParams
+	err = json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	reqBody.ActionContext = ctx
+	reqBody.PatientId = r.PathValue("id")
+	payload, err := e.usecases.MergePatients(reqBody)
+	if err != nil {
+		log.Errorf("[PATIENT API]: Failed to merge patients: %+v, error: %s\n", reqBody, err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
@@ -0,0 +1,132 @@
+package apis
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// queryTime parses a query value as either an RFC3339 timestamp or a plain date,
+// dateOnly reports whether the value was a plain date, so callers can include the whole day.
+func queryTime(query url.Values, key string) (t time.Time, dateOnly bool, err error) {
+	value := query.Get(key)
+	if value == "" {
+		return time.Time{}, false, nil
+	}
+
+	t, err = time.Parse(time.RFC3339, value)
+	if err == nil {
+		return t, false, nil
+	}
+
+	t, err = time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, false, ErrBadRequest{FieldName: key}
+	}
+
+	return t, true, nil
+}
+
+// queryInt parses a query value as an int, empty values are parsed as 0.
+func queryInt(query url.Values, key string) (int, error) {
+	value := query.Get(key)
+	if value == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, ErrBadRequest{FieldName: key}
+	}
+
+	return n, nil
+}
+
+// queryTimeRange parses the from and to query values as a [from, to) range,
+// a plain date to value includes the whole day.
+func queryTimeRange(query url.Values) (from, to time.Time, err error) {
+	return queryNamedTimeRange(query, "from", "to")
+}
+
+// queryNamedTimeRange is queryTimeRange with the range's query keys.
+func queryNamedTimeRange(query url.Values, fromKey, toKey string) (from, to time.Time, err error) {
+	from, _, err = queryTime(query, fromKey)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	to, toDateOnly, err := queryTime(query, toKey)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if toDateOnly {
+		to = to.AddDate(0, 0, 1)
+	}
+
+	return from, to, nil
+}
+
+// queryOptionalInt parses a query value as an int, empty values are parsed as nil.
+func queryOptionalInt(query url.Values, key string) (*int, error) {
+	if query.Get(key) == "" {
+		return nil, nil
+	}
+
+	n, err := queryInt(query, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &n, nil
+}
+
+// queryInts parses a comma separated query value as ints, empty values are parsed as nil.
+func queryInts(query url.Values, key string) ([]int, error) {
+	value := query.Get(key)
+	if value == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(value, ",")
+	ns := make([]int, 0, len(parts))
+	for _, part := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, ErrBadRequest{FieldName: key}
+		}
+		ns = append(ns, n)
+	}
+
+	return ns, nil
+}
+
+// queryBool parses a query value as a bool, empty values are parsed as false.
+func queryBool(query url.Values, key string) (bool, error) {
+	value := query.Get(key)
+	if value == "" {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, ErrBadRequest{FieldName: key}
+	}
+
+	return b, nil
+}
+
+// queryFloat parses a query value as a float, empty values are parsed as 0.
+func queryFloat(query url.Values, key string) (float64, error) {
+	value := query.Get(key)
+	if value == "" {
+		return 0, nil
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, ErrBadRequest{FieldName: key}
+	}
+
+	return f, nil
+}
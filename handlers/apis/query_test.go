@@ -0,0 +1,71 @@
+package apis
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestQueryTimeRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		wantFrom time.Time
+		wantTo   time.Time
+		wantErr  bool
+	}{
+		{
+			name: "no range",
+		},
+		{
+			name:     "timestamps are used as they are",
+			query:    "from=2024-01-02T10:00:00Z&to=2024-01-03T10:00:00Z",
+			wantFrom: time.Date(2024, time.January, 2, 10, 0, 0, 0, time.UTC),
+			wantTo:   time.Date(2024, time.January, 3, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "a plain date to includes the whole day",
+			query:    "from=2024-01-02&to=2024-01-02",
+			wantFrom: time.Date(2024, time.January, 2, 0, 0, 0, 0, time.UTC),
+			wantTo:   time.Date(2024, time.January, 3, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "open ended",
+			query:    "from=2024-01-02",
+			wantFrom: time.Date(2024, time.January, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "invalid from",
+			query:   "from=yesterday",
+			wantErr: true,
+		},
+		{
+			name:    "invalid to",
+			query:   "to=02/01/2024",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			from, to, err := queryTimeRange(query)
+			if tt.wantErr {
+				if _, ok := err.(ErrBadRequest); !ok {
+					t.Errorf("queryTimeRange() error = %v, want a bad request", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("queryTimeRange() error = %v", err)
+			}
+			if !from.Equal(tt.wantFrom) || !to.Equal(tt.wantTo) {
+				t.Errorf("queryTimeRange() = [%v, %v), want [%v, %v)", from, to, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
@@ -51,8 +51,15 @@ func (e *virusApi) HandleListViruses(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	includeArchived, err := queryBool(r.URL.Query(), "include_archived")
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
 	payload, err := e.usecases.ListAllViruses(actions.ListAllVirusesParams{
-		ActionContext: ctx,
+		ActionContext:   ctx,
+		IncludeArchived: includeArchived,
 	})
 	if err != nil {
 		log.Errorf("[VIRUS API]: Failed to get viruss, error: %s\n", err.Error())
@@ -88,3 +95,31 @@ func (e *virusApi) HandleDeleteVirus(w http.ResponseWriter, r *http.Request) {
 
 	_ = json.NewEncoder(w).Encode(payload)
 }
+
+// HandleSetVirusArchived archives the virus on POST, and unarchives it on DELETE.
+func (e *virusApi) HandleSetVirusArchived(w http.ResponseWriter, r *http.Request) {
+	ctx, err := parseContext(r.Context())
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	payload, err := e.usecases.SetVirusArchived(actions.SetVirusArchivedParams{
+		ActionContext: ctx,
+		VirusId:       uint(id),
+		Archived:      r.Method != http.MethodDelete,
+	})
+	if err != nil {
+		log.Errorf("[VIRUS API]: Failed to set virus archived, error: %s\n", err.Error())
+		handleErrorResponse(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(payload)
+}
@@ -0,0 +1,173 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"shs/actions"
+	"shs/app/models"
+	"shs/handlers/middlewares/auth"
+	"shs/log"
+	"strings"
+)
+
+const (
+	maxRecordedBodySize = 64 << 10 // 64 KB
+	redactedValue       = "[redacted]"
+)
+
+type Middleware struct {
+	usecases *actions.Actions
+}
+
+// New returns a new audit middleware instance.
+func New(usecases *actions.Actions) *Middleware {
+	return &Middleware{
+		usecases: usecases,
+	}
+}
+
+// AuditApi records a write API's request into the audit log after it's handled,
+// it must be wrapped with auth.Middleware.AuthApi so the acting account is known.
+func (m *Middleware) AuditApi(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		account, ok := r.Context().Value(auth.AccountKey).(models.Account)
+		if !ok {
+			h(w, r)
+			return
+		}
+
+		body := readRequestBody(r)
+		recorder := &responseRecorder{
+			ResponseWriter: w,
+			statusCode:     http.StatusOK,
+		}
+
+		h(recorder, r)
+
+		resourceType, resourceId := requestResource(r)
+		if resourceId == "" {
+			resourceId = recorder.createdResourceId()
+		}
+
+		payload, _ := json.Marshal(map[string]any{
+			"path": r.URL.Path,
+			"body": body,
+		})
+
+		_, err := m.usecases.RecordAuditLog(actions.RecordAuditLogParams{
+			ActionContext: actions.ActionContext{
+				Account: account,
+			},
+			Action:       r.Pattern,
+			ResourceType: resourceType,
+			ResourceId:   resourceId,
+			StatusCode:   recorder.statusCode,
+			Payload:      string(payload),
+		})
+		if err != nil {
+			log.Errorf("[AUDIT MIDDLEWARE]: Failed to record audit log for %s, error: %s\n", r.Pattern, err.Error())
+		}
+	}
+}
+
+// requestResource returns the first path segment of the matched pattern as the resource type,
+// and the value of the pattern's first wildcard as the resource id.
+func requestResource(r *http.Request) (resourceType, resourceId string) {
+	_, path, _ := strings.Cut(r.Pattern, " ")
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "" {
+			continue
+		}
+		if wildcard, ok := strings.CutPrefix(segment, "{"); ok {
+			resourceId = r.PathValue(strings.TrimSuffix(wildcard, "}"))
+			break
+		}
+		if resourceType == "" {
+			resourceType = segment
+		}
+	}
+
+	return resourceType, resourceId
+}
+
+// readRequestBody reads the request's body into a summary that's safe to store,
+// the body is restored so that the wrapped handler can still read it.
+func readRequestBody(r *http.Request) any {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+		return fmt.Sprintf("multipart upload of %d bytes", r.ContentLength)
+	}
+	if r.Body == nil {
+		return nil
+	}
+
+	rawBody, err := io.ReadAll(r.Body)
+	_ = r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(rawBody))
+	if err != nil || len(rawBody) == 0 {
+		return nil
+	}
+	if len(rawBody) > maxRecordedBodySize {
+		return fmt.Sprintf("body of %d bytes", len(rawBody))
+	}
+
+	var body any
+	err = json.Unmarshal(rawBody, &body)
+	if err != nil {
+		return fmt.Sprintf("non-json body of %d bytes", len(rawBody))
+	}
+
+	return redactSecrets(body)
+}
+
+func redactSecrets(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key := range v {
+			if strings.Contains(strings.ToLower(key), "password") {
+				v[key] = redactedValue
+				continue
+			}
+			v[key] = redactSecrets(v[key])
+		}
+	case []any:
+		for i := range v {
+			v[i] = redactSecrets(v[i])
+		}
+	}
+
+	return value
+}
+
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(statusCode int) {
+	r.statusCode = statusCode
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.body.Len() < maxRecordedBodySize {
+		r.body.Write(b)
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// createdResourceId returns the id from creation responses, e.g. {"id": "000420"}
+func (r *responseRecorder) createdResourceId() string {
+	var created struct {
+		Id any `json:"id"`
+	}
+	err := json.Unmarshal(r.body.Bytes(), &created)
+	if err != nil || created.Id == nil {
+		return ""
+	}
+
+	return fmt.Sprint(created.Id)
+}
@@ -0,0 +1,111 @@
+package audit
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRequestResource(t *testing.T) {
+	tests := []struct {
+		pattern          string
+		pathValues       map[string]string
+		wantResourceType string
+		wantResourceId   string
+	}{
+		{pattern: "POST /patients", wantResourceType: "patients"},
+		{
+			pattern:          "PATCH /patients/{id}",
+			pathValues:       map[string]string{"id": "AB12CD"},
+			wantResourceType: "patients",
+			wantResourceId:   "AB12CD",
+		},
+		{
+			pattern:          "DELETE /patients/{id}/diagnoses/{result_id}",
+			pathValues:       map[string]string{"id": "AB12CD", "result_id": "4"},
+			wantResourceType: "patients",
+			wantResourceId:   "AB12CD",
+		},
+		{
+			pattern:          "POST /medicines/products/{id}/min-stock",
+			pathValues:       map[string]string{"id": "3"},
+			wantResourceType: "medicines",
+			wantResourceId:   "3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", nil)
+			r.Pattern = tt.pattern
+			for key, value := range tt.pathValues {
+				r.SetPathValue(key, value)
+			}
+
+			resourceType, resourceId := requestResource(r)
+			if resourceType != tt.wantResourceType || resourceId != tt.wantResourceId {
+				t.Errorf("requestResource() = (%q, %q), want (%q, %q)", resourceType, resourceId, tt.wantResourceType, tt.wantResourceId)
+			}
+		})
+	}
+}
+
+func TestReadRequestBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        any
+	}{
+		{
+			name: "empty body",
+			want: nil,
+		},
+		{
+			name: "passwords are redacted at any depth",
+			body: `{"username":"admin","password":"secret","accounts":[{"new_password":"secret2"}]}`,
+			want: map[string]any{
+				"username": "admin",
+				"password": redactedValue,
+				"accounts": []any{map[string]any{"new_password": redactedValue}},
+			},
+		},
+		{
+			name: "non json body",
+			body: "first_name,last_name",
+			want: "non-json body of 20 bytes",
+		},
+		{
+			name:        "multipart upload",
+			contentType: "multipart/form-data; boundary=x",
+			body:        "--x--",
+			want:        "multipart upload of 5 bytes",
+		},
+		{
+			name: "too large body",
+			body: `"` + strings.Repeat("a", maxRecordedBodySize) + `"`,
+			want: "body of 65538 bytes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+
+			if got := readRequestBody(r); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readRequestBody() = %v, want %v", got, tt.want)
+			}
+
+			restored, err := io.ReadAll(r.Body)
+			if err != nil || string(restored) != tt.body {
+				t.Errorf("readRequestBody() didn't restore the body, got %q", restored)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package lognotifier
+
+import (
+	"shs/app/models"
+	"shs/log"
+)
+
+// Notifier writes alerts to the server's logs, it's the default app.Notifier
+// until alerts are sent somewhere people are actually looking, e.g. email or SMS.
+type Notifier struct{}
+
+func New() *Notifier {
+	return &Notifier{}
+}
+
+func (n *Notifier) Notify(alerts []models.Alert) error {
+	for _, alert := range alerts {
+		log.Warningf("[ALERT]: %s: %s\n", alert.Type, alert.Message)
+	}
+
+	return nil
+}
@@ -1,17 +1,21 @@
 package mariadb
 
 import (
+	"fmt"
 	"shs/app/models"
 	"shs/config"
+	"strings"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 	"gorm.io/gorm/schema"
 )
 
 var migratableModels = []schema.Tabler{
 	new(models.Account),
 	new(models.Virus),
+	new(models.MedicineProduct),
 	new(models.Medicine),
 	new(models.Visit),
 	new(models.BloodTest),
@@ -21,11 +25,37 @@ var migratableModels = []schema.Tabler{
 	new(models.Address),
 	new(models.Patient),
 	new(models.PatientId),
+	new(models.PatientVirus),
+	new(models.Relative),
+	new(models.PatientRelationship),
+	new(models.PatientFieldChange),
+	new(models.PatientAlias),
 	new(models.PatientUseMedicine),
+	new(models.Prescription),
 	new(models.PrescribedMedicine),
 	new(models.JointsEvaluation),
 	new(models.Diagnosis),
+	new(models.DiagnosisField),
 	new(models.DiagnosisResult),
+	new(models.DiagnosisFilledField),
+	new(models.Alert),
+	new(models.BleedEpisode),
+	new(models.BleedInfusion),
+	new(models.JointHealthScore),
+}
+
+// appendOnlyModels are migrated like the rest, but rows can't be updated or deleted,
+// and they're not wiped by DeleteAll.
+var appendOnlyModels = []schema.Tabler{
+	new(models.AuditLog),
+	new(models.StockMovement),
+	new(models.BloodTestFieldVersion),
+}
+
+// deletableAppendOnlyModels can't be updated, but they're deleted with their parents,
+// like the field versions of a blood test that's deleted since nothing references it.
+var deletableAppendOnlyModels = map[string]bool{
+	models.BloodTestFieldVersion{}.TableName(): true,
 }
 
 func Migrate() error {
@@ -34,6 +64,21 @@ func Migrate() error {
 		return err
 	}
 
+	err = (&Repository{dbConn}).dropMedicineProductNameIndex()
+	if err != nil {
+		return err
+	}
+
+	err = (&Repository{dbConn}).migrateMedicineProducts()
+	if err != nil {
+		return err
+	}
+
+	migrator := dbConn.Migrator()
+	classifyPatients := migrator.HasTable(new(models.Patient)) &&
+		(!migrator.HasColumn(new(models.Patient), "hemophilia_severity") || !migrator.HasColumn(new(models.Patient), "inhibitor_positive"))
+	grantAuditLogs := migrator.HasTable(new(models.Account)) && !migrator.HasTable(new(models.AuditLog))
+
 	for _, table := range migratableModels {
 		err = dbConn.Debug().AutoMigrate(table)
 		if err != nil {
@@ -41,13 +86,79 @@ func Migrate() error {
 		}
 	}
 
-	for _, table := range migratableModels {
+	for _, table := range appendOnlyModels {
+		err = dbConn.Debug().AutoMigrate(table)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, table := range append(migratableModels, appendOnlyModels...) {
 		err = dbConn.Exec("ALTER TABLE " + table.TableName() + " CONVERT TO CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci").Error
 		if err != nil {
 			return err
 		}
 	}
 
+	for _, table := range appendOnlyModels {
+		for _, operation := range []string{"UPDATE", "DELETE"} {
+			if operation == "DELETE" && deletableAppendOnlyModels[table.TableName()] {
+				err = dbConn.Exec("DROP TRIGGER IF EXISTS " + table.TableName() + "_no_delete").Error
+				if err != nil {
+					return err
+				}
+				continue
+			}
+
+			err = dbConn.Exec(fmt.Sprintf(
+				"CREATE TRIGGER IF NOT EXISTS %s_no_%s BEFORE %s ON %s FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = '%s is append-only'",
+				table.TableName(), strings.ToLower(operation), operation, table.TableName(), table.TableName(),
+			)).Error
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = (&Repository{dbConn}).createOpeningStockMovements()
+	if err != nil {
+		return err
+	}
+
+	err = (&Repository{dbConn}).createMissingPrescriptions()
+	if err != nil {
+		return err
+	}
+
+	err = (&Repository{dbConn}).createFirstBloodTestFieldVersions()
+	if err != nil {
+		return err
+	}
+
+	err = (&Repository{dbConn}).normalizePatientFields()
+	if err != nil {
+		return err
+	}
+
+	if grantAuditLogs {
+		err = (&Repository{dbConn}).grantAuditLogsPermission()
+		if err != nil {
+			return err
+		}
+	}
+
+	if classifyPatients {
+		err = (&Repository{dbConn}).classifyPatients()
+		if err != nil {
+			return err
+		}
+	}
+
+	err = (&Repository{dbConn}).reclassifyZeroFactorLevels()
+	if err != nil {
+		return err
+	}
+
 	_ = (&Repository{dbConn}).CreateSuperAdmin()
 
 	return nil
@@ -65,7 +176,8 @@ func (r *Repository) CreateSuperAdmin() error {
 			models.AccountPermissionReadMedicine | models.AccountPermissionWriteMedicine |
 			models.AccountPermissionReadVirus | models.AccountPermissionWriteVirus |
 			models.AccountPermissionReadBloodTest | models.AccountPermissionWriteBloodTest |
-			models.AccountPermissionReadOtherVisits | models.AccountPermissionWriteOtherVisits,
+			models.AccountPermissionReadOtherVisits | models.AccountPermissionWriteOtherVisits |
+			models.AccountPermissionReadAuditLogs,
 		CreatedAt: time.Now().UTC(),
 		UpdatedAt: time.Now().UTC(),
 	}
@@ -73,6 +185,248 @@ func (r *Repository) CreateSuperAdmin() error {
 	return r.client.Create(&superMechman).Error
 }
 
+// grantAuditLogsPermission grants reading the audit logs to the admins that predate the audit logs,
+// it only runs with the audit logs' first migration, so that it doesn't grant it back after it's revoked.
+func (r *Repository) grantAuditLogsPermission() error {
+	return r.client.
+		Model(new(models.Account)).
+		Where("type IN ?", []models.AccountType{models.AccountTypeSuperAdmin, models.AccountTypeAdmin}).
+		Update("permissions", gorm.Expr("permissions | ?", models.AccountPermissionReadAuditLogs)).
+		Error
+}
+
+// dropMedicineProductNameIndex drops the products' unique index that left out the dose and the unit,
+// which is replaced by idx_medicine_product_strength, so that batches of different strengths aren't merged into one product.
+func (r *Repository) dropMedicineProductNameIndex() error {
+	migrator := r.client.Migrator()
+	if !migrator.HasIndex(new(models.MedicineProduct), "idx_medicine_product") {
+		return nil
+	}
+
+	return migrator.DropIndex(new(models.MedicineProduct), "idx_medicine_product")
+}
+
+// migrateMedicineProducts moves the attributes of medicines that predate products into products,
+// where batches are grouped by name, dose, unit, manufacturer and factor type.
+func (r *Repository) migrateMedicineProducts() error {
+	migrator := r.client.Migrator()
+	if !migrator.HasTable(new(models.Medicine)) || !migrator.HasColumn(new(models.Medicine), "name") {
+		return nil
+	}
+
+	err := r.client.AutoMigrate(new(models.MedicineProduct))
+	if err != nil {
+		return err
+	}
+
+	for _, table := range []schema.Tabler{new(models.MedicineProduct), new(models.Medicine)} {
+		err = r.client.Exec("ALTER TABLE " + table.TableName() + " CONVERT TO CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci").Error
+		if err != nil {
+			return err
+		}
+	}
+
+	if !migrator.HasColumn(new(models.Medicine), "product_id") {
+		err = r.client.Exec(fmt.Sprintf("ALTER TABLE %s ADD product_id BIGINT UNSIGNED", models.Medicine{}.TableName())).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	err = r.client.Exec(fmt.Sprintf(
+		"INSERT IGNORE INTO %[1]s (name, dose, unit, manufacturer, factor_type, created_at, updated_at) "+
+			"SELECT name, dose, unit, manufacturer, factor_type, MIN(created_at), ? FROM %[2]s "+
+			"GROUP BY name, dose, unit, manufacturer, factor_type",
+		models.MedicineProduct{}.TableName(), models.Medicine{}.TableName(),
+	), time.Now().UTC()).Error
+	if err != nil {
+		return err
+	}
+
+	err = r.client.Exec(fmt.Sprintf(
+		"UPDATE %[1]s m JOIN %[2]s p ON m.name = p.name AND m.dose = p.dose AND m.unit = p.unit "+
+			"AND m.manufacturer = p.manufacturer AND m.factor_type = p.factor_type "+
+			"SET m.product_id = p.id",
+		models.Medicine{}.TableName(), models.MedicineProduct{}.TableName(),
+	)).Error
+	if err != nil {
+		return err
+	}
+
+	// the old columns are dropped last in a single statement, so that an interrupted migration is picked up again on the next run.
+	return r.client.Exec(fmt.Sprintf(
+		"ALTER TABLE %s DROP COLUMN name, DROP COLUMN dose, DROP COLUMN unit, DROP COLUMN manufacturer, DROP COLUMN factor_type",
+		models.Medicine{}.TableName(),
+	)).Error
+}
+
+// createOpeningStockMovements records the amounts of medicines that predate the stock ledger as opening adjustments,
+// so that every medicine's amount matches the sum of its movements.
+func (r *Repository) createOpeningStockMovements() error {
+	return r.client.Exec(fmt.Sprintf(
+		"INSERT INTO %[1]s (medicine_id, type, quantity, reason, created_at) "+
+			"SELECT m.id, ?, m.amount, ?, ? FROM %[2]s m "+
+			"WHERE m.amount <> 0 AND NOT EXISTS (SELECT 1 FROM %[1]s sm WHERE sm.medicine_id = m.id)",
+		models.StockMovement{}.TableName(), models.Medicine{}.TableName(),
+	), models.StockMovementTypeAdjustment, "opening balance", time.Now().UTC()).Error
+}
+
+// createMissingPrescriptions groups prescribed medicines that predate prescriptions by their visit and product into prescriptions,
+// with their total IU computed from the products' doses.
+func (r *Repository) createMissingPrescriptions() error {
+	err := r.client.Exec(fmt.Sprintf(
+		"INSERT INTO %[1]s (visit_id, patient_id, product_id, quantity, dose_per_kg, total_iu, instructions, created_at, updated_at) "+
+			"SELECT pm.visit_id, pm.patient_id, m.product_id, COUNT(*), "+
+			"IF(v.patient_weight > 0, SUM(IF(LOWER(TRIM(p.unit)) = 'iu', p.dose, 0)) / v.patient_weight, 0), "+
+			"SUM(IF(LOWER(TRIM(p.unit)) = 'iu', p.dose, 0)), '', MIN(pm.created_at), ? "+
+			"FROM %[2]s pm "+
+			"JOIN %[3]s m ON m.id = pm.medicine_id "+
+			"JOIN %[4]s p ON p.id = m.product_id "+
+			"JOIN %[5]s v ON v.id = pm.visit_id "+
+			"WHERE pm.prescription_id IS NULL OR pm.prescription_id = 0 "+
+			"GROUP BY pm.visit_id, pm.patient_id, m.product_id, v.patient_weight",
+		models.Prescription{}.TableName(), models.PrescribedMedicine{}.TableName(), models.Medicine{}.TableName(),
+		models.MedicineProduct{}.TableName(), models.Visit{}.TableName(),
+	), time.Now().UTC()).Error
+	if err != nil {
+		return err
+	}
+
+	return r.client.Exec(fmt.Sprintf(
+		"UPDATE %[1]s pm "+
+			"JOIN %[2]s m ON m.id = pm.medicine_id "+
+			"JOIN %[3]s ps ON ps.visit_id = pm.visit_id AND ps.product_id = m.product_id "+
+			"SET pm.prescription_id = ps.id "+
+			"WHERE pm.prescription_id IS NULL OR pm.prescription_id = 0",
+		models.PrescribedMedicine{}.TableName(), models.Medicine{}.TableName(), models.Prescription{}.TableName(),
+	)).Error
+}
+
+// createFirstBloodTestFieldVersions records the definitions of fields that predate versioning as their first versions,
+// which are in force since the fields were created.
+func (r *Repository) createFirstBloodTestFieldVersions() error {
+	return r.client.Exec(fmt.Sprintf(
+		"INSERT INTO %[1]s (blood_test_field_id, version, name, unit, required, "+
+			"min_value_number, min_value_string, max_value_number, max_value_string, "+
+			"critical_min_value_number, critical_max_value_number, retired, effective_from, created_at) "+
+			"SELECT f.id, f.version, f.name, f.unit, f.required, "+
+			"f.min_value_number, f.min_value_string, f.max_value_number, f.max_value_string, "+
+			"f.critical_min_value_number, f.critical_max_value_number, f.retired, f.created_at, ? FROM %[2]s f "+
+			"WHERE NOT EXISTS (SELECT 1 FROM %[1]s fv WHERE fv.blood_test_field_id = f.id)",
+		models.BloodTestFieldVersion{}.TableName(), models.BloodTestField{}.TableName(),
+	), time.Now().UTC()).Error
+}
+
+// normalizePatientFields sets the normalized search fields of patients that predate them.
+func (r *Repository) normalizePatientFields() error {
+	var patients []models.Patient
+	err := r.client.
+		Model(new(models.Patient)).
+		Select("id", "first_name", "last_name", "father_name", "mother_name", "phone_number").
+		Where("first_name_normalized = '' AND first_name <> ''").
+		Find(&patients).
+		Error
+	if err != nil {
+		return err
+	}
+
+	for _, patient := range patients {
+		patient.SetNormalizedFields()
+		err = r.client.
+			Model(new(models.Patient)).
+			Where("id = ?", patient.Id).
+			Updates(map[string]any{
+				"first_name_normalized":   patient.FirstNameNormalized,
+				"last_name_normalized":    patient.LastNameNormalized,
+				"father_name_normalized":  patient.FatherNameNormalized,
+				"mother_name_normalized":  patient.MotherNameNormalized,
+				"name_skeletons":          patient.NameSkeletons,
+				"phone_number_normalized": patient.PhoneNumberNormalized,
+			}).
+			Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// classifyPatients classifies the hemophilia and inhibitor of patients that predate the classifications,
+// using their factor and titer results.
+func (r *Repository) classifyPatients() error {
+	bloodTests, err := r.ListAllBloodTests()
+	if err != nil {
+		return err
+	}
+
+	var patientIds []uint
+	err = r.client.
+		Model(new(models.BloodTestResult)).
+		Distinct("patient_id").
+		Where("pending = ?", false).
+		Pluck("patient_id", &patientIds).
+		Error
+	if err != nil {
+		return err
+	}
+
+	for _, patientId := range patientIds {
+		results, err := r.ListPatientBloodTestResults(patientId)
+		if err != nil {
+			return err
+		}
+
+		err = r.UpdatePatientHemophilia(patientId, models.ClassifyHemophilia(results, bloodTests))
+		if err != nil {
+			return err
+		}
+
+		err = r.UpdatePatientInhibitor(patientId, models.ClassifyInhibitor(models.ListInhibitorTiters(results, bloodTests)))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reclassifyZeroFactorLevels reclassifies the hemophilia of patients that were classified from a 0% factor level,
+// which older imports stored for the factor levels that weren't given, and were classified as severe.
+func (r *Repository) reclassifyZeroFactorLevels() error {
+	var patientIds []uint
+	err := r.client.
+		Model(new(models.Patient)).
+		Where("factor_level = ? AND factor_level_blood_test_result_id != ?", 0, 0).
+		Pluck("id", &patientIds).
+		Error
+	if err != nil {
+		return err
+	}
+	if len(patientIds) == 0 {
+		return nil
+	}
+
+	bloodTests, err := r.ListAllBloodTests()
+	if err != nil {
+		return err
+	}
+
+	for _, patientId := range patientIds {
+		results, err := r.ListPatientBloodTestResults(patientId)
+		if err != nil {
+			return err
+		}
+
+		err = r.UpdatePatientHemophilia(patientId, models.ClassifyHemophilia(results, bloodTests))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (r *Repository) DeleteAll() error {
 	err := r.client.Exec("SET FOREIGN_KEY_CHECKS=0;").Error
 	if err != nil {
@@ -1,7 +1,6 @@
 package mariadb
 
 import (
-	"errors"
 	"fmt"
 	"shs/app"
 	"shs/app/models"
@@ -9,6 +8,8 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
 )
 
 type Repository struct {
@@ -30,6 +31,14 @@ func New() (*Repository, error) {
 // App Repository
 // --------------------------------
 
+func (r *Repository) WithTx(fn func(tx app.Repository) error) error {
+	return r.client.Transaction(func(tx *gorm.DB) error {
+		return fn(&Repository{
+			client: tx,
+		})
+	})
+}
+
 func (r *Repository) GetAccount(id uint) (models.Account, error) {
 	var account models.Account
 
@@ -193,6 +202,27 @@ func (r *Repository) UpdateAccountUsername(id uint, username string) error {
 	return nil
 }
 
+func (r *Repository) SetAccountDisabledAt(id uint, disabledAt *time.Time) error {
+	result := r.client.
+		Model(new(models.Account)).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"disabled_at": disabledAt,
+			"updated_at":  time.Now().UTC(),
+		})
+	err := tryWrapDbError(result.Error)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected == 0 {
+		return &app.ErrNotFound{
+			ResourceName: "account",
+		}
+	}
+
+	return nil
+}
+
 func (r *Repository) UpdateAccountPassword(id uint, password string) error {
 	err := tryWrapDbError(
 		r.client.
@@ -238,6 +268,19 @@ func (r *Repository) CreateBloodTest(bt models.BloodTest) (models.BloodTest, err
 
 func (r *Repository) DeleteBloodTest(id uint) error {
 	err := tryWrapDbError(
+		r.client.
+			Where("blood_test_field_id IN (?)", r.client.
+				Model(new(models.BloodTestField)).
+				Select("id").
+				Where("blood_test_id = ?", id)).
+			Delete(new(models.BloodTestFieldVersion)).
+			Error,
+	)
+	if err != nil {
+		return err
+	}
+
+	err = tryWrapDbError(
 		r.client.
 			Model(new(models.BloodTestField)).
 			Delete(&models.BloodTestField{BloodTestId: id}, "blood_test_id = ?", id).
@@ -277,6 +320,7 @@ func (r *Repository) GetBloodTest(id uint) (models.BloodTest, error) {
 		r.client.
 			Model(new(models.BloodTest)).
 			Preload("Fields").
+			Preload("Fields.Versions", preloadBloodTestFieldVersions).
 			First(&bt, "id = ?", id).
 			Error,
 	)
@@ -292,8 +336,138 @@ func (r *Repository) GetBloodTest(id uint) (models.BloodTest, error) {
 	return bt, nil
 }
 
+func (r *Repository) GetBloodTestField(id uint) (models.BloodTestField, error) {
+	var field models.BloodTestField
+
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.BloodTestField)).
+			Preload("Versions", preloadBloodTestFieldVersions).
+			First(&field, "id = ?", id).
+			Error,
+	)
+	if _, ok := err.(*ErrRecordNotFound); ok {
+		return models.BloodTestField{}, &app.ErrNotFound{
+			ResourceName: "blood_test_field",
+		}
+	}
+	if err != nil {
+		return models.BloodTestField{}, err
+	}
+
+	return field, nil
+}
+
 func (r *Repository) UpdateBloodTest(id uint, bt models.BloodTest) (models.BloodTest, error) {
-	return models.BloodTest{}, errors.New("not implemented")
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.BloodTest)).
+			Where("id = ?", id).
+			Updates(map[string]any{
+				"name":       bt.Name,
+				"version":    bt.Version,
+				"updated_at": time.Now().UTC(),
+			}).
+			Error,
+	)
+	if _, ok := err.(*ErrRecordNotFound); ok {
+		return models.BloodTest{}, &app.ErrNotFound{
+			ResourceName: "blood_test",
+		}
+	}
+	if err != nil {
+		return models.BloodTest{}, err
+	}
+
+	return r.GetBloodTest(id)
+}
+
+func (r *Repository) CreateBloodTestField(field models.BloodTestField) (models.BloodTestField, error) {
+	field.CreatedAt = time.Now().UTC()
+	field.UpdatedAt = time.Now().UTC()
+
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.BloodTestField)).
+			Create(&field).
+			Error,
+	)
+	if _, ok := err.(*ErrRecordExists); ok {
+		return models.BloodTestField{}, &app.ErrExists{
+			ResourceName: "blood_test_field",
+		}
+	}
+	if err != nil {
+		return models.BloodTestField{}, err
+	}
+
+	return field, nil
+}
+
+func (r *Repository) UpdateBloodTestField(field models.BloodTestField) error {
+	field.UpdatedAt = time.Now().UTC()
+
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.BloodTestField)).
+			Where("id = ?", field.Id).
+			Select(
+				"name", "unit", "required",
+				"min_value_number", "min_value_string", "max_value_number", "max_value_string",
+				"critical_min_value_number", "critical_max_value_number",
+				"version", "retired", "updated_at",
+			).
+			Updates(&field).
+			Error,
+	)
+	if _, ok := err.(*ErrRecordNotFound); ok {
+		return &app.ErrNotFound{
+			ResourceName: "blood_test_field",
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *Repository) CreateBloodTestFieldVersion(version models.BloodTestFieldVersion) error {
+	version.CreatedAt = time.Now().UTC()
+
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.BloodTestFieldVersion)).
+			Create(&version).
+			Error,
+	)
+	if _, ok := err.(*ErrRecordExists); ok {
+		return &app.ErrExists{
+			ResourceName: "blood_test_field_version",
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// preloadBloodTestFieldVersions orders the preloaded field versions by version,
+// which is what [models.BloodTestField.At] expects.
+func preloadBloodTestFieldVersions(db *gorm.DB) *gorm.DB {
+	return db.Order("version ASC")
+}
+
+func (r *Repository) SetBloodTestArchivedAt(id uint, archivedAt *time.Time) error {
+	return r.setArchivedAt(new(models.BloodTest), "blood_test", id, archivedAt)
+}
+
+func (r *Repository) GetBloodTestUsage(id uint) (models.Usage, error) {
+	return r.countReferences(id, map[string]string{
+		"blood_test_results":  models.BloodTestResult{}.TableName() + ".blood_test_id",
+		"identifying_viruses": "identifying_blood_tests.blood_test_id",
+	})
 }
 
 func (r *Repository) ListAllBloodTests() ([]models.BloodTest, error) {
@@ -303,6 +477,7 @@ func (r *Repository) ListAllBloodTests() ([]models.BloodTest, error) {
 		r.client.
 			Model(new(models.BloodTest)).
 			Preload("Fields").
+			Preload("Fields.Versions", preloadBloodTestFieldVersions).
 			Find(&bloodTests).
 			Error,
 	)
@@ -337,6 +512,33 @@ func (r *Repository) CreateBloodTestResult(btResult models.BloodTestResult) (mod
 	return btResult, nil
 }
 
+func (r *Repository) ListPatientBloodTestFieldPoints(patientId, fieldId uint, from, to time.Time) ([]models.BloodTestFieldPoint, error) {
+	query := r.client.
+		Table(models.BloodTestFilledField{}.TableName()+" ff").
+		Select("ff.blood_test_result_id, ff.value_number AS value, btr.created_at AS measured_at").
+		Joins("JOIN "+models.BloodTestResult{}.TableName()+" btr ON btr.id = ff.blood_test_result_id").
+		Where("btr.patient_id = ? AND ff.blood_test_field_id = ? AND btr.pending = ?", patientId, fieldId, false)
+	if !from.IsZero() {
+		query = query.Where("btr.created_at >= ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("btr.created_at < ?", to)
+	}
+
+	var points []models.BloodTestFieldPoint
+	err := tryWrapDbError(
+		query.
+			Order("btr.created_at ASC").
+			Scan(&points).
+			Error,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return points, nil
+}
+
 func (r *Repository) ListPatientBloodTestResults(patientId uint) ([]models.BloodTestResult, error) {
 	var bloodTestResults []models.BloodTestResult
 
@@ -477,6 +679,16 @@ func (r *Repository) DeleteVirus(id uint) error {
 	return nil
 }
 
+func (r *Repository) SetVirusArchivedAt(id uint, archivedAt *time.Time) error {
+	return r.setArchivedAt(new(models.Virus), "virus", id, archivedAt)
+}
+
+func (r *Repository) GetVirusUsage(id uint) (models.Usage, error) {
+	return r.countReferences(id, map[string]string{
+		"patients": "has_viruses.virus_id",
+	})
+}
+
 func (r *Repository) ListAllViruses() ([]models.Virus, error) {
 	var viruses []models.Virus
 
@@ -494,60 +706,70 @@ func (r *Repository) ListAllViruses() ([]models.Virus, error) {
 	return viruses, nil
 }
 
-func (r *Repository) ListVirusesForPatient(patientId uint) ([]models.Virus, error) {
-	viruses := make([]models.Virus, 0)
-
-	query := fmt.Sprintf(`SELECT %s.id, viruses.name
-	FROM viruses
-		JOIN has_viruses ON %s.id = has_viruses.virus_id
-	WHERE has_viruses.patient_id = ?`, models.Virus{}.TableName(), models.Virus{}.TableName())
+func (r *Repository) GetVirus(id uint) (models.Virus, error) {
+	var virus models.Virus
 
 	err := tryWrapDbError(
 		r.client.
-			Raw(query, patientId).
-			Find(&viruses).
+			Model(new(models.Virus)).
+			Preload("IdentifyingBloodTests").
+			First(&virus, "id = ?", id).
 			Error,
 	)
+	if _, ok := err.(*ErrRecordNotFound); ok {
+		return models.Virus{}, &app.ErrNotFound{
+			ResourceName: "virus",
+		}
+	}
 	if err != nil {
-		return nil, err
+		return models.Virus{}, err
 	}
 
-	return viruses, nil
+	return virus, nil
 }
 
-func (r *Repository) CreateMedicine(medicine models.Medicine) (models.Medicine, error) {
-	medicine.CreatedAt = time.Now().UTC()
-	medicine.UpdatedAt = time.Now().UTC()
+func (r *Repository) CreatePatientVirus(patientVirus models.PatientVirus) (models.PatientVirus, error) {
+	patientVirus.CreatedAt = time.Now().UTC()
+	patientVirus.UpdatedAt = time.Now().UTC()
 
 	err := tryWrapDbError(
 		r.client.
-			Model(new(models.Medicine)).
-			Create(&medicine).
+			Model(new(models.PatientVirus)).
+			Omit("Virus").
+			Create(&patientVirus).
 			Error,
 	)
 	if _, ok := err.(*ErrRecordExists); ok {
-		return models.Medicine{}, &app.ErrExists{
-			ResourceName: "medicine",
+		return models.PatientVirus{}, &app.ErrExists{
+			ResourceName: "patient_virus",
 		}
 	}
 	if err != nil {
-		return models.Medicine{}, err
+		return models.PatientVirus{}, err
 	}
 
-	return medicine, nil
-
+	return patientVirus, nil
 }
 
-func (r *Repository) DeleteMedicine(id uint) error {
+func (r *Repository) UpdatePatientVirus(patientVirus models.PatientVirus) error {
 	err := tryWrapDbError(
 		r.client.
-			Model(new(models.Medicine)).
-			Delete(&models.Medicine{Id: id}, "id = ?", id).
+			Model(new(models.PatientVirus)).
+			Where("patient_id = ? AND virus_id = ?", patientVirus.PatientId, patientVirus.VirusId).
+			Updates(map[string]any{
+				"status":               patientVirus.Status,
+				"detected_at":          patientVirus.DetectedAt,
+				"status_changed_at":    patientVirus.StatusChangedAt,
+				"blood_test_result_id": patientVirus.BloodTestResultId,
+				"account_id":           patientVirus.AccountId,
+				"notes":                patientVirus.Notes,
+				"updated_at":           time.Now().UTC(),
+			}).
 			Error,
 	)
 	if _, ok := err.(*ErrRecordNotFound); ok {
 		return &app.ErrNotFound{
-			ResourceName: "medicine",
+			ResourceName: "patient_virus",
 		}
 	}
 	if err != nil {
@@ -557,91 +779,141 @@ func (r *Repository) DeleteMedicine(id uint) error {
 	return nil
 }
 
-func (r *Repository) ListAllMedicines() ([]models.Medicine, error) {
-	var medicines []models.Medicine
+func (r *Repository) GetPatientVirus(patientId, virusId uint) (models.PatientVirus, error) {
+	var patientVirus models.PatientVirus
 
 	err := tryWrapDbError(
 		r.client.
-			Model(new(models.Medicine)).
-			Find(&medicines).
+			Model(new(models.PatientVirus)).
+			Preload("Virus").
+			First(&patientVirus, "patient_id = ? AND virus_id = ?", patientId, virusId).
 			Error,
 	)
+	if _, ok := err.(*ErrRecordNotFound); ok {
+		return models.PatientVirus{}, &app.ErrNotFound{
+			ResourceName: "patient_virus",
+		}
+	}
 	if err != nil {
-		return nil, err
+		return models.PatientVirus{}, err
 	}
 
-	return medicines, nil
+	return patientVirus, nil
 }
 
-func (r *Repository) ListMedicinesByIds(ids []uint) ([]models.Medicine, error) {
-	var medicines []models.Medicine
+func (r *Repository) ListPatientViruses(patientId uint) ([]models.PatientVirus, error) {
+	var patientViruses []models.PatientVirus
 
 	err := tryWrapDbError(
 		r.client.
-			Model(new(models.Medicine)).
-			Where("id IN ?", ids).
-			Find(&medicines).
+			Model(new(models.PatientVirus)).
+			Preload("Virus").
+			Where("patient_id = ?", patientId).
+			Order("detected_at DESC").
+			Find(&patientViruses).
 			Error,
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	return medicines, nil
+	return patientViruses, nil
 }
 
-func (r *Repository) UpdateMedicineAmount(id uint, newAmount int) error {
+func (r *Repository) ListVirusesForPatient(patientId uint) ([]models.Virus, error) {
+	viruses := make([]models.Virus, 0)
+
+	query := fmt.Sprintf(`SELECT %s.id, viruses.name
+	FROM viruses
+		JOIN has_viruses ON %s.id = has_viruses.virus_id
+	WHERE has_viruses.patient_id = ?`, models.Virus{}.TableName(), models.Virus{}.TableName())
+
 	err := tryWrapDbError(
 		r.client.
-			Model(new(models.Medicine)).
-			Where("id = ?", id).
-			Update("amount", newAmount).
+			Raw(query, patientId).
+			Find(&viruses).
 			Error,
 	)
+	if err != nil {
+		return nil, err
+	}
 
-	if _, ok := err.(*ErrRecordNotFound); ok {
-		return &app.ErrNotFound{
-			ResourceName: "medicine",
-		}
+	return viruses, nil
+}
+
+func (r *Repository) CreateRelative(relative models.Relative) (models.Relative, error) {
+	relative.CreatedAt = time.Now().UTC()
+	relative.UpdatedAt = time.Now().UTC()
+
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.Relative)).
+			Create(&relative).
+			Error,
+	)
+	if err != nil {
+		return models.Relative{}, err
 	}
+
+	return relative, nil
+}
+
+func (r *Repository) UpdateRelative(relative models.Relative) error {
+	result := r.client.
+		Model(new(models.Relative)).
+		Where("id = ?", relative.Id).
+		Updates(map[string]any{
+			"name":           relative.Name,
+			"gender":         relative.Gender,
+			"carrier_status": relative.CarrierStatus,
+			"notes":          relative.Notes,
+			"account_id":     relative.AccountId,
+			"updated_at":     time.Now().UTC(),
+		})
+	err := tryWrapDbError(result.Error)
 	if err != nil {
 		return err
 	}
+	if result.RowsAffected == 0 {
+		return &app.ErrNotFound{
+			ResourceName: "relative",
+		}
+	}
 
 	return nil
 }
 
-func (r *Repository) GetMedicine(id uint) (models.Medicine, error) {
-	var medicine models.Medicine
+func (r *Repository) GetRelative(id uint) (models.Relative, error) {
+	var relative models.Relative
 
 	err := tryWrapDbError(
 		r.client.
-			Model(new(models.Medicine)).
-			First(&medicine, "id = ?", id).
+			Model(new(models.Relative)).
+			First(&relative, "id = ?", id).
 			Error,
 	)
 	if _, ok := err.(*ErrRecordNotFound); ok {
-		return models.Medicine{}, &app.ErrNotFound{
-			ResourceName: "medicine",
+		return models.Relative{}, &app.ErrNotFound{
+			ResourceName: "relative",
 		}
 	}
 	if err != nil {
-		return models.Medicine{}, err
+		return models.Relative{}, err
 	}
 
-	return medicine, nil
+	return relative, nil
 }
 
-func (r *Repository) DecrementMedicineAmount(id uint, amount int) error {
+func (r *Repository) DeleteRelative(id uint) error {
 	err := tryWrapDbError(
 		r.client.
-			Exec(fmt.Sprintf("UPDATE %s SET amount = amount - ? WHERE id = ?;", models.Medicine{}.TableName()), amount, id).
+			Model(new(models.Relative)).
+			Delete(&models.Relative{Id: id}, "id = ?", id).
 			Error,
 	)
-
 	if _, ok := err.(*ErrRecordNotFound); ok {
 		return &app.ErrNotFound{
-			ResourceName: "medicine",
+			ResourceName: "relative",
 		}
 	}
 	if err != nil {
@@ -651,176 +923,1107 @@ func (r *Repository) DecrementMedicineAmount(id uint, amount int) error {
 	return nil
 }
 
-func (r *Repository) findOrCreateLastPatientId() (models.PatientId, error) {
-	var patientIds []models.PatientId
+func (r *Repository) ListRelativesByIds(ids []uint) ([]models.Relative, error) {
+	relatives := make([]models.Relative, 0, len(ids))
+	if len(ids) == 0 {
+		return relatives, nil
+	}
+
 	err := tryWrapDbError(
 		r.client.
-			Model(new(models.PatientId)).
-			Order("id DESC").
-			Limit(1).
-			Find(&patientIds).
+			Model(new(models.Relative)).
+			Where("id IN ?", ids).
+			Find(&relatives).
 			Error,
 	)
-	lastPatientId := models.PatientId{
-		PublicId: 1,
-	}
-	if len(patientIds) > 0 {
-		lastPatientId = patientIds[0]
-	}
 	if err != nil {
-		err = tryWrapDbError(
-			r.client.
-				Model(new(models.PatientId)).
-				Create(&lastPatientId).
-				Error,
-		)
-		return lastPatientId, err
+		return nil, err
 	}
 
-	err = tryWrapDbError(
+	return relatives, nil
+}
+
+func (r *Repository) CreatePatientRelationship(relationship models.PatientRelationship) (models.PatientRelationship, error) {
+	relationship.CreatedAt = time.Now().UTC()
+	relationship.UpdatedAt = time.Now().UTC()
+
+	err := tryWrapDbError(
 		r.client.
-			Model(new(models.PatientId)).
-			Create(&models.PatientId{
-				PublicId: lastPatientId.PublicId + 1,
-			}).
+			Model(new(models.PatientRelationship)).
+			Create(&relationship).
 			Error,
 	)
+	if _, ok := err.(*ErrRecordExists); ok {
+		return models.PatientRelationship{}, &app.ErrExists{
+			ResourceName: "patient_relationship",
+		}
+	}
 	if err != nil {
-		return models.PatientId{}, err
+		return models.PatientRelationship{}, err
 	}
 
-	return lastPatientId, nil
+	return relationship, nil
 }
 
-func (r *Repository) CreatePatient(patient models.Patient) (models.Patient, error) {
-	lastPatientId, err := r.findOrCreateLastPatientId()
-	if err != nil {
-		return models.Patient{}, err
-	}
+func (r *Repository) GetPatientRelationship(id uint) (models.PatientRelationship, error) {
+	var relationship models.PatientRelationship
+
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.PatientRelationship)).
+			First(&relationship, "id = ?", id).
+			Error,
+	)
+	if _, ok := err.(*ErrRecordNotFound); ok {
+		return models.PatientRelationship{}, &app.ErrNotFound{
+			ResourceName: "patient_relationship",
+		}
+	}
+	if err != nil {
+		return models.PatientRelationship{}, err
+	}
+
+	return relationship, nil
+}
+
+func (r *Repository) DeletePatientRelationship(id uint) error {
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.PatientRelationship)).
+			Delete(&models.PatientRelationship{Id: id}, "id = ?", id).
+			Error,
+	)
+	if _, ok := err.(*ErrRecordNotFound); ok {
+		return &app.ErrNotFound{
+			ResourceName: "patient_relationship",
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *Repository) ListRelationshipsOf(patientIds, relativeIds []uint) ([]models.PatientRelationship, error) {
+	relationships := make([]models.PatientRelationship, 0)
+	if len(patientIds) == 0 && len(relativeIds) == 0 {
+		return relationships, nil
+	}
+
+	query := r.client.Model(new(models.PatientRelationship))
+	switch {
+	case len(relativeIds) == 0:
+		query = query.Where("patient_id IN ? OR relative_patient_id IN ?", patientIds, patientIds)
+	case len(patientIds) == 0:
+		query = query.Where("relative_id IN ?", relativeIds)
+	default:
+		query = query.Where("patient_id IN ? OR relative_patient_id IN ? OR relative_id IN ?", patientIds, patientIds, relativeIds)
+	}
+
+	err := tryWrapDbError(
+		query.
+			Order("id ASC").
+			Find(&relationships).
+			Error,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return relationships, nil
+}
+
+func (r *Repository) ListPatientsByIds(ids []uint) ([]models.Patient, error) {
+	patients := make([]models.Patient, 0, len(ids))
+	if len(ids) == 0 {
+		return patients, nil
+	}
+
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.Patient)).
+			Where("id IN ?", ids).
+			Find(&patients).
+			Error,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return patients, nil
+}
+
+func (r *Repository) ListPatientsWithLastName(lastName string) ([]models.Patient, error) {
+	var patients []models.Patient
+
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.Patient)).
+			Where("last_name = ?", lastName).
+			Find(&patients).
+			Error,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return patients, nil
+}
+
+func (r *Repository) CreateMedicine(medicine models.Medicine) (models.Medicine, error) {
+	medicine.CreatedAt = time.Now().UTC()
+	medicine.UpdatedAt = time.Now().UTC()
+
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.Medicine)).
+			Omit("Product").
+			Create(&medicine).
+			Error,
+	)
+	if _, ok := err.(*ErrRecordExists); ok {
+		return models.Medicine{}, &app.ErrExists{
+			ResourceName: "medicine",
+		}
+	}
+	if err != nil {
+		return models.Medicine{}, err
+	}
+
+	return medicine, nil
+
+}
+
+func (r *Repository) DeleteMedicine(id uint) error {
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.Medicine)).
+			Delete(&models.Medicine{Id: id}, "id = ?", id).
+			Error,
+	)
+	if _, ok := err.(*ErrRecordNotFound); ok {
+		return &app.ErrNotFound{
+			ResourceName: "medicine",
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *Repository) SetMedicineArchivedAt(id uint, archivedAt *time.Time) error {
+	return r.setArchivedAt(new(models.Medicine), "medicine", id, archivedAt)
+}
+
+func (r *Repository) GetMedicineUsage(id uint) (models.Usage, error) {
+	return r.countReferences(id, map[string]string{
+		"prescribed_medicines":   models.PrescribedMedicine{}.TableName() + ".medicine_id",
+		"patients_use_medicines": models.PatientUseMedicine{}.TableName() + ".medicine_id",
+		"stock_movements":        models.StockMovement{}.TableName() + ".medicine_id",
+	})
+}
+
+func (r *Repository) ListAllMedicines() ([]models.Medicine, error) {
+	var medicines []models.Medicine
+
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.Medicine)).
+			Preload("Product").
+			Find(&medicines).
+			Error,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return medicines, nil
+}
+
+func (r *Repository) ListMedicinesByIds(ids []uint) ([]models.Medicine, error) {
+	var medicines []models.Medicine
+
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.Medicine)).
+			Preload("Product").
+			Where("id IN ?", ids).
+			Find(&medicines).
+			Error,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return medicines, nil
+}
+
+func (r *Repository) ListMedicineBatchesInStock(productId uint) ([]models.Medicine, error) {
+	var medicines []models.Medicine
+
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.Medicine)).
+			Preload("Product").
+			Where("product_id = ? AND amount > 0 AND archived_at IS NULL", productId).
+			Order("expires_at ASC").
+			Find(&medicines).
+			Error,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return medicines, nil
+}
+
+func (r *Repository) FindOrCreateMedicineProduct(product models.MedicineProduct) (models.MedicineProduct, error) {
+	product.CreatedAt = time.Now().UTC()
+	product.UpdatedAt = time.Now().UTC()
+
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.MedicineProduct)).
+			Where(map[string]any{
+				"name":         product.Name,
+				"dose":         product.Dose,
+				"unit":         product.Unit,
+				"manufacturer": product.Manufacturer,
+				"factor_type":  product.FactorType,
+			}).
+			FirstOrCreate(&product).
+			Error,
+	)
+	if err != nil {
+		return models.MedicineProduct{}, err
+	}
+
+	return product, nil
+}
+
+func (r *Repository) UpdateMedicineProductMinStock(id uint, minStock int) error {
+	result := r.client.
+		Model(new(models.MedicineProduct)).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"min_stock":  minStock,
+			"updated_at": time.Now().UTC(),
+		})
+	err := tryWrapDbError(result.Error)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected == 0 {
+		return &app.ErrNotFound{
+			ResourceName: "medicine_product",
+		}
+	}
+
+	return nil
+}
+
+func (r *Repository) ListMedicineProductsDispensations(since time.Time) ([]models.MedicineProductDispensation, error) {
+	var dispensations []models.MedicineProductDispensation
+
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.StockMovement)).
+			Select("medicines.product_id AS product_id, -SUM(stock_movements.quantity) AS dispensed").
+			Joins("JOIN medicines ON medicines.id = stock_movements.medicine_id").
+			Where("stock_movements.type = ? AND stock_movements.created_at >= ?", models.StockMovementTypeDispensation, since).
+			Group("medicines.product_id").
+			Scan(&dispensations).
+			Error,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return dispensations, nil
+}
+
+func (r *Repository) GetMedicineProduct(id uint) (models.MedicineProduct, error) {
+	var product models.MedicineProduct
+
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.MedicineProduct)).
+			Preload("Batches", func(db *gorm.DB) *gorm.DB {
+				return db.Order("expires_at ASC")
+			}).
+			First(&product, "id = ?", id).
+			Error,
+	)
+	if _, ok := err.(*ErrRecordNotFound); ok {
+		return models.MedicineProduct{}, &app.ErrNotFound{
+			ResourceName: "medicine_product",
+		}
+	}
+	if err != nil {
+		return models.MedicineProduct{}, err
+	}
+
+	return product, nil
+}
+
+func (r *Repository) ListAllMedicineProducts() ([]models.MedicineProduct, error) {
+	var products []models.MedicineProduct
+
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.MedicineProduct)).
+			Preload("Batches", func(db *gorm.DB) *gorm.DB {
+				return db.Order("expires_at ASC")
+			}).
+			Order("name ASC").
+			Find(&products).
+			Error,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return products, nil
+}
+
+func (r *Repository) ListMedicinesInStockExpiringBefore(before time.Time) ([]models.Medicine, error) {
+	var medicines []models.Medicine
+
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.Medicine)).
+			Preload("Product").
+			Where("expires_at < ? AND amount > 0 AND archived_at IS NULL", before).
+			Order("expires_at ASC").
+			Find(&medicines).
+			Error,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return medicines, nil
+}
+
+func (r *Repository) GetMedicine(id uint) (models.Medicine, error) {
+	var medicine models.Medicine
+
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.Medicine)).
+			Preload("Product").
+			First(&medicine, "id = ?", id).
+			Error,
+	)
+	if _, ok := err.(*ErrRecordNotFound); ok {
+		return models.Medicine{}, &app.ErrNotFound{
+			ResourceName: "medicine",
+		}
+	}
+	if err != nil {
+		return models.Medicine{}, err
+	}
+
+	return medicine, nil
+}
+
+// GetMedicineForUpdate gets the medicine and locks its row until the transaction ends,
+// so that its amount can't change between reading it and writing a movement based on it.
+func (r *Repository) GetMedicineForUpdate(id uint) (models.Medicine, error) {
+	var medicine models.Medicine
+
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.Medicine)).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&medicine, "id = ?", id).
+			Error,
+	)
+	if _, ok := err.(*ErrRecordNotFound); ok {
+		return models.Medicine{}, &app.ErrNotFound{
+			ResourceName: "medicine",
+		}
+	}
+	if err != nil {
+		return models.Medicine{}, err
+	}
+
+	return medicine, nil
+}
+
+// CreateStockMovement applies the movement's quantity to the medicine's amount and records it in the ledger,
+// the amount is changed in a single conditional statement, so concurrent movements can't drive it below zero.
+func (r *Repository) CreateStockMovement(movement models.StockMovement) (models.StockMovement, error) {
+	movement.CreatedAt = time.Now().UTC()
+
+	err := r.client.Transaction(func(tx *gorm.DB) error {
+		result := tx.
+			Exec(fmt.Sprintf("UPDATE %s SET amount = amount + ? WHERE id = ? AND amount + ? >= 0;", models.Medicine{}.TableName()), movement.Quantity, movement.MedicineId, movement.Quantity)
+		err := tryWrapDbError(result.Error)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected == 0 {
+			medicine, err := (&Repository{client: tx}).GetMedicine(movement.MedicineId)
+			if err != nil {
+				return err
+			}
+
+			return &app.ErrInsufficientMedicine{
+				MedicineName:    medicine.Product.Name,
+				ExceedingAmount: -movement.Quantity,
+				LeftPackages:    medicine.Amount,
+			}
+		}
+
+		return tryWrapDbError(
+			tx.
+				Model(new(models.StockMovement)).
+				Create(&movement).
+				Error,
+		)
+	})
+	if err != nil {
+		return models.StockMovement{}, err
+	}
+
+	return movement, nil
+}
+
+func (r *Repository) ListMedicineStockMovements(filter models.StockMovementFilter) ([]models.StockMovement, int64, error) {
+	filteredMovements := func() *gorm.DB {
+		query := r.client.
+			Model(new(models.StockMovement)).
+			Where("medicine_id = ?", filter.MedicineId)
+		if filter.Type != "" {
+			query = query.Where("type = ?", filter.Type)
+		}
+		if !filter.From.IsZero() {
+			query = query.Where("created_at >= ?", filter.From)
+		}
+		if !filter.To.IsZero() {
+			query = query.Where("created_at < ?", filter.To)
+		}
+		return query
+	}
+
+	var total int64
+	err := tryWrapDbError(
+		filteredMovements().
+			Count(&total).
+			Error,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var movements []models.StockMovement
+	err = tryWrapDbError(
+		filteredMovements().
+			Order("id DESC").
+			Offset(filter.Offset).
+			Limit(filter.Limit).
+			Find(&movements).
+			Error,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return movements, total, nil
+}
+
+func (r *Repository) GetMedicineLedgerAmount(medicineId uint) (int, error) {
+	var ledgerAmount int
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.StockMovement)).
+			Select("COALESCE(SUM(quantity), 0)").
+			Where("medicine_id = ?", medicineId).
+			Scan(&ledgerAmount).
+			Error,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return ledgerAmount, nil
+}
+
+func (r *Repository) findOrCreateLastPatientId() (models.PatientId, error) {
+	var patientIds []models.PatientId
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.PatientId)).
+			Order("id DESC").
+			Limit(1).
+			Find(&patientIds).
+			Error,
+	)
+	lastPatientId := models.PatientId{
+		PublicId: 1,
+	}
+	if len(patientIds) > 0 {
+		lastPatientId = patientIds[0]
+	}
+	if err != nil {
+		err = tryWrapDbError(
+			r.client.
+				Model(new(models.PatientId)).
+				Create(&lastPatientId).
+				Error,
+		)
+		return lastPatientId, err
+	}
+
+	err = tryWrapDbError(
+		r.client.
+			Model(new(models.PatientId)).
+			Create(&models.PatientId{
+				PublicId: lastPatientId.PublicId + 1,
+			}).
+			Error,
+	)
+	if err != nil {
+		return models.PatientId{}, err
+	}
+
+	return lastPatientId, nil
+}
+
+func (r *Repository) CreatePatient(patient models.Patient) (models.Patient, error) {
+	lastPatientId, err := r.findOrCreateLastPatientId()
+	if err != nil {
+		return models.Patient{}, err
+	}
 
 	patient.PublicId = fmt.Sprintf("%06d", lastPatientId.PublicId)
 	patient.CreatedAt = time.Now().UTC()
 	patient.UpdatedAt = time.Now().UTC()
 	patient.FillEmptyFieldsUsingPublicId()
 
-	if patient.NationalId == "" {
-		patient.NationalId = "please_change_" + patient.PublicId
+	if patient.NationalId == "" {
+		patient.NationalId = "please_change_" + patient.PublicId
+	}
+	patient.SetNormalizedFields()
+
+	err = tryWrapDbError(
+		r.client.
+			Model(new(models.Patient)).
+			Create(&patient).
+			Error,
+	)
+	if _, ok := err.(*ErrRecordExists); ok {
+		return models.Patient{}, &app.ErrExists{
+			ResourceName: "patient",
+		}
+	}
+	if err != nil {
+		return models.Patient{}, err
+	}
+
+	return patient, nil
+}
+
+func (r *Repository) GetPatientById(id uint) (models.Patient, error) {
+	var patient models.Patient
+
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.Patient)).
+			Preload("Residency").
+			Preload("PlaceOfBirth").
+			First(&patient, "id = ?", id).
+			Error,
+	)
+	if _, ok := err.(*ErrRecordNotFound); ok {
+		return models.Patient{}, &app.ErrNotFound{
+			ResourceName: "patient",
+		}
+	}
+	if err != nil {
+		return models.Patient{}, err
+	}
+
+	return patient, nil
+}
+
+func (r *Repository) GetPatientByPublicId(publicId string) (models.Patient, error) {
+	var patient models.Patient
+
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.Patient)).
+			Preload("Residency").
+			Preload("PlaceOfBirth").
+			First(&patient, "public_id = ?", publicId).
+			Error,
+	)
+	if _, ok := err.(*ErrRecordNotFound); ok {
+		return models.Patient{}, &app.ErrNotFound{
+			ResourceName: "patient",
+		}
+	}
+	if err != nil {
+		return models.Patient{}, err
+	}
+
+	return patient, nil
+}
+
+func (r *Repository) FindPatientsByVisitDateRange(filter models.VisitDateRangeFilter) ([]models.PatientVisitsSummary, int64, error) {
+	visitsInRange := func() *gorm.DB {
+		query := r.client.
+			Model(new(models.Visit)).
+			Where("created_at >= ? AND created_at < ?", filter.From, filter.To)
+		if filter.Reason != "" {
+			query = query.Where("reason = ?", filter.Reason)
+		}
+		return query
+	}
+
+	var total int64
+	err := tryWrapDbError(
+		visitsInRange().
+			Distinct("patient_id").
+			Count(&total).
+			Error,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var visitsSummaries []struct {
+		PatientId   uint
+		VisitsCount int
+		LastVisitAt time.Time
+	}
+
+	err = tryWrapDbError(
+		visitsInRange().
+			Select("patient_id, COUNT(*) AS visits_count, MAX(created_at) AS last_visit_at").
+			Group("patient_id").
+			Order("last_visit_at DESC, patient_id DESC").
+			Offset(filter.Offset).
+			Limit(filter.Limit).
+			Scan(&visitsSummaries).
+			Error,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	patientIds := make([]uint, 0, len(visitsSummaries))
+	for _, vs := range visitsSummaries {
+		patientIds = append(patientIds, vs.PatientId)
+	}
+
+	var patients []models.Patient
+	if len(patientIds) > 0 {
+		err = tryWrapDbError(
+			r.client.
+				Model(new(models.Patient)).
+				Preload("Residency").
+				Preload("PlaceOfBirth").
+				Where("id IN ?", patientIds).
+				Find(&patients).
+				Error,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	patientsMapped := make(map[uint]models.Patient, len(patients))
+	for _, patient := range patients {
+		patientsMapped[patient.Id] = patient
+	}
+
+	summaries := make([]models.PatientVisitsSummary, 0, len(visitsSummaries))
+	for _, vs := range visitsSummaries {
+		patient, ok := patientsMapped[vs.PatientId]
+		if !ok {
+			continue
+		}
+		summaries = append(summaries, models.PatientVisitsSummary{
+			Patient:     patient,
+			VisitsCount: vs.VisitsCount,
+			LastVisitAt: vs.LastVisitAt,
+		})
+	}
+
+	return summaries, total, nil
+}
+
+func (r *Repository) FindPatientsByFields(patientIndexFields models.PatientIndexFields) ([]models.Patient, error) {
+	findQuery := make([]string, 0, 9)
+	findArgs := make([]any, 0, 9)
+	if patientIndexFields.FirstName != "" {
+		findQuery = append(findQuery, "first_name_normalized LIKE ?")
+		findArgs = append(findArgs, likeArg(models.NormalizeArabic(patientIndexFields.FirstName)))
+	}
+	if patientIndexFields.LastName != "" {
+		findQuery = append(findQuery, "last_name_normalized LIKE ?")
+		findArgs = append(findArgs, likeArg(models.NormalizeArabic(patientIndexFields.LastName)))
+	}
+	if patientIndexFields.FatherName != "" {
+		findQuery = append(findQuery, "father_name_normalized LIKE ?")
+		findArgs = append(findArgs, likeArg(models.NormalizeArabic(patientIndexFields.FatherName)))
+	}
+	if patientIndexFields.MotherName != "" {
+		findQuery = append(findQuery, "mother_name_normalized LIKE ?")
+		findArgs = append(findArgs, likeArg(models.NormalizeArabic(patientIndexFields.MotherName)))
+	}
+	if phoneNumber := models.OnlyDigits(patientIndexFields.PhoneNumber); phoneNumber != "" {
+		findQuery = append(findQuery, "phone_number_normalized LIKE ?")
+		findArgs = append(findArgs, likeArg(phoneNumber))
+	}
+	if patientIndexFields.NationalId != "" {
+		findQuery = append(findQuery, "national_id = ?")
+		findArgs = append(findArgs, patientIndexFields.NationalId)
+	}
+	if patientIndexFields.PublicId != "" {
+		findQuery = append(findQuery, "public_id = ?")
+		findArgs = append(findArgs, patientIndexFields.PublicId)
+	}
+	if patientIndexFields.HemophiliaType != "" {
+		findQuery = append(findQuery, "hemophilia_type = ?")
+		findArgs = append(findArgs, patientIndexFields.HemophiliaType)
+	}
+	if patientIndexFields.HemophiliaSeverity != "" {
+		findQuery = append(findQuery, "hemophilia_severity = ?")
+		findArgs = append(findArgs, patientIndexFields.HemophiliaSeverity)
+	}
+
+	var patients []models.Patient
+
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.Patient)).
+			Where(strings.Join(findQuery, " AND "), findArgs...).
+			Find(&patients).
+			Error,
+	)
+	if _, ok := err.(*ErrRecordNotFound); ok {
+		return nil, &app.ErrNotFound{
+			ResourceName: "patient",
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return patients, nil
+}
+
+func (r *Repository) ListPatients(filter models.PatientListFilter) ([]models.Patient, int64, error) {
+	listQuery := make([]string, 0, 12)
+	listArgs := make([]any, 0, 12)
+	for _, term := range filter.Terms {
+		termQuery := []string{
+			"first_name_normalized LIKE ?",
+			"last_name_normalized LIKE ?",
+			"father_name_normalized LIKE ?",
+			"mother_name_normalized LIKE ?",
+			"public_id LIKE ?",
+			"national_id LIKE ?",
+		}
+		listArgs = append(listArgs,
+			likeArg(term.Normalized),
+			likeArg(term.Normalized),
+			likeArg(term.Normalized),
+			likeArg(term.Normalized),
+			prefixLikeArg(term.Normalized),
+			prefixLikeArg(term.Normalized),
+		)
+		if term.Digits != "" {
+			termQuery = append(termQuery, "phone_number_normalized LIKE ?")
+			listArgs = append(listArgs, likeArg(term.Digits))
+		}
+		if term.Skeleton != "" {
+			termQuery = append(termQuery, "name_skeletons LIKE ?")
+			listArgs = append(listArgs, likeArg(term.Skeleton))
+		}
+		listQuery = append(listQuery, "("+strings.Join(termQuery, " OR ")+")")
+	}
+	if len(filter.Ids) > 0 {
+		listQuery = append(listQuery, "id IN ?")
+		listArgs = append(listArgs, filter.Ids)
+	}
+	if filter.Gender != nil {
+		listQuery = append(listQuery, "gender = ?")
+		listArgs = append(listArgs, *filter.Gender)
+	}
+	if filter.Governorate != "" {
+		listQuery = append(listQuery, "residency_id IN (SELECT id FROM addresses WHERE LOWER(governorate) = LOWER(?))")
+		listArgs = append(listArgs, filter.Governorate)
+	}
+	if filter.DiagnosisId != 0 {
+		listQuery = append(listQuery,
+			"id IN (SELECT patient_id FROM diagnoses_results WHERE diagnosis_id = ? AND replaced_by_id = 0 AND revoked_at IS NULL)")
+		listArgs = append(listArgs, filter.DiagnosisId)
+	}
+	if filter.HemophiliaType != "" {
+		listQuery = append(listQuery, "hemophilia_type = ?")
+		listArgs = append(listArgs, filter.HemophiliaType)
+	}
+	if filter.HemophiliaSeverity != "" {
+		listQuery = append(listQuery, "hemophilia_severity = ?")
+		listArgs = append(listArgs, filter.HemophiliaSeverity)
+	}
+	if !filter.BornAfter.IsZero() {
+		listQuery = append(listQuery, "date_of_birth > ?")
+		listArgs = append(listArgs, filter.BornAfter)
+	}
+	if !filter.BornBefore.IsZero() {
+		listQuery = append(listQuery, "date_of_birth <= ?")
+		listArgs = append(listArgs, filter.BornBefore)
+	}
+	if !filter.CreatedFrom.IsZero() {
+		listQuery = append(listQuery, "created_at >= ?")
+		listArgs = append(listArgs, filter.CreatedFrom)
+	}
+	if !filter.CreatedTo.IsZero() {
+		listQuery = append(listQuery, "created_at < ?")
+		listArgs = append(listArgs, filter.CreatedTo)
+	}
+	if !filter.VisitedFrom.IsZero() || !filter.VisitedTo.IsZero() {
+		visitQuery := []string{"1 = 1"}
+		if !filter.VisitedFrom.IsZero() {
+			visitQuery = append(visitQuery, "created_at >= ?")
+			listArgs = append(listArgs, filter.VisitedFrom)
+		}
+		if !filter.VisitedTo.IsZero() {
+			visitQuery = append(visitQuery, "created_at < ?")
+			listArgs = append(listArgs, filter.VisitedTo)
+		}
+		listQuery = append(listQuery, "id IN (SELECT patient_id FROM visits WHERE "+strings.Join(visitQuery, " AND ")+")")
 	}
 
-	err = tryWrapDbError(
-		r.client.
-			Model(new(models.Patient)).
-			Create(&patient).
+	patientsInList := func() *gorm.DB {
+		query := r.client.
+			Model(new(models.Patient))
+		if len(listQuery) > 0 {
+			query = query.Where(strings.Join(listQuery, " AND "), listArgs...)
+		}
+		return query
+	}
+
+	var total int64
+	err := tryWrapDbError(
+		patientsInList().
+			Count(&total).
 			Error,
 	)
-	if _, ok := err.(*ErrRecordExists); ok {
-		return models.Patient{}, &app.ErrExists{
-			ResourceName: "patient",
+	if err != nil {
+		return nil, 0, err
+	}
+
+	column := filter.Sort.Column()
+	if column == "" {
+		column = models.PatientSortCreatedAt.Column()
+	}
+	direction, comparison := "ASC", ">"
+	if filter.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	query := patientsInList()
+	if filter.Cursor != nil {
+		value, err := filter.Cursor.SortValue()
+		if err != nil {
+			return nil, 0, err
 		}
+		query = query.Where(
+			fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", column, comparison),
+			value, value, filter.Cursor.Id,
+		)
 	}
+
+	var patients []models.Patient
+
+	err = tryWrapDbError(
+		query.
+			Preload("Residency").
+			Preload("PlaceOfBirth").
+			Order(fmt.Sprintf("%s %s, id %s", column, direction, direction)).
+			Limit(filter.Limit).
+			Find(&patients).
+			Error,
+	)
 	if err != nil {
-		return models.Patient{}, err
+		return nil, 0, err
 	}
 
-	return patient, nil
+	return patients, total, nil
 }
 
-func (r *Repository) GetPatientById(id uint) (models.Patient, error) {
-	var patient models.Patient
+func (r *Repository) ListPatientIdsByIdentifiers(terms []models.PatientSearchTerm) ([]uint, error) {
+	if len(terms) == 0 {
+		return []uint{}, nil
+	}
+
+	identifiers := make([]string, 0, len(terms))
+	for _, term := range terms {
+		identifiers = append(identifiers, term.Normalized)
+	}
+	query := []string{"public_id IN ?", "national_id IN ?"}
+	args := []any{identifiers, identifiers}
+	for _, term := range terms {
+		if phoneKey := models.PhoneKey(term.Digits); len(phoneKey) == models.PhoneSuffixLength {
+			query = append(query, "phone_number_normalized LIKE ?")
+			args = append(args, "%"+phoneKey)
+		}
+	}
+
+	ids := make([]uint, 0)
 
 	err := tryWrapDbError(
 		r.client.
 			Model(new(models.Patient)).
-			Preload("Residency").
-			Preload("PlaceOfBirth").
-			First(&patient, "id = ?", id).
+			Where(strings.Join(query, " OR "), args...).
+			Pluck("id", &ids).
+			Error,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (r *Repository) UpdatePatientHemophilia(id uint, classification models.HemophiliaClassification) error {
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.Patient)).
+			Where("id = ?", id).
+			Updates(map[string]any{
+				"hemophilia_type":                   classification.Type,
+				"hemophilia_severity":               classification.Severity,
+				"factor_level":                      classification.FactorLevel,
+				"factor_level_measured_at":          classification.MeasuredAt,
+				"factor_level_blood_test_result_id": classification.BloodTestResultId,
+				"updated_at":                        time.Now().UTC(),
+			}).
 			Error,
 	)
 	if _, ok := err.(*ErrRecordNotFound); ok {
-		return models.Patient{}, &app.ErrNotFound{
+		return &app.ErrNotFound{
 			ResourceName: "patient",
 		}
 	}
 	if err != nil {
-		return models.Patient{}, err
+		return err
 	}
 
-	return patient, nil
+	return nil
 }
 
-func (r *Repository) GetPatientByPublicId(publicId string) (models.Patient, error) {
-	var patient models.Patient
-
+func (r *Repository) UpdatePatientInhibitor(id uint, classification models.InhibitorClassification) error {
 	err := tryWrapDbError(
 		r.client.
 			Model(new(models.Patient)).
-			Preload("Residency").
-			Preload("PlaceOfBirth").
-			First(&patient, "public_id = ?", publicId).
+			Where("id = ?", id).
+			Updates(map[string]any{
+				"inhibitor_positive":    classification.Positive,
+				"inhibitor_responder":   classification.Responder,
+				"inhibitor_titer":       classification.LatestTiter,
+				"inhibitor_peak_titer":  classification.PeakTiter,
+				"inhibitor_measured_at": classification.MeasuredAt,
+				"updated_at":            time.Now().UTC(),
+			}).
 			Error,
 	)
 	if _, ok := err.(*ErrRecordNotFound); ok {
-		return models.Patient{}, &app.ErrNotFound{
+		return &app.ErrNotFound{
 			ResourceName: "patient",
 		}
 	}
 	if err != nil {
-		return models.Patient{}, err
+		return err
 	}
 
-	return patient, nil
+	return nil
 }
 
-func (r *Repository) FindPatientsByVisitDateRange(from, to time.Time) ([]models.Patient, error) {
-	return nil, errors.New("not inmplemented")
-}
+func (r *Repository) ListPatientsWithInhibitors() ([]models.Patient, error) {
+	var patients []models.Patient
 
-func (r *Repository) FindPatientsByFields(patientIndexFields models.PatientIndexFields) ([]models.Patient, error) {
-	findQuery := make([]string, 0, 9)
-	findArgs := make([]any, 0, 9)
-	if patientIndexFields.FirstName != "" {
-		findQuery = append(findQuery, "LOWER(first_name) LIKE LOWER(?)")
-		findArgs = append(findArgs, likeArg(patientIndexFields.FirstName))
-	}
-	if patientIndexFields.LastName != "" {
-		findQuery = append(findQuery, "LOWER(last_name) LIKE LOWER(?)")
-		findArgs = append(findArgs, likeArg(patientIndexFields.LastName))
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.Patient)).
+			Where("inhibitor_positive = ?", true).
+			Order("inhibitor_titer DESC").
+			Find(&patients).
+			Error,
+	)
+	if err != nil {
+		return nil, err
 	}
-	if patientIndexFields.FatherName != "" {
-		findQuery = append(findQuery, "LOWER(father_name) LIKE LOWER(?)")
-		findArgs = append(findArgs, likeArg(patientIndexFields.FatherName))
+
+	return patients, nil
+}
+
+func (r *Repository) DeletePatient(id uint) error {
+	err := tryWrapDbError(
+		r.client.
+			Exec("DELETE FROM did_blood_tests WHERE patient_id = ?", id).
+			Error,
+	)
+	if err != nil {
+		return err
 	}
-	if patientIndexFields.MotherName != "" {
-		findQuery = append(findQuery, "LOWER(mother_name) LIKE LOWER(?)")
-		findArgs = append(findArgs, likeArg(patientIndexFields.MotherName))
+
+	err = tryWrapDbError(
+		r.client.
+			Exec("DELETE FROM has_viruses WHERE patient_id = ?", id).
+			Error,
+	)
+	if err != nil {
+		return err
 	}
-	if patientIndexFields.PhoneNumber != "" {
-		findQuery = append(findQuery, "LOWER(phone_number) LIKE LOWER(?)")
-		findArgs = append(findArgs, likeArg(patientIndexFields.PhoneNumber))
+
+	err = tryWrapDbError(
+		r.client.
+			Exec("DELETE FROM patient_relationships WHERE patient_id = ? OR relative_patient_id = ?", id, id).
+			Error,
+	)
+	if err != nil {
+		return err
 	}
-	if patientIndexFields.NationalId != "" {
-		findQuery = append(findQuery, "national_id = ?")
-		findArgs = append(findArgs, patientIndexFields.NationalId)
+
+	err = tryWrapDbError(
+		r.client.
+			Model(new(models.Patient)).
+			Delete(&models.Patient{Id: id}, "id = ?", id).
+			Error,
+	)
+	if _, ok := err.(*ErrRecordNotFound); ok {
+		return &app.ErrNotFound{
+			ResourceName: "patient",
+		}
 	}
-	if patientIndexFields.PublicId != "" {
-		findQuery = append(findQuery, "public_id = ?")
-		findArgs = append(findArgs, patientIndexFields.PublicId)
+	if err != nil {
+		return err
 	}
 
+	return nil
+}
+
+func (r *Repository) ListAllPatients() ([]models.Patient, error) {
 	var patients []models.Patient
 
 	err := tryWrapDbError(
 		r.client.
 			Model(new(models.Patient)).
-			Where(strings.Join(findQuery, " AND "), findArgs...).
+			Select("id", "public_id", "national_id", "first_name", "last_name", "father_name", "mother_name", "date_of_birth", "gender", "phone_number", "created_at").
+			Order("id ASC").
 			Find(&patients).
 			Error,
 	)
-	if _, ok := err.(*ErrRecordNotFound); ok {
-		return nil, &app.ErrNotFound{
-			ResourceName: "patient",
-		}
-	}
 	if err != nil {
 		return nil, err
 	}
@@ -828,14 +2031,36 @@ func (r *Repository) FindPatientsByFields(patientIndexFields models.PatientIndex
 	return patients, nil
 }
 
-func (r *Repository) ListLastPatients(limit int) ([]models.Patient, error) {
+func (r *Repository) ListDuplicateCandidatePatients() ([]models.Patient, error) {
 	var patients []models.Patient
 
+	phoneKey := fmt.Sprintf("RIGHT(phone_number_normalized, %d)", models.PhoneSuffixLength)
+	nameKey := "SUBSTRING_INDEX(name_skeletons, ' ', 2)"
+
 	err := tryWrapDbError(
 		r.client.
 			Model(new(models.Patient)).
-			Order("created_at DESC").
-			Limit(limit).
+			Select("id", "public_id", "national_id", "first_name", "last_name", "father_name", "mother_name", "date_of_birth", "gender", "phone_number", "created_at").
+			Where(
+				r.client.
+					Where("phone_number_normalized <> '' AND "+phoneKey+" IN (?)",
+						r.client.
+							Model(new(models.Patient)).
+							Select(phoneKey).
+							Where("phone_number_normalized <> ''").
+							Group(phoneKey).
+							Having("COUNT(*) > 1"),
+					).
+					Or("name_skeletons <> '' AND "+nameKey+" IN (?)",
+						r.client.
+							Model(new(models.Patient)).
+							Select(nameKey).
+							Where("name_skeletons <> ''").
+							Group(nameKey).
+							Having("COUNT(*) > 1"),
+					),
+			).
+			Order("id ASC").
 			Find(&patients).
 			Error,
 	)
@@ -846,41 +2071,234 @@ func (r *Repository) ListLastPatients(limit int) ([]models.Patient, error) {
 	return patients, nil
 }
 
-func (r *Repository) DeletePatient(id uint) error {
+func (r *Repository) MovePatientRecords(fromId, toId uint) error {
+	err := r.mergePatientInfections(fromId, toId)
+	if err != nil {
+		return err
+	}
+
+	statements := []string{
+		"UPDATE visits SET patient_id = @to WHERE patient_id = @from",
+		"UPDATE prescriptions SET patient_id = @to WHERE patient_id = @from",
+		"UPDATE prescribed_medicines SET patient_id = @to WHERE patient_id = @from",
+		"UPDATE patients_use_medicines SET patient_id = @to WHERE patient_id = @from",
+		"UPDATE blood_test_results SET patient_id = @to WHERE patient_id = @from",
+		"UPDATE did_blood_tests SET patient_id = @to WHERE patient_id = @from",
+		"UPDATE diagnoses_results SET patient_id = @to WHERE patient_id = @from",
+		"UPDATE joints_evaluations SET patient_id = @to WHERE patient_id = @from",
+		"UPDATE patient_joint_evaluation SET patient_id = @to WHERE patient_id = @from",
+		"UPDATE bleed_episodes SET patient_id = @to WHERE patient_id = @from",
+		"UPDATE alerts SET patient_id = @to WHERE patient_id = @from",
+		"UPDATE patient_field_changes SET patient_id = @to WHERE patient_id = @from",
+		"UPDATE patient_aliases SET patient_id = @to WHERE patient_id = @from",
+		"UPDATE has_viruses SET patient_id = @to WHERE patient_id = @from",
+		"DELETE FROM patient_relationships WHERE (patient_id = @from AND relative_patient_id = @to) OR (patient_id = @to AND relative_patient_id = @from)",
+		"UPDATE IGNORE patient_relationships SET patient_id = @to WHERE patient_id = @from",
+		"UPDATE IGNORE patient_relationships SET relative_patient_id = @to WHERE relative_patient_id = @from",
+	}
+
+	for _, statement := range statements {
+		err = tryWrapDbError(
+			r.client.
+				Exec(statement, map[string]any{"from": fromId, "to": toId}).
+				Error,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mergePatientInfections merges the infections of the patient with fromId into the infections of the patient with toId with the same viruses,
+// and deletes them, so that the rest of the patient's infections can be moved.
+func (r *Repository) mergePatientInfections(fromId, toId uint) error {
+	var infections []models.PatientVirus
 	err := tryWrapDbError(
 		r.client.
-			Exec("DELETE FROM did_blood_tests WHERE patient_id = ?", id).
+			Model(new(models.PatientVirus)).
+			Where("patient_id IN ?", []uint{fromId, toId}).
+			Find(&infections).
 			Error,
 	)
 	if err != nil {
 		return err
 	}
 
-	err = tryWrapDbError(
+	survivorInfections := make(map[uint]models.PatientVirus)
+	for _, infection := range infections {
+		if infection.PatientId == toId {
+			survivorInfections[infection.VirusId] = infection
+		}
+	}
+
+	for _, infection := range infections {
+		survivorInfection, ok := survivorInfections[infection.VirusId]
+		if infection.PatientId != fromId || !ok {
+			continue
+		}
+
+		merged := models.MergeInfections(survivorInfection, infection)
+		err = tryWrapDbError(
+			r.client.
+				Model(new(models.PatientVirus)).
+				Where("patient_id = ? AND virus_id = ?", toId, infection.VirusId).
+				Updates(map[string]any{
+					"status":               merged.Status,
+					"detected_at":          merged.DetectedAt,
+					"status_changed_at":    merged.StatusChangedAt,
+					"blood_test_result_id": merged.BloodTestResultId,
+					"account_id":           merged.AccountId,
+					"notes":                merged.Notes,
+					"updated_at":           time.Now().UTC(),
+				}).
+				Error,
+		)
+		if err != nil {
+			return err
+		}
+
+		err = tryWrapDbError(
+			r.client.
+				Exec("DELETE FROM has_viruses WHERE patient_id = ? AND virus_id = ?", fromId, infection.VirusId).
+				Error,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *Repository) CreatePatientAlias(alias models.PatientAlias) (models.PatientAlias, error) {
+	alias.CreatedAt = time.Now().UTC()
+
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.PatientAlias)).
+			Create(&alias).
+			Error,
+	)
+	if _, ok := err.(*ErrRecordExists); ok {
+		return models.PatientAlias{}, &app.ErrExists{
+			ResourceName: "patient_alias",
+		}
+	}
+	if err != nil {
+		return models.PatientAlias{}, err
+	}
+
+	return alias, nil
+}
+
+func (r *Repository) GetPatientAliasByPublicId(publicId string) (models.PatientAlias, error) {
+	var alias models.PatientAlias
+
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.PatientAlias)).
+			First(&alias, "alias_public_id = ?", publicId).
+			Error,
+	)
+	if _, ok := err.(*ErrRecordNotFound); ok {
+		return models.PatientAlias{}, &app.ErrNotFound{
+			ResourceName: "patient_alias",
+		}
+	}
+	if err != nil {
+		return models.PatientAlias{}, err
+	}
+
+	return alias, nil
+}
+
+func (r *Repository) UpdatePatient(id uint, patient models.Patient) error {
+	patient.SetNormalizedFields()
+
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.Patient)).
+			Where("id = ?", id).
+			Updates(map[string]any{
+				"national_id":             patient.NationalId,
+				"nationality":             patient.Nationality,
+				"first_name":              patient.FirstName,
+				"last_name":               patient.LastName,
+				"father_name":             patient.FatherName,
+				"mother_name":             patient.MotherName,
+				"place_of_birth_id":       patient.PlaceOfBirthId,
+				"date_of_birth":           patient.DateOfBirth,
+				"residency_id":            patient.ResidencyId,
+				"gender":                  patient.Gender,
+				"phone_number":            patient.PhoneNumber,
+				"family_history_exists":   patient.FamilyHistoryExists,
+				"carrier_status":          patient.CarrierStatus,
+				"first_visit_reason":      patient.FirstVisitReason,
+				"bat_score":               patient.BATScore,
+				"first_name_normalized":   patient.FirstNameNormalized,
+				"last_name_normalized":    patient.LastNameNormalized,
+				"father_name_normalized":  patient.FatherNameNormalized,
+				"mother_name_normalized":  patient.MotherNameNormalized,
+				"name_skeletons":          patient.NameSkeletons,
+				"phone_number_normalized": patient.PhoneNumberNormalized,
+				"updated_at":              time.Now().UTC(),
+			}).
+			Error,
+	)
+	if _, ok := err.(*ErrRecordNotFound); ok {
+		return &app.ErrNotFound{
+			ResourceName: "patient",
+		}
+	}
+	if _, ok := err.(*ErrRecordExists); ok {
+		return &app.ErrExists{
+			ResourceName: "patient",
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *Repository) CreatePatientFieldChanges(changes []models.PatientFieldChange) error {
+	for i := range changes {
+		changes[i].CreatedAt = time.Now().UTC()
+		changes[i].UpdatedAt = time.Now().UTC()
+	}
+
+	err := tryWrapDbError(
 		r.client.
-			Exec("DELETE FROM has_viruses WHERE patient_id = ?", id).
+			Model(new(models.PatientFieldChange)).
+			Create(changes).
 			Error,
 	)
 	if err != nil {
 		return err
 	}
 
-	err = tryWrapDbError(
+	return nil
+}
+
+func (r *Repository) ListPatientFieldChanges(patientId uint) ([]models.PatientFieldChange, error) {
+	var changes []models.PatientFieldChange
+
+	err := tryWrapDbError(
 		r.client.
-			Model(new(models.Patient)).
-			Delete(&models.Patient{Id: id}, "id = ?", id).
+			Model(new(models.PatientFieldChange)).
+			Where("patient_id = ?", patientId).
+			Order("created_at DESC").
+			Find(&changes).
 			Error,
 	)
-	if _, ok := err.(*ErrRecordNotFound); ok {
-		return &app.ErrNotFound{
-			ResourceName: "patient",
-		}
-	}
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return changes, nil
 }
 
 func (r *Repository) CreatePatientVisit(visit models.Visit) (models.Visit, error) {
@@ -966,6 +2384,67 @@ func (r *Repository) CreatePrescribedMedicine(pm models.PrescribedMedicine) (mod
 	return pm, nil
 }
 
+func (r *Repository) CreatePrescription(prescription models.Prescription) (models.Prescription, error) {
+	prescription.CreatedAt = time.Now().UTC()
+	prescription.UpdatedAt = time.Now().UTC()
+
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.Prescription)).
+			Omit("Visit").
+			Create(&prescription).
+			Error,
+	)
+	if err != nil {
+		return models.Prescription{}, err
+	}
+
+	return prescription, nil
+}
+
+func (r *Repository) ListVisitPrescriptions(visitId uint) ([]models.Prescription, error) {
+	var prescriptions []models.Prescription
+
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.Prescription)).
+			Where("visit_id = ?", visitId).
+			Find(&prescriptions).
+			Error,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return prescriptions, nil
+}
+
+func (r *Repository) ListPatientPrescriptions(patientId uint, from, to time.Time) ([]models.Prescription, error) {
+	query := r.client.
+		Model(new(models.Prescription)).
+		Preload("Visit").
+		Where("patient_id = ?", patientId)
+	if !from.IsZero() {
+		query = query.Where("created_at >= ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("created_at < ?", to)
+	}
+
+	var prescriptions []models.Prescription
+	err := tryWrapDbError(
+		query.
+			Order("created_at ASC").
+			Find(&prescriptions).
+			Error,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return prescriptions, nil
+}
+
 func (r *Repository) GetPatientLastVisit(patientId uint) (models.Visit, error) {
 	var visits []models.Visit
 
@@ -1013,6 +2492,23 @@ func (r *Repository) ListPatientVisitPrescribedMedicine(visitId uint) ([]models.
 	return prescribedMeds, nil
 }
 
+func (r *Repository) ListPrescribedMedicinesByIds(ids []uint) ([]models.PrescribedMedicine, error) {
+	var prescribedMeds []models.PrescribedMedicine
+
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.PrescribedMedicine)).
+			Where("id IN ?", ids).
+			Find(&prescribedMeds).
+			Error,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return prescribedMeds, nil
+}
+
 func (r *Repository) UseMedicineForVisit(prescribedMedicineId, visitId uint) error {
 	err := tryWrapDbError(
 		r.client.
@@ -1161,7 +2657,9 @@ func (r *Repository) ListJointEvaluationsForPatient(patientId uint) ([]models.Jo
 	err := tryWrapDbError(
 		r.client.
 			Model(new(models.JointsEvaluation)).
+			Preload("Joints").
 			Where("patient_id = ?", patientId).
+			Order("created_at DESC").
 			Find(&jes).
 			Error,
 	)
@@ -1196,6 +2694,16 @@ func (r *Repository) CreateDiagnosis(diagnosis models.Diagnosis) (models.Diagnos
 
 func (r *Repository) DeleteDiagnisis(id uint) error {
 	err := tryWrapDbError(
+		r.client.
+			Model(new(models.DiagnosisField)).
+			Delete(&models.DiagnosisField{DiagnosisId: id}, "diagnosis_id = ?", id).
+			Error,
+	)
+	if err != nil {
+		return err
+	}
+
+	err = tryWrapDbError(
 		r.client.
 			Model(new(models.Diagnosis)).
 			Delete(&models.Diagnosis{Id: id}, "id = ?", id).
@@ -1213,12 +2721,45 @@ func (r *Repository) DeleteDiagnisis(id uint) error {
 	return nil
 }
 
+func (r *Repository) GetDiagnosis(id uint) (models.Diagnosis, error) {
+	var diagnosis models.Diagnosis
+
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.Diagnosis)).
+			Preload("Fields").
+			First(&diagnosis, "id = ?", id).
+			Error,
+	)
+	if _, ok := err.(*ErrRecordNotFound); ok {
+		return models.Diagnosis{}, &app.ErrNotFound{
+			ResourceName: "diagnosis",
+		}
+	}
+	if err != nil {
+		return models.Diagnosis{}, err
+	}
+
+	return diagnosis, nil
+}
+
+func (r *Repository) SetDiagnosisArchivedAt(id uint, archivedAt *time.Time) error {
+	return r.setArchivedAt(new(models.Diagnosis), "diagnosis", id, archivedAt)
+}
+
+func (r *Repository) GetDiagnosisUsage(id uint) (models.Usage, error) {
+	return r.countReferences(id, map[string]string{
+		"diagnoses_results": models.DiagnosisResult{}.TableName() + ".diagnosis_id",
+	})
+}
+
 func (r *Repository) ListAllDiagnoses() ([]models.Diagnosis, error) {
 	var diagnoses []models.Diagnosis
 
 	err := tryWrapDbError(
 		r.client.
 			Model(new(models.Diagnosis)).
+			Preload("Fields").
 			Find(&diagnoses).
 			Error,
 	)
@@ -1259,7 +2800,9 @@ func (r *Repository) ListPatientDiagnosisResults(patientId uint) ([]models.Diagn
 	err := tryWrapDbError(
 		r.client.
 			Model(new(models.DiagnosisResult)).
+			Preload("FilledFields").
 			Where("patient_id = ?", patientId).
+			Order("diagnosed_at ASC, id ASC").
 			Find(&diagnoses).
 			Error,
 	)
@@ -1270,6 +2813,310 @@ func (r *Repository) ListPatientDiagnosisResults(patientId uint) ([]models.Diagn
 	return diagnoses, nil
 }
 
+func (r *Repository) GetDiagnosisResult(id uint) (models.DiagnosisResult, error) {
+	var diagnosis models.DiagnosisResult
+
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.DiagnosisResult)).
+			Preload("FilledFields").
+			First(&diagnosis, "id = ?", id).
+			Error,
+	)
+	if _, ok := err.(*ErrRecordNotFound); ok {
+		return models.DiagnosisResult{}, &app.ErrNotFound{
+			ResourceName: "diagnosis_result",
+		}
+	}
+	if err != nil {
+		return models.DiagnosisResult{}, err
+	}
+
+	return diagnosis, nil
+}
+
+// inactiveDiagnosisResultError is the error of a conditional update of an active diagnosis result that updated nothing,
+// either because the result doesn't exist or because it was replaced or revoked concurrently.
+func (r *Repository) inactiveDiagnosisResultError(id uint) error {
+	dr, err := r.GetDiagnosisResult(id)
+	if err != nil {
+		return err
+	}
+
+	return &app.ErrInactiveDiagnosisResult{
+		ReplacedById: dr.ReplacedById,
+	}
+}
+
+func (r *Repository) SetDiagnosisResultReplacedBy(id, replacedById uint) error {
+	result := r.client.
+		Model(new(models.DiagnosisResult)).
+		Where("id = ? AND replaced_by_id = 0 AND revoked_at IS NULL", id).
+		Updates(map[string]any{
+			"replaced_by_id": replacedById,
+			"updated_at":     time.Now().UTC(),
+		})
+	err := tryWrapDbError(result.Error)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected == 0 {
+		return r.inactiveDiagnosisResultError(id)
+	}
+
+	return nil
+}
+
+func (r *Repository) RevokeDiagnosisResult(id, revokedById uint, reason string) error {
+	result := r.client.
+		Model(new(models.DiagnosisResult)).
+		Where("id = ? AND replaced_by_id = 0 AND revoked_at IS NULL", id).
+		Updates(map[string]any{
+			"revoked_at":        time.Now().UTC(),
+			"revoked_by_id":     revokedById,
+			"revocation_reason": reason,
+			"updated_at":        time.Now().UTC(),
+		})
+	err := tryWrapDbError(result.Error)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected == 0 {
+		return r.inactiveDiagnosisResultError(id)
+	}
+
+	return nil
+}
+
+func (r *Repository) CreateAuditLog(auditLog models.AuditLog) (models.AuditLog, error) {
+	auditLog.CreatedAt = time.Now().UTC()
+
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.AuditLog)).
+			Create(&auditLog).
+			Error,
+	)
+	if err != nil {
+		return models.AuditLog{}, err
+	}
+
+	return auditLog, nil
+}
+
+func (r *Repository) ListAuditLogs(filter models.AuditLogFilter) ([]models.AuditLog, int64, error) {
+	filteredAuditLogs := func() *gorm.DB {
+		query := r.client.Model(new(models.AuditLog))
+		if filter.AccountId != 0 {
+			query = query.Where("account_id = ?", filter.AccountId)
+		}
+		if filter.Action != "" {
+			query = query.Where("action = ?", filter.Action)
+		}
+		if filter.ResourceType != "" {
+			query = query.Where("resource_type = ?", filter.ResourceType)
+		}
+		if filter.ResourceId != "" {
+			query = query.Where("resource_id = ?", filter.ResourceId)
+		}
+		if !filter.From.IsZero() {
+			query = query.Where("created_at >= ?", filter.From)
+		}
+		if !filter.To.IsZero() {
+			query = query.Where("created_at < ?", filter.To)
+		}
+		return query
+	}
+
+	var total int64
+	err := tryWrapDbError(
+		filteredAuditLogs().
+			Count(&total).
+			Error,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var auditLogs []models.AuditLog
+	err = tryWrapDbError(
+		filteredAuditLogs().
+			Order("id DESC").
+			Offset(filter.Offset).
+			Limit(filter.Limit).
+			Find(&auditLogs).
+			Error,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return auditLogs, total, nil
+}
+
 func likeArg(arg string) string {
 	return fmt.Sprintf("%%%s%%", arg)
 }
+
+func prefixLikeArg(arg string) string {
+	return fmt.Sprintf("%s%%", arg)
+}
+
+func (r *Repository) CreateAlert(alert models.Alert) (models.Alert, error) {
+	alert.CreatedAt = time.Now().UTC()
+
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.Alert)).
+			Create(&alert).
+			Error,
+	)
+	if err != nil {
+		return models.Alert{}, err
+	}
+
+	return alert, nil
+}
+
+func (r *Repository) ListAlerts(filter models.AlertFilter) ([]models.Alert, int64, error) {
+	filteredAlerts := func() *gorm.DB {
+		query := r.client.Model(new(models.Alert))
+		if filter.Type != "" {
+			query = query.Where("type = ?", filter.Type)
+		}
+		if filter.ProductId != 0 {
+			query = query.Where("product_id = ?", filter.ProductId)
+		}
+		if filter.PatientId != 0 {
+			query = query.Where("patient_id = ?", filter.PatientId)
+		}
+		if !filter.From.IsZero() {
+			query = query.Where("created_at >= ?", filter.From)
+		}
+		if !filter.To.IsZero() {
+			query = query.Where("created_at < ?", filter.To)
+		}
+		return query
+	}
+
+	var total int64
+	err := tryWrapDbError(
+		filteredAlerts().
+			Count(&total).
+			Error,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var alerts []models.Alert
+	err = tryWrapDbError(
+		filteredAlerts().
+			Order("id DESC").
+			Offset(filter.Offset).
+			Limit(filter.Limit).
+			Find(&alerts).
+			Error,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return alerts, total, nil
+}
+
+func (r *Repository) CreateBleedEpisode(episode models.BleedEpisode) (models.BleedEpisode, error) {
+	episode.CreatedAt = time.Now().UTC()
+	episode.UpdatedAt = time.Now().UTC()
+	for i := range episode.Infusions {
+		episode.Infusions[i].CreatedAt = episode.CreatedAt
+	}
+
+	err := tryWrapDbError(
+		r.client.
+			Model(new(models.BleedEpisode)).
+			Create(&episode).
+			Error,
+	)
+	if _, ok := err.(*ErrRecordExists); ok {
+		return models.BleedEpisode{}, &app.ErrExists{
+			ResourceName: "bleed_infusion",
+		}
+	}
+	if err != nil {
+		return models.BleedEpisode{}, err
+	}
+
+	return episode, nil
+}
+
+func (r *Repository) ListPatientBleedEpisodes(patientId uint, from, to time.Time) ([]models.BleedEpisode, error) {
+	query := r.client.
+		Model(new(models.BleedEpisode)).
+		Preload("Infusions").
+		Where("patient_id = ?", patientId)
+	if !from.IsZero() {
+		query = query.Where("occurred_at >= ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("occurred_at < ?", to)
+	}
+
+	var episodes []models.BleedEpisode
+	err := tryWrapDbError(
+		query.
+			Order("occurred_at DESC").
+			Find(&episodes).
+			Error,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return episodes, nil
+}
+
+// setArchivedAt sets the catalog entry's archived_at, where a nil archivedAt unarchives it.
+func (r *Repository) setArchivedAt(model schema.Tabler, resourceName string, id uint, archivedAt *time.Time) error {
+	result := r.client.
+		Model(model).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"archived_at": archivedAt,
+			"updated_at":  time.Now().UTC(),
+		})
+	err := tryWrapDbError(result.Error)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected == 0 {
+		return &app.ErrNotFound{
+			ResourceName: resourceName,
+		}
+	}
+
+	return nil
+}
+
+// countReferences counts the rows that reference the id in each of the given "table.column"s, by the usage's kind.
+func (r *Repository) countReferences(id uint, references map[string]string) (models.Usage, error) {
+	usage := make(models.Usage, len(references))
+	for kind, reference := range references {
+		table, column, _ := strings.Cut(reference, ".")
+
+		var count int64
+		err := tryWrapDbError(
+			r.client.
+				Table(table).
+				Where(column+" = ?", id).
+				Count(&count).
+				Error,
+		)
+		if err != nil {
+			return nil, err
+		}
+		usage[kind] = count
+	}
+
+	return usage, nil
+}
@@ -0,0 +1,115 @@
+import { test, expect } from "@playwright/test";
+import { loginAccount, resetCache, resetDB, seedAccounts } from "./factory";
+import accounts from "./accounts.json";
+
+test.beforeAll(async ({ request }) => {
+  await resetDB(request);
+  await resetCache(request);
+  await seedAccounts(request);
+});
+
+test.describe("Medicine Stock", () => {
+  test("concurrent visits never drive a medicine's amount below zero", async ({
+    request,
+  }) => {
+    const token = await loginAccount(request, accounts.b);
+    const headers = { Authorization: token };
+
+    const initialAmount = 5;
+    const concurrentVisits = 20;
+
+    let resp = await request.post("/v1/medicines", {
+      headers,
+      data: JSON.stringify({
+        new_medicine: {
+          name: "Concurrency Factor",
+          dose: 500,
+          unit: "IU",
+          amount: initialAmount,
+          expires_at: "2099-01-01T00:00:00Z",
+          received_at: "2025-01-01T00:00:00Z",
+          manufacturer: "Stress Labs",
+          batch_number: "CONC-001",
+          factor_type: "VIII",
+        },
+      }),
+    });
+    expect(resp).toBeOK();
+
+    resp = await request.get("/v1/medicines", { headers });
+    expect(resp).toBeOK();
+    const medicine = (await resp.json()).data.find(
+      (m: { batch_number: string }) => m.batch_number === "CONC-001",
+    );
+    expect(medicine).toBeDefined();
+
+    resp = await request.post("/v1/patients", {
+      headers,
+      data: JSON.stringify({
+        new_patient: {
+          national_id: "01020304050",
+          nationality: "syrian",
+          first_name: "Stock",
+          last_name: "Tester",
+          father_name: "Load",
+          mother_name: "Race",
+          place_of_birth: {
+            governorate: "Damascus",
+            suburb: "Mezzeh",
+            street: "Main",
+          },
+          date_of_birth: "2000-01-01T00:00:00Z",
+          residency: {
+            governorate: "Damascus",
+            suburb: "Mezzeh",
+            street: "Main",
+          },
+          gender: true,
+          phone_number: "+963900000000",
+        },
+      }),
+    });
+    expect(resp).toBeOK();
+    const patientId = (await resp.json()).id;
+
+    const responses = await Promise.all(
+      Array.from({ length: concurrentVisits }, () =>
+        request.post(`/v1/patients/${patientId}/checkup`, {
+          headers,
+          data: JSON.stringify({
+            visit_reason: "primary_prophylaxis",
+            visit_extra_details: "",
+            patient_weight: 70,
+            patient_height: 175,
+            prescribed_medicines: [{ id: medicine.id, amount: 1 }],
+          }),
+        }),
+      ),
+    );
+
+    const succeeded = responses.filter((r) => r.ok());
+    const rejected = responses.filter((r) => !r.ok());
+    expect(succeeded.length).toBe(initialAmount);
+    expect(rejected.length).toBe(concurrentVisits - initialAmount);
+    for (const r of rejected) {
+      expect(r.status()).toBe(403);
+      expect((await r.json()).error_id).toBe("insufficient-medicine-amount");
+    }
+
+    resp = await request.get(`/v1/medicines/${medicine.id}`, { headers });
+    expect(resp).toBeOK();
+    expect((await resp.json()).data.amount).toBe(0);
+
+    resp = await request.get(`/v1/medicines/${medicine.id}/movements`, {
+      headers,
+    });
+    expect(resp).toBeOK();
+    const movements = await resp.json();
+    expect(movements.ledger_amount).toBe(movements.amount);
+    expect(
+      movements.data.filter(
+        (m: { type: string }) => m.type === "dispensation",
+      ).length,
+    ).toBe(initialAmount);
+  });
+});